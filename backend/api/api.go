@@ -11,9 +11,10 @@ import (
 )
 
 // StartServer starts the HTTP server
-func StartServer(cfg *config.Config, service *service.JobService) error {
+func StartServer(cfg *config.Config, service *service.JobService, renderService *service.RenderService) error {
 	// Create handlers
 	mtxHandler := handlers.NewJobsHandler(service)
+	renderHandler := handlers.NewRendersHandler(renderService)
 
 	// Create router
 	router := mux.NewRouter()
@@ -22,6 +23,16 @@ func StartServer(cfg *config.Config, service *service.JobService) error {
 	router.HandleFunc("/api/jobs", mtxHandler.UploadJob).Methods("POST")
 	router.HandleFunc("/api/jobs", mtxHandler.ListJobs).Methods("GET")
 	router.HandleFunc("/api/jobs/{id:[0-9]+}", mtxHandler.GetJob).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/versions", mtxHandler.ListVersions).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/thumbnail", mtxHandler.GetThumbnail).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/vertices/{vid:[0-9]+}", mtxHandler.GetVertex).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.png", mtxHandler.GetTile).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/projections", mtxHandler.ProjectJob).Methods("POST")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/renders", renderHandler.CreateRender).Methods("POST")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/renders", renderHandler.ListRenders).Methods("GET")
+	router.HandleFunc("/api/jobs/{id:[0-9]+}/renders/{rid:[0-9]+}", renderHandler.GetRender).Methods("GET")
+	router.HandleFunc("/api/compare", mtxHandler.CompareJobs).Methods("POST")
+	router.HandleFunc("/api/diff", mtxHandler.DiffJobs).Methods("POST")
 	router.HandleFunc("/api/jbos/{id:[0-9]+}/download", mtxHandler.DownloadJob).Methods("GET")
 
 	// Health check endpoint
@@ -30,18 +30,22 @@ func main() {
 	}
 
 	jobCreatedCh := make(chan struct{}, 100)
-	jobService := service.NewJobService(db, jobCreatedCh)
+	renderCreatedCh := make(chan struct{}, 100)
 	workerClient := clients.NewWorkerClient(cfg.WorkerHost)
+	jobService := service.NewJobService(db, jobCreatedCh, workerClient)
+	renderService := service.NewRenderService(db, jobService, renderCreatedCh, workerClient)
 	s := scheduler.NewScheduler(
 		jobService,
+		renderService,
 		logger,
 		jobCreatedCh,
+		renderCreatedCh,
 		db,
 		workerClient,
 	)
 	s.Start()
 	// Start API server
-	if err := api.StartServer(cfg, jobService); err != nil {
+	if err := api.StartServer(cfg, jobService, renderService); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
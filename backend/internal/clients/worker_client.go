@@ -4,11 +4,16 @@ import (
 	"backend/internal/dto"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 )
 
+// ErrNotFound is returned when the worker has no files for the requested job
+var ErrNotFound = errors.New("not found on worker")
+
 type WorkerClient struct {
 	httpClient *http.Client
 	workerHost string
@@ -65,6 +70,116 @@ func (c *WorkerClient) Ping(taskReq dto.JobRequest) (*dto.JobResponse, error) {
 	return &taskResp, nil
 }
 
+// renderTimeout bounds a re-projection or render, which draws and uploads synchronously
+const renderTimeout = 2 * time.Minute
+
+// Project asks the worker to re-project the stored embedding of a finished job
+func (c *WorkerClient) Project(projReq dto.ProjectionRequest) (*dto.RenderResponse, error) {
+	return c.postRender("/project", projReq)
+}
+
+// Render asks the worker to draw the stored embedding of a finished job with new options
+func (c *WorkerClient) Render(renderReq dto.RenderRequest) (*dto.RenderResponse, error) {
+	return c.postRender("/render", renderReq)
+}
+
+// Compare asks the worker to align the drawings of two finished jobs
+func (c *WorkerClient) Compare(compareReq dto.CompareRequest) (*dto.RenderResponse, error) {
+	return c.postRender("/compare", compareReq)
+}
+
+// Diff asks the worker to compare the graphs of two finished jobs
+func (c *WorkerClient) Diff(diffReq dto.CompareRequest) (*dto.RenderResponse, error) {
+	return c.postRender("/diff", diffReq)
+}
+
+// Tile fetches one PNG tile of the map of a finished job, drawn on demand by the worker
+func (c *WorkerClient) Tile(tileReq dto.TileRequest) ([]byte, error) {
+	return c.postFetch("/tile", tileReq)
+}
+
+// Thumbnail fetches the PNG thumbnail of a finished job
+func (c *WorkerClient) Thumbnail(thumbReq dto.ThumbnailRequest) ([]byte, error) {
+	return c.postFetch("/thumbnail", thumbReq)
+}
+
+// Vertex looks up a vertex in the drawing of a finished job
+func (c *WorkerClient) Vertex(vertexReq dto.VertexRequest) (*dto.VertexInfo, error) {
+	body, err := c.postFetch("/vertex", vertexReq)
+	if err != nil {
+		return nil, err
+	}
+	var info dto.VertexInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &info, nil
+}
+
+// postFetch asks the worker for a file or record of a finished job,
+// ErrNotFound when the worker does not have it
+func (c *WorkerClient) postFetch(path string, payload interface{}) ([]byte, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.workerHost+path, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := *c.httpClient
+	client.Timeout = renderTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("worker returned non-OK status code: %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *WorkerClient) postRender(path string, payload interface{}) (*dto.RenderResponse, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.workerHost+path, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := *c.httpClient
+	client.Timeout = renderTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("worker returned non-OK status code: %d", resp.StatusCode)
+	}
+
+	var renderResp dto.RenderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&renderResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &renderResp, nil
+}
+
 // SetTimeout allows configuring a custom timeout for the HTTP client
 func (c *WorkerClient) SetTimeout(timeout time.Duration) {
 	c.httpClient.Timeout = timeout
@@ -40,6 +40,22 @@ func InitSchema(db *sql.DB) error {
             error TEXT,
             result_url TEXT
         );
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS params JSONB NOT NULL DEFAULT '{}';
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS spectral_report JSONB;
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS partition JSONB;
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS layout_metrics JSONB;
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES jobs(id) ON DELETE SET NULL;
+        ALTER TABLE jobs ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+        CREATE TABLE IF NOT EXISTS renders (
+            id SERIAL PRIMARY KEY,
+            job_id INTEGER NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+            params JSONB NOT NULL DEFAULT '{}',
+            status VARCHAR(50) DEFAULT 'created',
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            error TEXT,
+            result_url TEXT
+        );
+        ALTER TABLE renders ADD COLUMN IF NOT EXISTS layout_metrics JSONB;
     `)
 	return err
 }
@@ -1,13 +1,90 @@
 package dto
 
+import (
+	"encoding/json"
+
+	"backend/internal/models"
+)
+
 type JobRequest struct {
-	ID      string  `json:"id"`
-	Content *string `json:"content"`
+	ID      string            `json:"id"`
+	Content *string           `json:"content"`
+	Params  *models.JobParams `json:"params,omitempty"`
+	// ParentID is the job whose eigenvectors warm-start the solver
+	ParentID *string `json:"parent_id,omitempty"`
 }
 
 type JobResponse struct {
-	ID     string  `json:"id"`
-	Status string  `json:"status"`
-	Result *string `json:"result"`
-	Error  *string `json:"error"`
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Result    *string         `json:"result"`
+	Error     *string         `json:"error"`
+	Report    json.RawMessage `json:"report,omitempty"`
+	Partition json.RawMessage `json:"partition,omitempty"`
+	Metrics   json.RawMessage `json:"metrics,omitempty"`
+}
+
+// ProjectionRequest asks the worker to re-project the stored embedding of a job
+type ProjectionRequest struct {
+	ID         string            `json:"id"`
+	Projection models.Projection `json:"projection"`
+	Refinement models.Refinement `json:"refinement"`
+}
+
+// RenderRequest asks the worker to draw the stored embedding of a job with new options
+type RenderRequest struct {
+	ID       string              `json:"id"`
+	RenderID string              `json:"render_id"`
+	Params   models.RenderParams `json:"params"`
+}
+
+// RenderResponse is the worker's answer to a ProjectionRequest, RenderRequest or CompareRequest
+type RenderResponse struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Result    *string         `json:"result"`
+	Err       *string         `json:"err"`
+	Metrics   json.RawMessage `json:"metrics,omitempty"`
+	Alignment json.RawMessage `json:"alignment,omitempty"`
+	Diff      json.RawMessage `json:"diff,omitempty"`
+}
+
+// CompareRequest asks the worker to align the drawing of job OtherID to the drawing of job ID,
+// or to compare their graphs
+type CompareRequest struct {
+	ID      string `json:"id"`
+	OtherID string `json:"other_id"`
+}
+
+// VertexRequest asks the worker for vertex Vertex of the drawing of job ID
+type VertexRequest struct {
+	ID     string `json:"id"`
+	Vertex int    `json:"vertex"`
+}
+
+// VertexInfo locates a vertex in the 2D drawing of a job: its coordinates,
+// degree and neighbors, cut at the worker's limit when Truncated is set. In a
+// bipartite graph Side is "row" or "column" and Index the row or column number
+// in the matrix; otherwise Index is the vertex id.
+type VertexInfo struct {
+	Vertex    int       `json:"vertex"`
+	Side      string    `json:"side,omitempty"`
+	Index     int       `json:"index"`
+	Position  []float64 `json:"position"`
+	Degree    int       `json:"degree"`
+	Neighbors []int     `json:"neighbors"`
+	Truncated bool      `json:"truncated"`
+}
+
+// ThumbnailRequest asks the worker for the thumbnail of job ID
+type ThumbnailRequest struct {
+	ID string `json:"id"`
+}
+
+// TileRequest asks the worker for tile (X, Y) of zoom level Z of the map of job ID
+type TileRequest struct {
+	ID string `json:"id"`
+	Z  int    `json:"z"`
+	X  int    `json:"x"`
+	Y  int    `json:"y"`
 }
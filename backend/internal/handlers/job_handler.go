@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 
+	"backend/internal/models"
 	"backend/internal/service"
 	"github.com/gorilla/mux"
 )
@@ -42,9 +44,32 @@ func (h *JobsHandler) UploadJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.Service.SaveJob(header.Filename, string(content))
+	params, err := parseJobParams(r)
 	if err != nil {
-		http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid job params: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var parentID *int
+	if v := r.FormValue("parent_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid parent_id", http.StatusBadRequest)
+			return
+		}
+		parentID = &id
+	}
+
+	id, err := h.Service.SaveJob(header.Filename, string(content), params, parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to save file: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -89,6 +114,29 @@ func (h *JobsHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(jobs)
 }
 
+// ListVersions lists all versions of the graph of a job, from the first one on
+func (h *JobsHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.Service.ListVersions(id)
+	if err != nil {
+		if err.Error() == "file not found" {
+			http.Error(w, "File not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
 func (h *JobsHandler) DownloadJob(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
@@ -111,3 +159,186 @@ func (h *JobsHandler) DownloadJob(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", file.Filename))
 	w.Write([]byte(file.Content))
 }
+
+func (h *JobsHandler) ProjectJob(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var projection models.Projection
+	if err := json.NewDecoder(r.Body).Decode(&projection); err != nil {
+		http.Error(w, "Invalid projection: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Service.ProjectJob(id, projection)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to project job: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *JobsHandler) CompareJobs(w http.ResponseWriter, r *http.Request) {
+	var req models.Comparison
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid comparison: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.BaseJobID <= 0 || req.JobID <= 0 {
+		http.Error(w, "base_job_id and job_id are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Service.CompareJobs(req.BaseJobID, req.JobID)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to compare jobs: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *JobsHandler) DiffJobs(w http.ResponseWriter, r *http.Request) {
+	var req models.GraphDiff
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid diff: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.BaseJobID <= 0 || req.JobID <= 0 {
+		http.Error(w, "base_job_id and job_id are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.Service.DiffJobs(req.BaseJobID, req.JobID)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to diff jobs: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *JobsHandler) GetTile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	coords := make([]int, 0, 4)
+	for _, key := range []string{"id", "z", "x", "y"} {
+		v, err := strconv.Atoi(vars[key])
+		if err != nil {
+			http.Error(w, "Invalid "+key, http.StatusBadRequest)
+			return
+		}
+		coords = append(coords, v)
+	}
+
+	tile, err := h.Service.GetTile(coords[0], coords[1], coords[2], coords[3])
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to get tile: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// the drawing of a finished job does not change, so tiles can be cached for long
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(tile)
+}
+
+func (h *JobsHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	thumbnail, err := h.Service.GetThumbnail(id)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to get thumbnail: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(thumbnail)
+}
+
+func (h *JobsHandler) GetVertex(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	vid, err := strconv.Atoi(vars["vid"])
+	if err != nil {
+		http.Error(w, "Invalid vertex id", http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.Service.GetVertex(id, vid)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case err.Error() == "vertex not found":
+			http.Error(w, "Vertex not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to get vertex: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// parseJobParams reads optional pipeline options from the upload form
+func parseJobParams(r *http.Request) (models.JobParams, error) {
+	var params models.JobParams
+	params.Coarsening = r.FormValue("coarsening")
+	params.WeightMode = r.FormValue("weight_mode")
+	params.Symmetrization = r.FormValue("symmetrization")
+	params.Laplacian = r.FormValue("laplacian")
+	params.Projection.Mode = r.FormValue("projection")
+	params.Refinement.Method = r.FormValue("refinement")
+	params.Animation.Format = r.FormValue("animation")
+
+	if err := formInt(r, "coarsening_levels", &params.CoarseningLevels); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "eigenvectors", &params.Eigenvectors); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "clusters", &params.Clusters); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "refine_iterations", &params.Refinement.Iterations); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "stress_pivots", &params.Refinement.Pivots); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "refine_frames", &params.Refinement.Frames); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "animation_every", &params.Animation.Every); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "animation_delay", &params.Animation.Delay); err != nil {
+		return params, err
+	}
+	if err := formInt(r, "animation_max_frames", &params.Animation.MaxFrames); err != nil {
+		return params, err
+	}
+	if v := r.FormValue("force_gravity"); v != "" {
+		gravity, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return params, fmt.Errorf("force_gravity: %w", err)
+		}
+		params.Refinement.Gravity = &gravity
+	}
+	if v := r.FormValue("force_repulsion"); v != "" {
+		repulsion, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return params, fmt.Errorf("force_repulsion: %w", err)
+		}
+		params.Refinement.Repulsion = repulsion
+	}
+	if v := r.FormValue("bisection"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return params, fmt.Errorf("bisection: %w", err)
+		}
+		params.Bisection = b
+	}
+	axes, err := parseAxes(r.FormValue("projection_axes"))
+	if err != nil {
+		return params, fmt.Errorf("projection_axes: %w", err)
+	}
+	params.Projection.Axes = axes
+	params.Exports = formList(r, "exports")
+	params.Snapshots = formList(r, "snapshots")
+	if v := r.FormValue("seed"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seed < 0 {
+			return params, fmt.Errorf("seed: must be a non-negative integer")
+		}
+		params.Seed = &seed
+	}
+	return params, nil
+}
+
+// formInt parses an optional integer form field, leaving dst untouched when it is absent
+func formInt(r *http.Request, key string, dst *int) error {
+	v := r.FormValue(key)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*dst = n
+	return nil
+}
+
+// formList reads an optional comma separated form field, skipping empty items
+func formList(r *http.Request, key string) []string {
+	var list []string
+	for _, item := range strings.Split(r.FormValue(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseAxes reads a comma separated list of eigenvector numbers such as "2,4"
+func parseAxes(v string) ([]int, error) {
+	var axes []int
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		axes = append(axes, a)
+	}
+	return axes, nil
+}
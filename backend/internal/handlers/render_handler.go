@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend/internal/models"
+	"backend/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type RendersHandler struct {
+	Service *service.RenderService
+}
+
+func NewRendersHandler(renderService *service.RenderService) *RendersHandler {
+	return &RendersHandler{
+		Service: renderService,
+	}
+}
+
+func (h *RendersHandler) CreateRender(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var params models.RenderParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Invalid render params: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	render, err := h.Service.CreateRender(jobID, params)
+	if err != nil {
+		switch {
+		case err.Error() == "file not found":
+			http.Error(w, "File not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidParams):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrJobNotFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to create render: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(render)
+}
+
+func (h *RendersHandler) ListRenders(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	renders, err := h.Service.ListRenders(jobID)
+	if err != nil {
+		http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(renders)
+}
+
+func (h *RendersHandler) GetRender(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	jobID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(params["rid"])
+	if err != nil {
+		http.Error(w, "Invalid render ID", http.StatusBadRequest)
+		return
+	}
+
+	render, err := h.Service.GetRender(jobID, id)
+	if err != nil {
+		if errors.Is(err, service.ErrRenderNotFound) {
+			http.Error(w, "Render not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(render)
+}
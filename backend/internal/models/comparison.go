@@ -0,0 +1,25 @@
+package models
+
+import "encoding/json"
+
+// Comparison aligns the drawing of JobID to the drawing of BaseJobID with orthogonal
+// Procrustes over the vertex ids drawn in both. Alignment holds the rotation, scale,
+// residual and number of shared vertices; ResUrl lists the aligned coordinates and
+// an overlay of both drawings.
+type Comparison struct {
+	BaseJobID int             `json:"base_job_id"`
+	JobID     int             `json:"job_id"`
+	Alignment json.RawMessage `json:"alignment,omitempty"`
+	ResUrl    *string         `json:"res_url,omitempty"`
+}
+
+// GraphDiff compares the graph of JobID to the graph of BaseJobID on the same vertex
+// ids. Diff holds the added and removed vertices and edges, the degree changes and
+// the components split or merged; ResUrl lists a drawing of the added and removed
+// edges on the layouts aligned to each other.
+type GraphDiff struct {
+	BaseJobID int             `json:"base_job_id"`
+	JobID     int             `json:"job_id"`
+	Diff      json.RawMessage `json:"diff,omitempty"`
+	ResUrl    *string         `json:"res_url,omitempty"`
+}
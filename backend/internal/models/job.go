@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -13,6 +14,20 @@ type Job struct {
 	Status     string    `json:"status"`
 	Error      *string   `json:"error,omitempty"`
 	ResUrl     *string   `json:"res_url,omitempty"`
+	Params     JobParams `json:"params"`
+	// ParentID is the job holding the previous version of the graph; Version
+	// counts the versions from the first one, which has no parent
+	ParentID *int `json:"parent_id,omitempty"`
+	Version  int  `json:"version"`
+	// SpectralReport holds the solver diagnostics: eigenvalues, residuals and
+	// per-stage iterations and timings
+	SpectralReport json.RawMessage `json:"spectral_report,omitempty"`
+	// Partition holds the sizes, cut and balance of the k-means clustering and
+	// the Fiedler bisection, when the job asked for them
+	Partition json.RawMessage `json:"partition,omitempty"`
+	// LayoutMetrics holds the quality of the drawing: stress, edge length
+	// uniformity, neighborhood preservation, crossings and angular resolution
+	LayoutMetrics json.RawMessage `json:"layout_metrics,omitempty"`
 }
 
 type JobList struct {
@@ -20,4 +35,8 @@ type JobList struct {
 	Filename   string    `json:"filename"`
 	Dimensions string    `json:"dimensions"`
 	CreatedAt  time.Time `json:"created_at"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	Version    int       `json:"version"`
+	// ThumbnailURL is the API path of the thumbnail, set for successful jobs
+	ThumbnailURL *string `json:"thumbnail_url,omitempty"`
 }
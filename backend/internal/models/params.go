@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// JobParams holds per-job options of the embedding pipeline
+type JobParams struct {
+	Coarsening       string     `json:"coarsening"`
+	CoarseningLevels int        `json:"coarsening_levels"`
+	Seed             *int64     `json:"seed"`
+	WeightMode       string     `json:"weight_mode"`
+	Symmetrization   string     `json:"symmetrization"`
+	Interpretation   string     `json:"interpretation"` // how the matrix was turned into a graph, set on upload
+	Laplacian        string     `json:"laplacian"`
+	Eigenvectors     int        `json:"eigenvectors"`
+	Projection       Projection `json:"projection"`
+	Clusters         int        `json:"clusters"`  // k-means clusters on the embedding, 0 to skip
+	Bisection        bool       `json:"bisection"` // split the graph by the sign of the Fiedler vector
+	Refinement       Refinement `json:"refinement"`
+	Exports          []string   `json:"exports"`   // 3D model formats of the 3D embedding: obj, ply, glb or vtk
+	Snapshots        []string   `json:"snapshots"` // views of the 3D snapshots: front, side, top, iso or "azimuth:elevation"
+	Animation        Animation  `json:"animation"`
+}
+
+// Animation asks for the layout to be saved every Every steps of the spectral
+// solver (Tutte smoothing rounds and Koren iterations) and of the force layout,
+// and drawn as an animation in Format "gif", "apng" or "frames" (a PNG per
+// frame) showing each frame for Delay milliseconds. An empty Format draws none.
+// The solver keeps at most MaxFrames frames, thinning them out evenly.
+type Animation struct {
+	Format    string `json:"format,omitempty"`
+	Every     int    `json:"every,omitempty"`
+	Delay     int    `json:"delay,omitempty"`
+	MaxFrames int    `json:"max_frames,omitempty"`
+}
+
+// Refinement selects the layout stage run after the spectral layout: "none" keeps
+// the spectral drawing, "stress" uses it as the start of stress majorization over
+// the edges and the graph distances to Pivots sampled vertices, "force" as the start
+// of a Barnes–Hut spring embedder with the given Gravity and Repulsion, saving the
+// 2D drawing every Frames iterations when Frames is set
+type Refinement struct {
+	Method     string   `json:"method"`
+	Iterations int      `json:"iterations,omitempty"`
+	Pivots     int      `json:"pivots,omitempty"`
+	Gravity    *float64 `json:"gravity,omitempty"`
+	Repulsion  float64  `json:"repulsion,omitempty"`
+	Frames     int      `json:"frames,omitempty"`
+}
+
+// Projection selects how the k-dimensional spectral embedding is drawn in 2D and 3D:
+// mode "first" uses the leading eigenvectors, "pca" the principal components and
+// "axes" the eigenvectors listed in Axes (2 is the Fiedler vector)
+type Projection struct {
+	Mode string `json:"mode"`
+	Axes []int  `json:"axes,omitempty"`
+}
+
+// Value implements driver.Valuer so params can be stored in a JSONB column
+func (p JobParams) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner for reading params from a JSONB column
+func (p *JobParams) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return errors.New("unsupported type for job params")
+	}
+}
+
+// ProjectionResult describes the drawings produced by re-projecting a finished job
+type ProjectionResult struct {
+	JobID      int        `json:"job_id"`
+	Projection Projection `json:"projection"`
+	ResUrl     *string    `json:"res_url,omitempty"`
+	// LayoutMetrics holds the quality of the projected drawing
+	LayoutMetrics json.RawMessage `json:"layout_metrics,omitempty"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RenderParams holds the drawing options of a render task
+type RenderParams struct {
+	Projection    Projection         `json:"projection"`
+	Formats       []string           `json:"formats"`
+	Width         int                `json:"width"`
+	Height        int                `json:"height"`
+	Margin        int                `json:"margin,omitempty"` // free space around the drawing in pixels
+	EdgeColor     string             `json:"edge_color"`
+	EdgeAlpha     *float64           `json:"edge_alpha,omitempty"` // edge opacity, 1 when unset
+	Background    string             `json:"background"`
+	VertexColor   string             `json:"vertex_color"`
+	VertexSize    float64            `json:"vertex_size"`
+	Antialias     bool               `json:"antialias,omitempty"`       // smooth edges and vertices in the PNG
+	MinEdgeLength float64            `json:"min_edge_length,omitempty"` // merge or drop shorter edges (pixels) in SVG and PDF
+	ColorBy       string             `json:"color_by"`                  // "cluster" or "bisection" colors vertices by a partition of the job, or by component, degree, eigenvector or attribute
+	Eigenvector   int                `json:"eigenvector,omitempty"`     // eigenvector number for color_by "eigenvector", 2 is the Fiedler vector
+	Attributes    map[string]float64 `json:"attributes,omitempty"`      // vertex values for color_by "attribute", keyed by vertex id
+	SizeBy        string             `json:"size_by,omitempty"`         // "degree" sizes vertices up to vertex_size
+	EdgeColorBy   string             `json:"edge_color_by,omitempty"`   // "length" or "weight"
+	Colormap      string             `json:"colormap,omitempty"`        // viridis, plasma or categorical
+	Mode          string             `json:"mode,omitempty"`            // "lines" (default) or "density" for a density map of large graphs
+	DensityScale  string             `json:"density_scale,omitempty"`   // linear, log or eq_hist (default) in density mode
+	Refinement    Refinement         `json:"refinement"`                // defaults to the refinement of the job
+	Labels        *Labels            `json:"labels,omitempty"`          // vertices named by their id in the drawings
+}
+
+// Labels selects the vertices labeled in a drawing: mode "top" labels the Count
+// vertices of highest degree, "all" every vertex (meant for small graphs) and
+// "list" the given Vertices. Labels that would overlap are left out, keeping
+// those of higher degree or listed first. Vertices of a bipartite graph are
+// listed by id, column j of a matrix with r rows being r + j, and labeled by
+// row or column as r12 or c7.
+type Labels struct {
+	Mode     string `json:"mode"`
+	Count    int    `json:"count,omitempty"`
+	Vertices []int  `json:"vertices,omitempty"`
+}
+
+// Value implements driver.Valuer so render params can be stored in a JSONB column
+func (p RenderParams) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner for reading render params from a JSONB column
+func (p *RenderParams) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return errors.New("unsupported type for render params")
+	}
+}
+
+// Render is a task drawing the stored embedding of a finished job with new options
+type Render struct {
+	ID        int          `json:"id"`
+	JobID     int          `json:"job_id"`
+	Params    RenderParams `json:"params"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	Error     *string      `json:"error,omitempty"`
+	ResUrl    *string      `json:"res_url,omitempty"`
+	// LayoutMetrics holds the quality of the drawing, see Job.LayoutMetrics
+	LayoutMetrics json.RawMessage `json:"layout_metrics,omitempty"`
+}
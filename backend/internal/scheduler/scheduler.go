@@ -14,8 +14,10 @@ import (
 
 type Scheduler struct {
 	jobService    *service.JobService
+	renderService *service.RenderService
 	logger        *log.Logger
 	jobCreated    chan struct{}
+	renderCreated chan struct{}
 	stop          chan struct{}
 	db            *sql.DB
 	workerClient  *clients.WorkerClient
@@ -24,18 +26,22 @@ type Scheduler struct {
 
 func NewScheduler(
 	mtxService *service.JobService,
+	renderService *service.RenderService,
 	logger *log.Logger,
 	jobScheduled chan struct{},
+	renderScheduled chan struct{},
 	db *sql.DB,
 	client *clients.WorkerClient,
 ) *Scheduler {
 	s := &Scheduler{
-		jobService:   mtxService,
-		logger:       logger,
-		jobCreated:   jobScheduled,
-		db:           db,
-		stop:         make(chan struct{}),
-		workerClient: client,
+		jobService:    mtxService,
+		renderService: renderService,
+		logger:        logger,
+		jobCreated:    jobScheduled,
+		renderCreated: renderScheduled,
+		db:            db,
+		stop:          make(chan struct{}),
+		workerClient:  client,
 	}
 	return s
 }
@@ -43,6 +49,7 @@ func NewScheduler(
 func (s *Scheduler) Start() {
 	go s.taskCreator()
 	go s.pollJobStatus()
+	go s.renderRunner()
 }
 
 func (s *Scheduler) Stop() {
@@ -86,6 +93,11 @@ func (s *Scheduler) runTasks() error {
 	req := dto.JobRequest{
 		ID:      strconv.Itoa(job.ID),
 		Content: &job.Content,
+		Params:  &job.Params,
+	}
+	if job.ParentID != nil {
+		parentID := strconv.Itoa(*job.ParentID)
+		req.ParentID = &parentID
 	}
 	_, err = s.workerClient.Ping(req)
 	if err != nil {
@@ -145,6 +157,9 @@ func (s *Scheduler) pollJobStatus() {
 				resp.Status,
 				resp.Error,
 				resp.Result,
+				resp.Report,
+				resp.Partition,
+				resp.Metrics,
 				tx,
 			)
 			if err != nil {
@@ -158,3 +173,33 @@ func (s *Scheduler) pollJobStatus() {
 		<-time.After(2 * time.Second)
 	}
 }
+
+// renderRunner executes queued render tasks one at a time. Renders only draw the
+// stored embedding, so the worker handles them synchronously.
+func (s *Scheduler) renderRunner() {
+	if err := s.renderService.RequeueExecuting(); err != nil {
+		s.logger.Println("occurred error during requeueing renders", err)
+	}
+	for {
+		select {
+		case <-s.renderCreated:
+		case <-time.After(5 * time.Second):
+		case <-s.stop:
+			return
+		}
+		for {
+			render, err := s.renderService.ClaimNextRender()
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				s.logger.Println("occurred error during claiming render", err)
+				break
+			}
+			s.logger.Println(fmt.Sprintf("rendering job %v (render %v)", render.JobID, render.ID))
+			if err := s.renderService.Execute(render); err != nil {
+				s.logger.Println("occurred error during render", err)
+			}
+		}
+	}
+}
@@ -2,42 +2,412 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
 	"strings"
 
+	"backend/internal/clients"
+	"backend/internal/dto"
 	"backend/internal/models"
 	"backend/pkg/mtxparser"
 )
 
+// ErrInvalidParams is returned when a job is submitted with unsupported options
+var ErrInvalidParams = errors.New("invalid job params")
+
+// ErrJobNotFinished is returned when an operation needs the results of a job that has not completed
+var ErrJobNotFinished = errors.New("job has not finished")
+
+// maxEigenvectors bounds the number of layout eigenvectors a job may request
+const maxEigenvectors = 50
+
+// maxClusters bounds the number of k-means clusters a job may request
+const maxClusters = 100
+
+// modelFormats lists the 3D model formats a job can export its 3D embedding to
+var modelFormats = map[string]bool{"obj": true, "ply": true, "glb": true, "vtk": true}
+
+// snapshotViews lists the named camera views of 3D snapshots
+var snapshotViews = map[string]bool{"front": true, "side": true, "top": true, "iso": true}
+
+// maxSnapshots bounds the number of 3D snapshots a job may request
+const maxSnapshots = 8
+
+// Limits of the animation options
+const (
+	maxFrameEvery          = 10000
+	minAnimationDelay      = 20
+	maxAnimationDelay      = 5000
+	maxAnimationFrames     = 1000
+	defaultAnimationFrames = 200
+)
+
+// Limits and defaults of the layout refinement
+const (
+	maxRefineIterations     = 10000
+	maxStressPivots         = 1000
+	maxForceStrength        = 100
+	maxRefineFrames         = 1000
+	defaultStressIterations = 100
+	defaultStressPivots     = 50
+	defaultForceIterations  = 200
+	defaultForceGravity     = 0.05
+	defaultForceRepulsion   = 1
+)
+
 type JobService struct {
 	DB           *sql.DB
 	jobCreatedCh chan struct{}
+	workerClient *clients.WorkerClient
+}
+
+func NewJobService(db *sql.DB, jobCreatedCh chan struct{}, workerClient *clients.WorkerClient) *JobService {
+	return &JobService{DB: db, jobCreatedCh: jobCreatedCh, workerClient: workerClient}
+}
+
+// resolveInterpretation decides how the matrix is turned into an undirected graph:
+// rectangular matrices become bipartite graphs (rows and columns as two vertex sets),
+// symmetric ones are used as stored and general square ones are symmetrized.
+// Files without a readable coordinate banner, which cleaner.py accepts as
+// plain edge lists, are taken as general square matrices.
+func (s *JobService) resolveInterpretation(content string, params *models.JobParams) {
+	header, err := mtxparser.ParseHeader(content)
+	if err != nil {
+		params.Interpretation = params.Symmetrization
+		return
+	}
+	switch {
+	case header.Rows != header.Cols:
+		params.Interpretation = "bipartite"
+	case header.Symmetry != "general":
+		params.Interpretation = "symmetric"
+	default:
+		params.Interpretation = params.Symmetrization
+	}
+}
+
+func (s *JobService) checkParams(params *models.JobParams) error {
+	switch params.Coarsening {
+	case "":
+		params.Coarsening = "simple"
+	case "none", "simple", "hem", "mis":
+	default:
+		return fmt.Errorf("%w: unknown coarsening strategy %q", ErrInvalidParams, params.Coarsening)
+	}
+	if params.CoarseningLevels < 0 {
+		return fmt.Errorf("%w: coarsening levels must not be negative", ErrInvalidParams)
+	}
+	switch params.WeightMode {
+	case "":
+		params.WeightMode = "ignore"
+	case "ignore", "abs", "asis", "inverse":
+	default:
+		return fmt.Errorf("%w: unknown weight mode %q", ErrInvalidParams, params.WeightMode)
+	}
+	switch params.Symmetrization {
+	case "":
+		params.Symmetrization = "sum"
+	case "sum", "max":
+	default:
+		return fmt.Errorf("%w: unknown symmetrization %q", ErrInvalidParams, params.Symmetrization)
+	}
+	switch params.Laplacian {
+	case "":
+		params.Laplacian = "generalized"
+	case "generalized", "combinatorial", "symmetric", "random-walk", "signless":
+	default:
+		return fmt.Errorf("%w: unknown laplacian %q", ErrInvalidParams, params.Laplacian)
+	}
+	if params.Seed == nil {
+		seed := rand.Int63n(1 << 31)
+		params.Seed = &seed
+	}
+	switch {
+	case params.Eigenvectors == 0:
+		params.Eigenvectors = 3
+	case params.Eigenvectors < 2 || params.Eigenvectors > maxEigenvectors:
+		return fmt.Errorf("%w: eigenvectors must be between 2 and %d", ErrInvalidParams, maxEigenvectors)
+	}
+	if params.Clusters != 0 && (params.Clusters < 2 || params.Clusters > maxClusters) {
+		return fmt.Errorf("%w: clusters must be between 2 and %d", ErrInvalidParams, maxClusters)
+	}
+	if err := checkRefinement(&params.Refinement); err != nil {
+		return err
+	}
+	if err := checkExports(params); err != nil {
+		return err
+	}
+	if err := checkSnapshots(params); err != nil {
+		return err
+	}
+	if err := checkAnimation(&params.Animation); err != nil {
+		return err
+	}
+	return checkProjection(&params.Projection, params.Eigenvectors)
+}
+
+// checkExports validates the 3D model formats of a job, exporting OBJ as jobs
+// always did when none are given
+func checkExports(params *models.JobParams) error {
+	if len(params.Exports) == 0 {
+		params.Exports = []string{"obj"}
+	}
+	seen := make(map[string]bool)
+	exports := params.Exports[:0]
+	for _, f := range params.Exports {
+		f = strings.ToLower(f)
+		if !modelFormats[f] {
+			return fmt.Errorf("%w: unknown export format %q, expected obj, ply, glb or vtk", ErrInvalidParams, f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			exports = append(exports, f)
+		}
+	}
+	params.Exports = exports
+	return nil
+}
+
+// checkSnapshots validates the views of the 3D snapshots of a job, an isometric
+// one when none are given. A view is named or given as "azimuth:elevation" in
+// degrees.
+func checkSnapshots(params *models.JobParams) error {
+	if len(params.Snapshots) == 0 {
+		params.Snapshots = []string{"iso"}
+	}
+	if len(params.Snapshots) > maxSnapshots {
+		return fmt.Errorf("%w: at most %d snapshots", ErrInvalidParams, maxSnapshots)
+	}
+	for _, view := range params.Snapshots {
+		if snapshotViews[view] {
+			continue
+		}
+		az, el, ok := strings.Cut(view, ":")
+		a, errA := strconv.ParseFloat(az, 64)
+		e, errE := strconv.ParseFloat(el, 64)
+		if !ok || errA != nil || errE != nil || !(math.Abs(a) <= 360 && math.Abs(e) <= 90) {
+			return fmt.Errorf("%w: invalid snapshot view %q, expected front, side, top, iso or azimuth:elevation", ErrInvalidParams, view)
+		}
+	}
+	return nil
+}
+
+// checkAnimation validates the animation of a job and fills in its defaults
+func checkAnimation(a *models.Animation) error {
+	switch a.Format {
+	case "":
+		*a = models.Animation{}
+		return nil
+	case "gif", "apng", "frames":
+	default:
+		return fmt.Errorf("%w: unknown animation format %q, expected gif, apng or frames", ErrInvalidParams, a.Format)
+	}
+	if a.Every == 0 {
+		a.Every = 10
+	}
+	if a.Every < 1 || a.Every > maxFrameEvery {
+		return fmt.Errorf("%w: animation steps between frames must be between 1 and %d", ErrInvalidParams, maxFrameEvery)
+	}
+	if a.Delay == 0 {
+		a.Delay = 100
+	}
+	if a.Delay < minAnimationDelay || a.Delay > maxAnimationDelay {
+		return fmt.Errorf("%w: animation delay must be between %d and %d ms", ErrInvalidParams, minAnimationDelay, maxAnimationDelay)
+	}
+	if a.MaxFrames == 0 {
+		a.MaxFrames = defaultAnimationFrames
+	}
+	if a.MaxFrames < 2 || a.MaxFrames > maxAnimationFrames {
+		return fmt.Errorf("%w: animation frames must be between 2 and %d", ErrInvalidParams, maxAnimationFrames)
+	}
+	return nil
 }
 
-func NewJobService(db *sql.DB, jobCreatedCh chan struct{}) *JobService {
-	return &JobService{DB: db, jobCreatedCh: jobCreatedCh}
+// checkRefinement validates the layout refinement and fills in its defaults
+func checkRefinement(r *models.Refinement) error {
+	switch r.Method {
+	case "", "none":
+		*r = models.Refinement{Method: "none"}
+		return nil
+	case "stress":
+		if r.Gravity != nil || r.Repulsion != 0 || r.Frames != 0 {
+			return fmt.Errorf("%w: gravity, repulsion and frames are options of the force refinement", ErrInvalidParams)
+		}
+		if r.Iterations == 0 {
+			r.Iterations = defaultStressIterations
+		}
+		if r.Pivots == 0 {
+			r.Pivots = defaultStressPivots
+		}
+		if r.Pivots < 1 || r.Pivots > maxStressPivots {
+			return fmt.Errorf("%w: stress pivots must be between 1 and %d", ErrInvalidParams, maxStressPivots)
+		}
+	case "force":
+		if r.Pivots != 0 {
+			return fmt.Errorf("%w: pivots are an option of the stress refinement", ErrInvalidParams)
+		}
+		if r.Iterations == 0 {
+			r.Iterations = defaultForceIterations
+		}
+		if r.Gravity == nil {
+			gravity := defaultForceGravity
+			r.Gravity = &gravity
+		}
+		if !(*r.Gravity >= 0 && *r.Gravity <= maxForceStrength) {
+			return fmt.Errorf("%w: gravity must be between 0 and %d", ErrInvalidParams, maxForceStrength)
+		}
+		if r.Repulsion == 0 {
+			r.Repulsion = defaultForceRepulsion
+		}
+		if !(r.Repulsion > 0 && r.Repulsion <= maxForceStrength) {
+			return fmt.Errorf("%w: repulsion must be positive and at most %d", ErrInvalidParams, maxForceStrength)
+		}
+	default:
+		return fmt.Errorf("%w: unknown refinement %q", ErrInvalidParams, r.Method)
+	}
+	if r.Iterations < 1 || r.Iterations > maxRefineIterations {
+		return fmt.Errorf("%w: refine iterations must be between 1 and %d", ErrInvalidParams, maxRefineIterations)
+	}
+	if r.Frames < 0 || (r.Frames > 0 && r.Iterations/r.Frames > maxRefineFrames) {
+		return fmt.Errorf("%w: frames must be saved at most %d times", ErrInvalidParams, maxRefineFrames)
+	}
+	return nil
 }
 
-func (s *JobService) checkDimensions(dimension string) error {
-	parts := strings.Split(dimension, ":")
-	if len(parts) != 2 {
-		return errors.New("invalid dimension")
+// checkProjection validates a projection of a k-dimensional embedding, defaulting to
+// the leading eigenvectors. Axes are eigenvector numbers 2..k+1.
+func checkProjection(p *models.Projection, k int) error {
+	switch p.Mode {
+	case "":
+		p.Mode = "first"
+	case "first", "pca", "axes":
+	default:
+		return fmt.Errorf("%w: unknown projection %q", ErrInvalidParams, p.Mode)
+	}
+	if p.Mode != "axes" {
+		if len(p.Axes) > 0 {
+			return fmt.Errorf("%w: projection axes are only used with projection \"axes\"", ErrInvalidParams)
+		}
+		return nil
+	}
+	if len(p.Axes) < 2 || len(p.Axes) > 3 {
+		return fmt.Errorf("%w: projection \"axes\" needs 2 or 3 eigenvectors", ErrInvalidParams)
 	}
-	if parts[0] != parts[1] {
-		return errors.New("dimension mismatch")
+	seen := make(map[int]bool)
+	for _, a := range p.Axes {
+		if a < 2 || a > k+1 {
+			return fmt.Errorf("%w: eigenvector %d is not computed, axes must be between 2 and %d", ErrInvalidParams, a, k+1)
+		}
+		if seen[a] {
+			return fmt.Errorf("%w: eigenvector %d is selected twice", ErrInvalidParams, a)
+		}
+		seen[a] = true
 	}
 	return nil
 }
 
-func (s *JobService) SaveJob(filename string, content string) (int, error) {
+// checkWeights rejects matrices with negative values when they are used as weights as-is
+func (s *JobService) checkWeights(content string, params models.JobParams) error {
+	if params.WeightMode != "asis" {
+		return nil
+	}
+	_, err := mtxparser.EachEntry(content, func(e mtxparser.Entry) error {
+		if e.Value < 0 {
+			return fmt.Errorf("%w: negative weight %v at (%d, %d), use weight_mode abs or inverse", ErrInvalidParams, e.Value, e.Row, e.Col)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrInvalidParams) {
+		return fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	return err
+}
+
+// inheritParams fills the drawing options left unset for a new version of a graph
+// with those of its parent, so that the versions are drawn alike. Bisection is
+// unset when false, so a child of a bisected job is bisected too.
+func inheritParams(params *models.JobParams, parent models.JobParams) {
+	if params.Coarsening == "" {
+		params.Coarsening = parent.Coarsening
+	}
+	if params.CoarseningLevels == 0 {
+		params.CoarseningLevels = parent.CoarseningLevels
+	}
+	if params.WeightMode == "" {
+		params.WeightMode = parent.WeightMode
+	}
+	if params.Symmetrization == "" {
+		params.Symmetrization = parent.Symmetrization
+	}
+	if params.Laplacian == "" {
+		params.Laplacian = parent.Laplacian
+	}
+	if params.Seed == nil {
+		params.Seed = parent.Seed
+	}
+	if params.Eigenvectors == 0 {
+		params.Eigenvectors = parent.Eigenvectors
+	}
+	if params.Projection.Mode == "" {
+		params.Projection = parent.Projection
+	}
+	if params.Refinement.Method == "" {
+		params.Refinement = parent.Refinement
+	}
+	if params.Clusters == 0 {
+		params.Clusters = parent.Clusters
+	}
+	if !params.Bisection {
+		params.Bisection = parent.Bisection
+	}
+	if len(params.Exports) == 0 {
+		params.Exports = parent.Exports
+	}
+	if len(params.Snapshots) == 0 {
+		params.Snapshots = parent.Snapshots
+	}
+	if params.Animation.Format == "" {
+		params.Animation = parent.Animation
+	}
+}
+
+// SaveJob stores a new job. With a parentID the graph is a new version of the
+// graph of that job: its solver is warm-started from the parent's eigenvectors and
+// it inherits the parent's drawing options, so the parent must have completed.
+func (s *JobService) SaveJob(filename string, content string, params models.JobParams, parentID *int) (int, error) {
+	version := 1
+	if parentID != nil {
+		parent, err := s.GetJobWithNoContent(*parentID)
+		if err != nil {
+			if err.Error() == "file not found" {
+				return 0, fmt.Errorf("%w: parent job %d not found", ErrInvalidParams, *parentID)
+			}
+			return 0, err
+		}
+		if parent.Status != "completed" || parent.Error != nil {
+			return 0, fmt.Errorf("%w: parent job %d", ErrJobNotFinished, *parentID)
+		}
+		inheritParams(&params, parent.Params)
+		version = parent.Version + 1
+	}
+	if err := s.checkParams(&params); err != nil {
+		return 0, err
+	}
+	if err := s.checkWeights(content, params); err != nil {
+		return 0, err
+	}
+	s.resolveInterpretation(content, &params)
 	// Parse dimensions from content
 	dimensions := mtxparser.ParseDimensions(content)
 
 	var id int
 	err := s.DB.QueryRow(
-		"INSERT INTO jobs (filename, content, dimensions) VALUES ($1, $2, $3) RETURNING id",
-		filename, content, dimensions,
+		"INSERT INTO jobs (filename, content, dimensions, params, parent_id, version) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		filename, content, dimensions, params, parentID, version,
 	).Scan(&id)
 	s.jobCreatedCh <- struct{}{}
 	return id, err
@@ -46,9 +416,9 @@ func (s *JobService) SaveJob(filename string, content string) (int, error) {
 func (s *JobService) GetJob(id int) (models.Job, error) {
 	var file models.Job
 	err := s.DB.QueryRow(
-		"SELECT id, filename, content, dimensions, created_at, status FROM jobs WHERE id = $1",
+		"SELECT id, filename, content, dimensions, created_at, status, params, parent_id, version FROM jobs WHERE id = $1",
 		id,
-	).Scan(&file.ID, &file.Filename, &file.Content, &file.Dimensions, &file.CreatedAt, &file.Status)
+	).Scan(&file.ID, &file.Filename, &file.Content, &file.Dimensions, &file.CreatedAt, &file.Status, &file.Params, &file.ParentID, &file.Version)
 
 	if err == sql.ErrNoRows {
 		return file, errors.New("file not found")
@@ -59,10 +429,14 @@ func (s *JobService) GetJob(id int) (models.Job, error) {
 
 func (s *JobService) GetJobWithNoContent(id int) (models.Job, error) {
 	var file models.Job
+	var report, partition, layoutMetrics []byte
 	err := s.DB.QueryRow(
-		"SELECT id, filename, dimensions, created_at, status, error, result_url FROM jobs WHERE id = $1",
+		"SELECT id, filename, dimensions, created_at, status, error, result_url, params, spectral_report, partition, layout_metrics, parent_id, version FROM jobs WHERE id = $1",
 		id,
-	).Scan(&file.ID, &file.Filename, &file.Dimensions, &file.CreatedAt, &file.Status, &file.Error, &file.ResUrl)
+	).Scan(&file.ID, &file.Filename, &file.Dimensions, &file.CreatedAt, &file.Status, &file.Error, &file.ResUrl, &file.Params, &report, &partition, &layoutMetrics, &file.ParentID, &file.Version)
+	file.SpectralReport = report
+	file.Partition = partition
+	file.LayoutMetrics = layoutMetrics
 
 	if err == sql.ErrNoRows {
 		return file, errors.New("file not found")
@@ -74,9 +448,9 @@ func (s *JobService) GetJobWithNoContent(id int) (models.Job, error) {
 func (s *JobService) ListJobs(status *string) ([]models.JobList, error) {
 	var rows *sql.Rows
 	var err error
-	que := "SELECT id, filename, dimensions, created_at FROM jobs ORDER BY created_at DESC"
+	que := "SELECT id, filename, dimensions, created_at, parent_id, version, status = 'completed' AND error IS NULL FROM jobs ORDER BY created_at DESC"
 	if status != nil {
-		que = "SELECT id, filename, dimensions, created_at FROM jobs WHERE status=$1 ORDER BY created_at DESC"
+		que = "SELECT id, filename, dimensions, created_at, parent_id, version, status = 'completed' AND error IS NULL FROM jobs WHERE status=$1 ORDER BY created_at DESC"
 		rows, err = s.DB.Query(
 			que,
 			*status,
@@ -89,18 +463,55 @@ func (s *JobService) ListJobs(status *string) ([]models.JobList, error) {
 	if err != nil {
 		return nil, err
 	}
+	return scanJobList(rows)
+}
+
+// ListVersions returns all versions of the graph of job id: the first version,
+// which has no parent, and every job derived from it, oldest versions first
+func (s *JobService) ListVersions(id int) ([]models.JobList, error) {
+	if _, err := s.GetJobWithNoContent(id); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query(`
+        WITH RECURSIVE ancestors AS (
+            SELECT id, parent_id FROM jobs WHERE id = $1
+            UNION ALL
+            SELECT j.id, j.parent_id FROM jobs j JOIN ancestors a ON j.id = a.parent_id
+        ), versions AS (
+            SELECT id FROM ancestors WHERE parent_id IS NULL
+            UNION ALL
+            SELECT j.id FROM jobs j JOIN versions v ON j.parent_id = v.id
+        )
+        SELECT id, filename, dimensions, created_at, parent_id, version, status = 'completed' AND error IS NULL FROM jobs
+        WHERE id IN (SELECT id FROM versions) ORDER BY version, created_at`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobList(rows)
+}
+
+// scanJobList reads the rows of a job list query; the last column tells whether
+// the job finished successfully and so has a thumbnail
+func scanJobList(rows *sql.Rows) ([]models.JobList, error) {
 	defer rows.Close()
 
 	var files []models.JobList
 	for rows.Next() {
 		var file models.JobList
-		if err := rows.Scan(&file.ID, &file.Filename, &file.Dimensions, &file.CreatedAt); err != nil {
+		var finished bool
+		if err := rows.Scan(&file.ID, &file.Filename, &file.Dimensions, &file.CreatedAt, &file.ParentID, &file.Version, &finished); err != nil {
 			return nil, err
 		}
+		if finished {
+			url := fmt.Sprintf("/api/jobs/%d/thumbnail", file.ID)
+			file.ThumbnailURL = &url
+		}
 		files = append(files, file)
 	}
 
-	return files, nil
+	return files, rows.Err()
 }
 
 func (s *JobService) SetStatus(id int, status string, tx *sql.Tx) error {
@@ -109,7 +520,7 @@ func (s *JobService) SetStatus(id int, status string, tx *sql.Tx) error {
 	return err
 }
 
-func (t *JobService) CompleteTaskInTx(id int, status string, errorMsg *string, resURL *string, tx *sql.Tx) error {
+func (t *JobService) CompleteTaskInTx(id int, status string, errorMsg *string, resURL *string, report, partition, layoutMetrics json.RawMessage, tx *sql.Tx) error {
 	var query string
 	var args []interface{}
 
@@ -136,6 +547,24 @@ func (t *JobService) CompleteTaskInTx(id int, status string, errorMsg *string, r
 		return fmt.Errorf("no job found with ID: %d", id)
 	}
 
+	if len(report) > 0 {
+		if _, err := tx.Exec(`UPDATE jobs SET spectral_report = $1 WHERE id = $2`, []byte(report), id); err != nil {
+			return fmt.Errorf("failed to save spectral report: %w", err)
+		}
+	}
+
+	if len(partition) > 0 {
+		if _, err := tx.Exec(`UPDATE jobs SET partition = $1 WHERE id = $2`, []byte(partition), id); err != nil {
+			return fmt.Errorf("failed to save partition: %w", err)
+		}
+	}
+
+	if len(layoutMetrics) > 0 {
+		if _, err := tx.Exec(`UPDATE jobs SET layout_metrics = $1 WHERE id = $2`, []byte(layoutMetrics), id); err != nil {
+			return fmt.Errorf("failed to save layout metrics: %w", err)
+		}
+	}
+
 	if resURL == nil {
 		return nil
 	}
@@ -159,3 +588,167 @@ func (t *JobService) CompleteTaskInTx(id int, status string, errorMsg *string, r
 
 	return nil
 }
+
+// ProjectJob draws the stored eigenvectors of a finished job with another projection,
+// without recomputing the embedding
+func (s *JobService) ProjectJob(id int, projection models.Projection) (models.ProjectionResult, error) {
+	result := models.ProjectionResult{JobID: id}
+	job, err := s.GetJobWithNoContent(id)
+	if err != nil {
+		return result, err
+	}
+	if job.Status != "completed" || job.Error != nil {
+		return result, ErrJobNotFinished
+	}
+	k := job.Params.Eigenvectors
+	if k == 0 {
+		// jobs submitted before eigenvectors became a parameter
+		k = 2
+	}
+	if err := checkProjection(&projection, k); err != nil {
+		return result, err
+	}
+	result.Projection = projection
+
+	resp, err := s.workerClient.Project(dto.ProjectionRequest{
+		ID:         strconv.Itoa(id),
+		Projection: projection,
+		Refinement: job.Params.Refinement,
+	})
+	if err != nil {
+		return result, err
+	}
+	if resp.Err != nil {
+		return result, fmt.Errorf("projection failed: %s", *resp.Err)
+	}
+	result.ResUrl = resp.Result
+	result.LayoutMetrics = resp.Metrics
+	return result, nil
+}
+
+// CompareJobs aligns the drawing of job id to the drawing of job baseID, so layouts
+// computed with other parameters or from another version of the graph can be
+// compared despite rotations and reflections
+func (s *JobService) CompareJobs(baseID, id int) (models.Comparison, error) {
+	result := models.Comparison{BaseJobID: baseID, JobID: id}
+	if err := s.checkPair(baseID, id); err != nil {
+		return result, err
+	}
+
+	resp, err := s.workerClient.Compare(dto.CompareRequest{
+		ID:      strconv.Itoa(baseID),
+		OtherID: strconv.Itoa(id),
+	})
+	if err != nil {
+		return result, err
+	}
+	if resp.Err != nil {
+		return result, fmt.Errorf("comparison failed: %s", *resp.Err)
+	}
+	result.Alignment = resp.Alignment
+	result.ResUrl = resp.Result
+	return result, nil
+}
+
+// DiffJobs compares the graph of job id to the graph of job baseID, matching
+// vertices by id as for versions of a graph, and draws the added and removed
+// edges on the aligned layouts
+func (s *JobService) DiffJobs(baseID, id int) (models.GraphDiff, error) {
+	result := models.GraphDiff{BaseJobID: baseID, JobID: id}
+	if err := s.checkPair(baseID, id); err != nil {
+		return result, err
+	}
+
+	resp, err := s.workerClient.Diff(dto.CompareRequest{
+		ID:      strconv.Itoa(baseID),
+		OtherID: strconv.Itoa(id),
+	})
+	if err != nil {
+		return result, err
+	}
+	if resp.Err != nil {
+		return result, fmt.Errorf("diff failed: %s", *resp.Err)
+	}
+	result.Diff = resp.Diff
+	result.ResUrl = resp.Result
+	return result, nil
+}
+
+// maxTileZoom is the deepest zoom level of the map of a job
+const maxTileZoom = 16
+
+// GetTile returns tile (x, y) of zoom level z of the map of a finished job as
+// PNG. The worker serves tiles of the pyramid drawn with the job and draws and
+// caches missing ones on demand.
+func (s *JobService) GetTile(id, z, x, y int) ([]byte, error) {
+	if z < 0 || z > maxTileZoom {
+		return nil, fmt.Errorf("%w: zoom must be between 0 and %d", ErrInvalidParams, maxTileZoom)
+	}
+	if x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return nil, fmt.Errorf("%w: tile %d/%d/%d is outside the map", ErrInvalidParams, z, x, y)
+	}
+	job, err := s.GetJobWithNoContent(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != "completed" || job.Error != nil {
+		return nil, ErrJobNotFinished
+	}
+	tile, err := s.workerClient.Tile(dto.TileRequest{ID: strconv.Itoa(id), Z: z, X: x, Y: y})
+	if errors.Is(err, clients.ErrNotFound) {
+		return nil, errors.New("file not found")
+	}
+	return tile, err
+}
+
+// GetThumbnail returns the small PNG preview of the drawing of a finished job.
+// The worker draws it with the job, or on the first request for older jobs.
+func (s *JobService) GetThumbnail(id int) ([]byte, error) {
+	job, err := s.GetJobWithNoContent(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != "completed" || job.Error != nil {
+		return nil, ErrJobNotFinished
+	}
+	thumbnail, err := s.workerClient.Thumbnail(dto.ThumbnailRequest{ID: strconv.Itoa(id)})
+	if errors.Is(err, clients.ErrNotFound) {
+		return nil, errors.New("file not found")
+	}
+	return thumbnail, err
+}
+
+// GetVertex returns the position of vertex vid in the 2D drawing of a finished
+// job with its degree and neighbors. Vertices are numbered as in graph.txt: in
+// a bipartite graph of a matrix with r rows column j is vertex r + j.
+func (s *JobService) GetVertex(id, vid int) (*dto.VertexInfo, error) {
+	job, err := s.GetJobWithNoContent(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != "completed" || job.Error != nil {
+		return nil, ErrJobNotFinished
+	}
+	info, err := s.workerClient.Vertex(dto.VertexRequest{ID: strconv.Itoa(id), Vertex: vid})
+	if errors.Is(err, clients.ErrNotFound) {
+		return nil, errors.New("vertex not found")
+	}
+	return info, err
+}
+
+// checkPair checks that two distinct jobs exist and have completed
+func (s *JobService) checkPair(baseID, id int) error {
+	if baseID == id {
+		return fmt.Errorf("%w: cannot compare a job with itself", ErrInvalidParams)
+	}
+	for _, jobID := range []int{baseID, id} {
+		job, err := s.GetJobWithNoContent(jobID)
+		if err != nil {
+			return err
+		}
+		if job.Status != "completed" || job.Error != nil {
+			return fmt.Errorf("%w: job %d", ErrJobNotFinished, jobID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestCheckParams(t *testing.T) {
+	seed := int64(7)
+	gravity, negative := 0.1, -1.0
+	tests := []struct {
+		name   string
+		params models.JobParams
+		want   *models.JobParams // the params after the defaults, nil when invalid
+	}{
+		{"defaults", models.JobParams{Seed: &seed}, &models.JobParams{
+			Coarsening: "simple", Seed: &seed, WeightMode: "ignore", Symmetrization: "sum", Laplacian: "generalized",
+			Eigenvectors: 3, Projection: models.Projection{Mode: "first"}, Refinement: models.Refinement{Method: "none"},
+			Exports: []string{"obj"}, Snapshots: []string{"iso"},
+		}},
+		{"exports lower case without repeats", models.JobParams{Seed: &seed, Exports: []string{"GLB", "ply", "glb"}, Snapshots: []string{"top", "30:-45"}},
+			&models.JobParams{
+				Coarsening: "simple", Seed: &seed, WeightMode: "ignore", Symmetrization: "sum", Laplacian: "generalized",
+				Eigenvectors: 3, Projection: models.Projection{Mode: "first"}, Refinement: models.Refinement{Method: "none"},
+				Exports: []string{"glb", "ply"}, Snapshots: []string{"top", "30:-45"},
+			}},
+		{"animation defaults", models.JobParams{Seed: &seed, Animation: models.Animation{Format: "gif"}},
+			&models.JobParams{
+				Coarsening: "simple", Seed: &seed, WeightMode: "ignore", Symmetrization: "sum", Laplacian: "generalized",
+				Eigenvectors: 3, Projection: models.Projection{Mode: "first"}, Refinement: models.Refinement{Method: "none"},
+				Exports: []string{"obj"}, Snapshots: []string{"iso"},
+				Animation: models.Animation{Format: "gif", Every: 10, Delay: 100, MaxFrames: 200},
+			}},
+		{"stress defaults", models.JobParams{Seed: &seed, Eigenvectors: 4, Refinement: models.Refinement{Method: "stress"},
+			Projection: models.Projection{Mode: "axes", Axes: []int{2, 5}}},
+			&models.JobParams{
+				Coarsening: "simple", Seed: &seed, WeightMode: "ignore", Symmetrization: "sum", Laplacian: "generalized",
+				Eigenvectors: 4, Projection: models.Projection{Mode: "axes", Axes: []int{2, 5}},
+				Refinement: models.Refinement{Method: "stress", Iterations: 100, Pivots: 50},
+				Exports:    []string{"obj"}, Snapshots: []string{"iso"},
+			}},
+		{"unknown coarsening", models.JobParams{Coarsening: "metis"}, nil},
+		{"negative coarsening levels", models.JobParams{CoarseningLevels: -1}, nil},
+		{"unknown weight mode", models.JobParams{WeightMode: "square"}, nil},
+		{"unknown symmetrization", models.JobParams{Symmetrization: "min"}, nil},
+		{"unknown laplacian", models.JobParams{Laplacian: "normalized"}, nil},
+		{"one eigenvector", models.JobParams{Eigenvectors: 1}, nil},
+		{"too many eigenvectors", models.JobParams{Eigenvectors: maxEigenvectors + 1}, nil},
+		{"one cluster", models.JobParams{Clusters: 1}, nil},
+		{"too many clusters", models.JobParams{Clusters: maxClusters + 1}, nil},
+		{"unknown refinement", models.JobParams{Refinement: models.Refinement{Method: "tsne"}}, nil},
+		{"stress with gravity", models.JobParams{Refinement: models.Refinement{Method: "stress", Gravity: &gravity}}, nil},
+		{"negative gravity", models.JobParams{Refinement: models.Refinement{Method: "force", Gravity: &negative}}, nil},
+		{"too many refine frames", models.JobParams{Refinement: models.Refinement{Method: "force", Iterations: 5000, Frames: 1}}, nil},
+		{"unknown export", models.JobParams{Exports: []string{"stl"}}, nil},
+		{"invalid snapshot", models.JobParams{Snapshots: []string{"30:100"}}, nil},
+		{"too many snapshots", models.JobParams{Snapshots: []string{"iso", "iso", "iso", "iso", "iso", "iso", "iso", "iso", "iso"}}, nil},
+		{"unknown animation", models.JobParams{Animation: models.Animation{Format: "mp4"}}, nil},
+		{"animation too fast", models.JobParams{Animation: models.Animation{Format: "gif", Delay: 1}}, nil},
+		{"too many animation frames", models.JobParams{Animation: models.Animation{Format: "apng", MaxFrames: maxAnimationFrames + 1}}, nil},
+		{"axes not computed", models.JobParams{Projection: models.Projection{Mode: "axes", Axes: []int{2, 5}}}, nil},
+		{"axes repeated", models.JobParams{Projection: models.Projection{Mode: "axes", Axes: []int{2, 2}}}, nil},
+		{"axes without mode", models.JobParams{Projection: models.Projection{Mode: "pca", Axes: []int{2, 3}}}, nil},
+	}
+	s := &JobService{}
+	for _, tt := range tests {
+		params := tt.params
+		err := s.checkParams(&params)
+		if tt.want == nil {
+			if !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("%s: error %v, want ErrInvalidParams", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(params, *tt.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", tt.name, params, *tt.want)
+		}
+	}
+}
+
+func TestCheckParamsSeed(t *testing.T) {
+	var params models.JobParams
+	if err := (&JobService{}).checkParams(&params); err != nil {
+		t.Fatal(err)
+	}
+	if params.Seed == nil || *params.Seed < 0 {
+		t.Errorf("no seed was drawn: %v", params.Seed)
+	}
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"backend/internal/clients"
+	"backend/internal/dto"
+	"backend/internal/models"
+)
+
+// ErrRenderNotFound is returned when a render task does not exist for the given job
+var ErrRenderNotFound = errors.New("render not found")
+
+// Limits of the render options
+const (
+	maxRenderSize = 8000
+	maxVertexSize = 50
+	maxMinEdge    = 10
+	maxAttributes = 1000000
+	maxLabels     = 1000
+	defaultLabels = 10
+	defaultWidth  = 1200
+	defaultHeight = 800
+	defaultFormat = "png"
+)
+
+var colorPattern = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// renderFormats lists the artifact formats a render task can produce: drawings
+// of the 2D embedding and 3D models of the 3D one
+var renderFormats = map[string]bool{"png": true, "svg": true, "pdf": true, "obj": true, "ply": true, "glb": true, "vtk": true}
+
+// colormaps lists the colormaps vertices and edges can be colored with
+var colormaps = map[string]bool{"viridis": true, "plasma": true, "categorical": true}
+
+// densityScales lists the scales of density maps
+var densityScales = map[string]bool{"linear": true, "log": true, "eq_hist": true}
+
+type RenderService struct {
+	DB              *sql.DB
+	jobService      *JobService
+	renderCreatedCh chan struct{}
+	workerClient    *clients.WorkerClient
+}
+
+func NewRenderService(db *sql.DB, jobService *JobService, renderCreatedCh chan struct{}, workerClient *clients.WorkerClient) *RenderService {
+	return &RenderService{
+		DB:              db,
+		jobService:      jobService,
+		renderCreatedCh: renderCreatedCh,
+		workerClient:    workerClient,
+	}
+}
+
+// checkRenderParams validates render options of a job and fills in defaults
+func checkRenderParams(params *models.RenderParams, job models.Job) error {
+	k := job.Params.Eigenvectors
+	if k == 0 {
+		// jobs submitted before eigenvectors became a parameter
+		k = 2
+	}
+	if err := checkProjection(&params.Projection, k); err != nil {
+		return err
+	}
+	if params.Refinement.Method == "" {
+		params.Refinement = job.Params.Refinement
+	}
+	if err := checkRefinement(&params.Refinement); err != nil {
+		return err
+	}
+	switch params.ColorBy {
+	case "", "component", "degree":
+	case "eigenvector":
+		if params.Eigenvector == 0 {
+			params.Eigenvector = 2
+		}
+		if params.Eigenvector < 2 || params.Eigenvector > k+1 {
+			return fmt.Errorf("%w: eigenvector must be between 2 and %d", ErrInvalidParams, k+1)
+		}
+	case "attribute":
+		if len(params.Attributes) == 0 {
+			return fmt.Errorf("%w: color_by attribute needs attributes", ErrInvalidParams)
+		}
+	case "cluster":
+		if job.Params.Clusters == 0 {
+			return fmt.Errorf("%w: job was not clustered, submit it with clusters", ErrInvalidParams)
+		}
+	case "bisection":
+		if !job.Params.Bisection {
+			return fmt.Errorf("%w: job was not bisected, submit it with bisection", ErrInvalidParams)
+		}
+	default:
+		return fmt.Errorf("%w: unknown color_by %q", ErrInvalidParams, params.ColorBy)
+	}
+	if params.ColorBy != "eigenvector" {
+		params.Eigenvector = 0
+	}
+	if params.ColorBy != "attribute" {
+		params.Attributes = nil
+	}
+	if len(params.Attributes) > maxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidParams, maxAttributes)
+	}
+	for key := range params.Attributes {
+		if v, err := strconv.Atoi(key); err != nil || v < 0 {
+			return fmt.Errorf("%w: invalid attribute vertex %q", ErrInvalidParams, key)
+		}
+	}
+	if params.SizeBy != "" && params.SizeBy != "degree" {
+		return fmt.Errorf("%w: unknown size_by %q", ErrInvalidParams, params.SizeBy)
+	}
+	if params.EdgeColorBy != "" && params.EdgeColorBy != "length" && params.EdgeColorBy != "weight" {
+		return fmt.Errorf("%w: unknown edge_color_by %q", ErrInvalidParams, params.EdgeColorBy)
+	}
+	if params.Colormap != "" && !colormaps[params.Colormap] {
+		return fmt.Errorf("%w: unknown colormap %q, expected viridis, plasma or categorical", ErrInvalidParams, params.Colormap)
+	}
+	if params.Width == 0 && params.Height == 0 {
+		params.Width, params.Height = defaultWidth, defaultHeight
+	}
+	if params.Width < 1 || params.Height < 1 || params.Width > maxRenderSize || params.Height > maxRenderSize {
+		return fmt.Errorf("%w: image size must be between 1 and %d pixels", ErrInvalidParams, maxRenderSize)
+	}
+	if params.VertexSize < 0 || params.VertexSize > maxVertexSize {
+		return fmt.Errorf("%w: vertex size must be between 0 and %d", ErrInvalidParams, maxVertexSize)
+	}
+	if params.Margin < 0 || 2*params.Margin >= min(params.Width, params.Height) {
+		return fmt.Errorf("%w: margin must be less than half the image size", ErrInvalidParams)
+	}
+	if params.EdgeAlpha != nil && !(*params.EdgeAlpha > 0 && *params.EdgeAlpha <= 1) {
+		return fmt.Errorf("%w: edge alpha must be greater than 0 and at most 1", ErrInvalidParams)
+	}
+	if params.MinEdgeLength < 0 || params.MinEdgeLength > maxMinEdge {
+		return fmt.Errorf("%w: min edge length must be between 0 and %d pixels", ErrInvalidParams, maxMinEdge)
+	}
+	for _, c := range []*string{&params.EdgeColor, &params.Background, &params.VertexColor} {
+		if *c == "" {
+			continue
+		}
+		if !colorPattern.MatchString(*c) {
+			return fmt.Errorf("%w: invalid color %q, expected #rrggbb", ErrInvalidParams, *c)
+		}
+		*c = "#" + strings.ToLower(strings.TrimPrefix(*c, "#"))
+	}
+	if len(params.Formats) == 0 {
+		params.Formats = []string{defaultFormat}
+	}
+	seen := make(map[string]bool)
+	formats := params.Formats[:0]
+	for _, f := range params.Formats {
+		f = strings.ToLower(f)
+		if !renderFormats[f] {
+			return fmt.Errorf("%w: unknown render format %q", ErrInvalidParams, f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			formats = append(formats, f)
+		}
+	}
+	params.Formats = formats
+	if err := checkLabels(params.Labels); err != nil {
+		return err
+	}
+	return checkRenderMode(params)
+}
+
+// checkLabels validates the vertex labels of a render, labeling the
+// defaultLabels vertices of highest degree when mode "top" has no count
+func checkLabels(l *models.Labels) error {
+	if l == nil {
+		return nil
+	}
+	switch l.Mode {
+	case "top":
+		if l.Count == 0 {
+			l.Count = defaultLabels
+		}
+		if l.Count < 1 || l.Count > maxLabels {
+			return fmt.Errorf("%w: label count must be between 1 and %d", ErrInvalidParams, maxLabels)
+		}
+		l.Vertices = nil
+	case "all":
+		l.Count, l.Vertices = 0, nil
+	case "list":
+		if len(l.Vertices) == 0 || len(l.Vertices) > maxLabels {
+			return fmt.Errorf("%w: labels need between 1 and %d vertices", ErrInvalidParams, maxLabels)
+		}
+		for _, v := range l.Vertices {
+			if v < 1 {
+				return fmt.Errorf("%w: invalid label vertex %d", ErrInvalidParams, v)
+			}
+		}
+		l.Count = 0
+	default:
+		return fmt.Errorf("%w: unknown labels mode %q, expected top, all or list", ErrInvalidParams, l.Mode)
+	}
+	return nil
+}
+
+// checkRenderMode validates the drawing mode: a density map is a PNG of its own
+// and does not take the vertex and edge styling of line drawings
+func checkRenderMode(params *models.RenderParams) error {
+	switch params.Mode {
+	case "", "lines":
+		params.Mode = ""
+		params.DensityScale = ""
+		return nil
+	case "density":
+	default:
+		return fmt.Errorf("%w: unknown mode %q, expected lines or density", ErrInvalidParams, params.Mode)
+	}
+	if params.DensityScale == "" {
+		params.DensityScale = "eq_hist"
+	}
+	if !densityScales[params.DensityScale] {
+		return fmt.Errorf("%w: unknown density scale %q, expected linear, log or eq_hist", ErrInvalidParams, params.DensityScale)
+	}
+	for _, f := range params.Formats {
+		if f == "svg" || f == "pdf" {
+			return fmt.Errorf("%w: density mode has no %s output", ErrInvalidParams, f)
+		}
+	}
+	if params.ColorBy != "" || params.SizeBy != "" || params.EdgeColorBy != "" || params.Labels != nil {
+		return fmt.Errorf("%w: color_by, size_by, edge_color_by and labels do not apply to density mode", ErrInvalidParams)
+	}
+	return nil
+}
+
+// CreateRender queues a render task for a finished job
+func (s *RenderService) CreateRender(jobID int, params models.RenderParams) (models.Render, error) {
+	render := models.Render{JobID: jobID}
+	job, err := s.jobService.GetJobWithNoContent(jobID)
+	if err != nil {
+		return render, err
+	}
+	if job.Status != "completed" || job.Error != nil {
+		return render, ErrJobNotFinished
+	}
+	if err := checkRenderParams(&params, job); err != nil {
+		return render, err
+	}
+
+	err = s.DB.QueryRow(
+		"INSERT INTO renders (job_id, params) VALUES ($1, $2) RETURNING id, status, created_at",
+		jobID, params,
+	).Scan(&render.ID, &render.Status, &render.CreatedAt)
+	if err != nil {
+		return render, err
+	}
+	render.Params = params
+	select {
+	case s.renderCreatedCh <- struct{}{}:
+	default:
+	}
+	return render, nil
+}
+
+// GetRender returns a render task of a job
+func (s *RenderService) GetRender(jobID, id int) (models.Render, error) {
+	var render models.Render
+	var layoutMetrics []byte
+	err := s.DB.QueryRow(
+		"SELECT id, job_id, params, status, created_at, error, result_url, layout_metrics FROM renders WHERE id = $1 AND job_id = $2",
+		id, jobID,
+	).Scan(&render.ID, &render.JobID, &render.Params, &render.Status, &render.CreatedAt, &render.Error, &render.ResUrl, &layoutMetrics)
+	render.LayoutMetrics = layoutMetrics
+	if err == sql.ErrNoRows {
+		return render, ErrRenderNotFound
+	}
+	return render, err
+}
+
+// ListRenders returns the render tasks of a job, newest first
+func (s *RenderService) ListRenders(jobID int) ([]models.Render, error) {
+	rows, err := s.DB.Query(
+		"SELECT id, job_id, params, status, created_at, error, result_url, layout_metrics FROM renders WHERE job_id = $1 ORDER BY created_at DESC",
+		jobID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	renders := []models.Render{}
+	for rows.Next() {
+		var render models.Render
+		var layoutMetrics []byte
+		if err := rows.Scan(&render.ID, &render.JobID, &render.Params, &render.Status, &render.CreatedAt, &render.Error, &render.ResUrl, &layoutMetrics); err != nil {
+			return nil, err
+		}
+		render.LayoutMetrics = layoutMetrics
+		renders = append(renders, render)
+	}
+	return renders, rows.Err()
+}
+
+// ClaimNextRender marks the oldest created render task as executing and returns it.
+// Returns sql.ErrNoRows when nothing is queued.
+func (s *RenderService) ClaimNextRender() (models.Render, error) {
+	var render models.Render
+	err := s.DB.QueryRow(`
+		UPDATE renders SET status = 'executing'
+		WHERE id = (SELECT id FROM renders WHERE status = 'created' ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING id, job_id, params, status, created_at`,
+	).Scan(&render.ID, &render.JobID, &render.Params, &render.Status, &render.CreatedAt)
+	return render, err
+}
+
+// RequeueExecuting puts render tasks interrupted by a restart back into the queue
+func (s *RenderService) RequeueExecuting() error {
+	_, err := s.DB.Exec("UPDATE renders SET status = 'created' WHERE status = 'executing'")
+	return err
+}
+
+// Execute runs a claimed render task on the worker and stores its outcome
+func (s *RenderService) Execute(render models.Render) error {
+	resp, err := s.workerClient.Render(dto.RenderRequest{
+		ID:       strconv.Itoa(render.JobID),
+		RenderID: strconv.Itoa(render.ID),
+		Params:   render.Params,
+	})
+	if err != nil {
+		msg := err.Error()
+		return s.completeRender(render.ID, &msg, nil, nil)
+	}
+	return s.completeRender(render.ID, resp.Err, resp.Result, resp.Metrics)
+}
+
+func (s *RenderService) completeRender(id int, errorMsg *string, resURL *string, layoutMetrics json.RawMessage) error {
+	var metrics []byte
+	if len(layoutMetrics) > 0 {
+		metrics = layoutMetrics
+	}
+	_, err := s.DB.Exec(
+		"UPDATE renders SET status = 'completed', error = $1, result_url = $2, layout_metrics = $3 WHERE id = $4",
+		errorMsg, resURL, metrics, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete render: %w", err)
+	}
+	return nil
+}
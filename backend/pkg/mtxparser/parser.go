@@ -1,7 +1,10 @@
 package mtxparser
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -33,3 +36,109 @@ func ParseDimensions(content string) string {
 
 	return "unknown"
 }
+
+// Header holds the Matrix Market banner and size line
+type Header struct {
+	Format   string // coordinate or array
+	Field    string // real, integer, complex or pattern
+	Symmetry string // general, symmetric, skew-symmetric or hermitian
+	Rows     int
+	Cols     int
+	NNZ      int
+}
+
+// Entry is a stored matrix entry with 1-based indices as in the file
+type Entry struct {
+	Row   int
+	Col   int
+	Value float64
+}
+
+// ErrNoBanner is returned for content without a %%MatrixMarket banner, which
+// is a plain coordinate list to the rest of the pipeline
+var ErrNoBanner = errors.New("missing MatrixMarket banner")
+
+// ParseHeader reads the banner and the size line of MTX content. A byte order
+// mark and blank lines before the banner are skipped.
+func ParseHeader(content string) (Header, error) {
+	var h Header
+	rest := strings.TrimPrefix(content, "\ufeff")
+	var first string
+	for first == "" && rest != "" {
+		first, rest, _ = strings.Cut(rest, "\n")
+		first = strings.TrimSpace(first)
+	}
+	if !strings.HasPrefix(first, "%%MatrixMarket") {
+		return h, ErrNoBanner
+	}
+	banner := strings.Fields(strings.ToLower(first))
+	if len(banner) < 5 {
+		return h, errors.New("incomplete MatrixMarket banner")
+	}
+	h.Format, h.Field, h.Symmetry = banner[2], banner[3], banner[4]
+	if h.Format != "coordinate" {
+		return h, fmt.Errorf("unsupported format %q", h.Format)
+	}
+
+	for len(rest) > 0 {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+		if _, err := fmt.Sscan(line, &h.Rows, &h.Cols, &h.NNZ); err != nil {
+			return h, fmt.Errorf("invalid size line: %w", err)
+		}
+		if h.Rows < 0 || h.Cols < 0 || h.NNZ < 0 {
+			return h, errors.New("invalid size line: negative size")
+		}
+		return h, nil
+	}
+	return h, errors.New("missing size line")
+}
+
+// EachEntry calls fn for the entries of a coordinate MTX file in file order
+// without keeping them, and stops at the first error fn returns. Pattern
+// matrices get value 1 for every entry. Content without a banner is read as
+// a size line followed by entries, as cleaner.py does.
+func EachEntry(content string, fn func(Entry) error) (Header, error) {
+	h, err := ParseHeader(content)
+	if err != nil && !errors.Is(err, ErrNoBanner) {
+		return h, err
+	}
+	content = strings.TrimPrefix(content, "\ufeff")
+	sizeSeen := false
+	for len(content) > 0 {
+		var line string
+		line, content, _ = strings.Cut(content, "\n")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+		if !sizeSeen {
+			sizeSeen = true
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return h, fmt.Errorf("invalid entry %q", line)
+		}
+		e := Entry{Value: 1}
+		if e.Row, err = strconv.Atoi(fields[0]); err != nil {
+			return h, fmt.Errorf("invalid entry %q: %w", line, err)
+		}
+		if e.Col, err = strconv.Atoi(fields[1]); err != nil {
+			return h, fmt.Errorf("invalid entry %q: %w", line, err)
+		}
+		if h.Field != "pattern" && len(fields) >= 3 {
+			if e.Value, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return h, fmt.Errorf("invalid entry %q: %w", line, err)
+			}
+		}
+		if err := fn(e); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
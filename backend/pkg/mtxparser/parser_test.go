@@ -0,0 +1,103 @@
+package mtxparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Header
+		err     error // nil for no error, errAny for some other error
+	}{
+		{"real general", "%%MatrixMarket matrix coordinate real general\n% comment\n3 4 2\n1 1 1.5\n",
+			Header{Format: "coordinate", Field: "real", Symmetry: "general", Rows: 3, Cols: 4, NNZ: 2}, nil},
+		{"byte order mark and blank lines", "\ufeff\n\n%%MatrixMarket matrix coordinate pattern symmetric\n\n2 2 1\n2 1\n",
+			Header{Format: "coordinate", Field: "pattern", Symmetry: "symmetric", Rows: 2, Cols: 2, NNZ: 1}, nil},
+		{"upper case and CRLF", "%%MatrixMarket MATRIX Coordinate Integer General\r\n2 3 0\r\n",
+			Header{Format: "coordinate", Field: "integer", Symmetry: "general", Rows: 2, Cols: 3}, nil},
+		{"no banner", "3 3 1\n1 2\n", Header{}, ErrNoBanner},
+		{"empty", "", Header{}, ErrNoBanner},
+		{"incomplete banner", "%%MatrixMarket matrix coordinate\n1 1 0\n", Header{}, errAny},
+		{"array format", "%%MatrixMarket matrix array real general\n2 2\n", Header{}, errAny},
+		{"negative size", "%%MatrixMarket matrix coordinate real general\n3 3 -1\n", Header{}, errAny},
+		{"missing size line", "%%MatrixMarket matrix coordinate real general\n% only comments\n", Header{}, errAny},
+	}
+	for _, tt := range tests {
+		h, err := ParseHeader(tt.content)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err == errAny && err == nil, tt.err != nil && tt.err != errAny && !errors.Is(err, tt.err):
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+		case tt.err == nil && h != tt.want:
+			t.Errorf("%s: header %+v, want %+v", tt.name, h, tt.want)
+		}
+	}
+}
+
+// errAny stands for any error but ErrNoBanner in the tables
+var errAny = errors.New("any error")
+
+func TestEachEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+	}{
+		{"values", "%%MatrixMarket matrix coordinate real general\n3 3 2\n1 2 -0.5\n3 1 2e3\n",
+			[]Entry{{1, 2, -0.5}, {3, 1, 2000}}},
+		{"pattern ignores extra fields", "%%MatrixMarket matrix coordinate pattern general\n2 2 2\n1 2 7\n2 1\n",
+			[]Entry{{1, 2, 1}, {2, 1, 1}}},
+		{"byte order mark before the banner", "\ufeff%%MatrixMarket matrix coordinate real symmetric\n2 2 1\n2 1 3\n",
+			[]Entry{{2, 1, 3}}},
+		{"no banner", "% edge list\n3 3 2\n1 2\n2 3 4\n\n",
+			[]Entry{{1, 2, 1}, {2, 3, 4}}},
+		{"byte order mark without banner", "\ufeff3 3 1\n1 3 2\n",
+			[]Entry{{1, 3, 2}}},
+	}
+	for _, tt := range tests {
+		var got []Entry
+		if _, err := EachEntry(tt.content, func(e Entry) error {
+			got = append(got, e)
+			return nil
+		}); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: entries %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEachEntryErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"short entry":   "%%MatrixMarket matrix coordinate real general\n2 2 1\n1\n",
+		"bad index":     "%%MatrixMarket matrix coordinate real general\n2 2 1\nx 1 1\n",
+		"bad value":     "%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1 one\n",
+		"array format":  "%%MatrixMarket matrix array real general\n2 2\n1\n2\n3\n4\n",
+		"negative size": "%%MatrixMarket matrix coordinate real general\n-2 2 1\n1 1 1\n",
+	} {
+		if _, err := EachEntry(content, func(Entry) error { return nil }); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	// an error of fn stops the scan
+	stop := errors.New("stop")
+	calls := 0
+	_, err := EachEntry("%%MatrixMarket matrix coordinate real general\n3 3 3\n1 1 1\n2 2 2\n3 3 3\n", func(e Entry) error {
+		calls++
+		if e.Value == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || calls != 2 {
+		t.Errorf("scan went on for %d entries with error %v", calls, err)
+	}
+}
@@ -1,15 +1,73 @@
+import os
 import sys
 
-def clean_matrix_market(input_filename, output_filename):
+WEIGHT_MODES = ("ignore", "abs", "asis", "inverse")
+INTERPRETATIONS = ("symmetric", "sum", "max", "bipartite")
+
+
+def convert_weight(value, mode):
+    """
+    Map a Matrix Market value to an edge weight according to the weight mode.
+    Returns None when the entry should not become an edge.
+    """
+    if mode == "abs":
+        weight = abs(value)
+    elif mode == "asis":
+        if value < 0:
+            raise ValueError(f"negative weight {value} is not allowed in 'asis' mode")
+        weight = value
+    elif mode == "inverse":
+        # values are distances: closer vertices should be pulled together harder
+        if value == 0:
+            return None
+        weight = 1.0 / abs(value)
+    else:
+        return 1.0
+    if weight == 0:
+        return None
+    return weight
+
+
+def build_edges(entries, rows, interpretation):
+    """
+    Turn matrix entries (i, j, w) into undirected edges.
+
+    symmetric: entries are used as stored (one triangle of a symmetric matrix)
+    sum:       A + A^T, weights of (i, j) and (j, i) are added
+    max:       max(A, A^T), the larger of the two weights is kept
+    bipartite: row i and column j become separate vertices i and rows + j
+    """
+    if interpretation == "symmetric":
+        return entries
+    if interpretation == "bipartite":
+        return [(i, rows + j, w) for i, j, w in entries]
+
+    merged = {}
+    for i, j, w in entries:
+        key = (max(i, j), min(i, j))
+        if key not in merged:
+            merged[key] = w
+        elif interpretation == "max":
+            merged[key] = max(merged[key], w)
+        else:
+            merged[key] += w
+    return [(i, j, w) for (i, j), w in merged.items()]
+
+
+def clean_matrix_market(input_filename, output_filename, weight_mode="ignore", interpretation="symmetric"):
     with open(input_filename, 'r') as fin:
         lines = fin.readlines()
+    banner = lines[0].lower().split() if lines else []
+    is_pattern = "pattern" in banner
     non_comment_lines = [line.strip() for line in lines if line.strip() and not line.strip().startswith('%')]
 
     if not non_comment_lines:
         print("No non-comment lines found in the input file.")
         return
 
-    output_lines = []
+    entries = []
+    skipped = 0
+    rows = int(non_comment_lines[0].split()[0])
 
     # dims = non_comment_lines[0].split()
     # if len(dims) < 2:
@@ -17,22 +75,60 @@ def clean_matrix_market(input_filename, output_filename):
     #     return
     # output_lines.append(" ".join(dims[:2]))
 
+    weighted = weight_mode != "ignore" and not is_pattern
     for line in non_comment_lines[1:]:
         parts = line.split()
-        if len(parts) >= 2:
-            output_lines.append(" ".join(parts[:2]))
+        if len(parts) < 2:
+            continue
+        weight = 1.0
+        if weighted and len(parts) >= 3:
+            weight = convert_weight(float(parts[2]), weight_mode)
+            if weight is None:
+                skipped += 1
+                continue
+        entries.append((int(parts[0]), int(parts[1]), weight))
+
+    output_lines = []
+    for i, j, w in build_edges(entries, rows, interpretation):
+        if weighted:
+            output_lines.append(f"{i} {j} {w:.17g}")
+        else:
+            output_lines.append(f"{i} {j}")
 
     with open(output_filename, 'w') as fout:
         for line in output_lines:
             fout.write(line + "\n")
+    if interpretation == "bipartite":
+        # the drawings name column vertices by their column, rows + j being column j
+        sides_filename = os.path.join(os.path.dirname(output_filename), "sides.txt")
+        with open(sides_filename, 'w') as fout:
+            fout.write(f"{rows}\n")
 
-    print(f"Cleaned file with only first two columns has been saved to '{output_filename}'.")
+    print(f"Interpreted matrix as '{interpretation}', {len(output_lines)} edges.")
+    if weighted:
+        print(f"Skipped {skipped} entries with zero weight.")
+        print(f"Cleaned file with weights ('{weight_mode}') has been saved to '{output_filename}'.")
+    else:
+        print(f"Cleaned file with only first two columns has been saved to '{output_filename}'.")
 
 if __name__ == "__main__":
-    if len(sys.argv) != 3:
-        print("Usage: python clean.py <input_matrix_market_file.mtx> <output_file.txt>")
+    if len(sys.argv) not in (3, 4, 5):
+        print("Usage: python clean.py <input_matrix_market_file.mtx> <output_file.txt> "
+              "[ignore|abs|asis|inverse] [symmetric|sum|max|bipartite]")
         sys.exit(1)
 
     input_filename = sys.argv[1]
     output_filename = sys.argv[2]
-    clean_matrix_market(input_filename, output_filename)
\ No newline at end of file
+    weight_mode = sys.argv[3] if len(sys.argv) >= 4 else "ignore"
+    interpretation = sys.argv[4] if len(sys.argv) == 5 else "symmetric"
+    if weight_mode not in WEIGHT_MODES:
+        print(f"Unknown weight mode '{weight_mode}'")
+        sys.exit(1)
+    if interpretation not in INTERPRETATIONS:
+        print(f"Unknown interpretation '{interpretation}'")
+        sys.exit(1)
+    try:
+        clean_matrix_market(input_filename, output_filename, weight_mode, interpretation)
+    except ValueError as e:
+        print(f"Error: {e}")
+        sys.exit(1)
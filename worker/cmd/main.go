@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"log"
 	"net/http"
@@ -17,9 +18,19 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	router := mux.NewRouter()
+	router.HandleFunc("/project", app.ProjectHandler).Methods("POST")
+	router.HandleFunc("/render", app.RenderHandler).Methods("POST")
+	router.HandleFunc("/compare", app.CompareHandler).Methods("POST")
+	router.HandleFunc("/diff", app.DiffHandler).Methods("POST")
+	router.HandleFunc("/tile", app.TileHandler).Methods("POST")
+	router.HandleFunc("/thumbnail", app.ThumbnailHandler).Methods("POST")
+	router.HandleFunc("/vertex", app.VertexHandler).Methods("POST")
+	router.HandleFunc("/", app.PingHandler)
+
 	srv := &http.Server{
 		Addr:    ":8000",
-		Handler: http.HandlerFunc(app.PingHandler),
+		Handler: router,
 	}
 
 	go func() {
@@ -0,0 +1,1030 @@
+// Command spectra post-processes solver output in the job directory. It is
+// invoked by draw.sh and render.sh with a subcommand:
+//
+//	spectra project -in eigenvectors.txt -out embedding.txt -dims 2 -mode pca
+//	spectra svg -graph graph.txt -in embedding.txt -out out.svg
+//	spectra png -graph graph.txt -in embedding.txt -out out.png -antialias
+//	spectra pdf -graph graph.txt -in embedding.txt -out out.pdf
+//	spectra density -graph graph.txt -in embedding.txt -out density.png -scale eq_hist
+//	spectra tiles -graph graph.txt -in embedding.txt -out-dir tiles -max-zoom 3
+//	spectra thumbnail -graph graph.txt -in embedding.txt -out thumbnail.png
+//	spectra partition -graph graph.txt -in eigenvectors.txt -clusters 4 -bisection -out-dir .
+//	spectra metrics -graph graph.txt -in embedding.txt -out metrics.json
+//	spectra refine -graph graph.txt -in embedding.txt -out embedding.txt -method stress
+//	spectra animate -graph graph.txt -frames-dir spectral_frames,frames -out out.animation.gif
+//	spectra snapshot -graph graph.txt -in embedding_3d.txt -out-dir . -views iso,front
+//	spectra obj -graph graph.txt -in embedding_3d.txt -out out.obj
+//	spectra ply -graph graph.txt -in embedding_3d.txt -out out.ply
+//	spectra glb -graph graph.txt -in embedding_3d.txt -out out.glb
+//	spectra vtk -graph graph.txt -in embedding_3d.txt -out out.vtk
+//	spectra align -base-graph a/graph.txt -base a/embedding.txt -graph b/graph.txt -in b/embedding.txt -out aligned.txt
+//	spectra diff -base-graph a/graph.txt -graph b/graph.txt -out diff.json -base a/embedding.txt -in b/embedding.txt -svg diff.svg
+//	spectra warmstart -parent-graph a/graph.txt -parent a/eigenvectors.txt -graph graph.txt -out warm_start.txt
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"worker/pkg/cluster"
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+	"worker/pkg/layout"
+	"worker/pkg/metrics"
+	"worker/pkg/render"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"project", "map the k-dimensional embedding to 2D or 3D", runProject},
+	{"svg", "draw a 2D embedding as SVG", runSVG},
+	{"png", "draw a 2D embedding as PNG", runPNG},
+	{"pdf", "draw a 2D embedding as PDF", runPDF},
+	{"density", "draw a 2D embedding as a PNG density map", runDensity},
+	{"tiles", "draw a 2D embedding as a pyramid of map tiles", runTiles},
+	{"thumbnail", "draw a small square PNG preview of a 2D embedding", runThumbnail},
+	{"animate", "draw saved layout frames as an animated GIF or PNG", runAnimate},
+	{"snapshot", "draw PNG snapshots of a 3D embedding from camera views", runSnapshot},
+	{"obj", "export a 3D embedding as a Wavefront OBJ model", runOBJ},
+	{"ply", "export a 3D embedding as a PLY model", runPLY},
+	{"glb", "export a 3D embedding as a binary glTF model", runGLB},
+	{"vtk", "export a 3D embedding as legacy VTK polydata", runVTK},
+	{"partition", "cluster the graph and bisect it by the Fiedler vector", runPartition},
+	{"metrics", "measure the quality of a drawing", runMetrics},
+	{"refine", "improve a drawing starting from the spectral coordinates", runRefine},
+	{"align", "rotate a drawing onto another one with Procrustes", runAlign},
+	{"diff", "list the changes between two versions of a graph and draw them", runDiff},
+	{"warmstart", "map the eigenvectors of an earlier graph version onto a new one", runWarmStart},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "spectra %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: spectra <command> [options]")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func runProject(args []string) error {
+	fs := flag.NewFlagSet("project", flag.ContinueOnError)
+	in := fs.String("in", "eigenvectors.txt", "k-dimensional embedding written by spectral_embed")
+	out := fs.String("out", "embedding.txt", "projected embedding")
+	dims := fs.Int("dims", 2, "target dimension")
+	mode := fs.String("mode", embedding.ModeFirst, "projection mode: first, pca or axes")
+	axes := fs.String("axes", "", "comma separated eigenvector numbers for mode axes, e.g. 2,4")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	p := embedding.Projection{Mode: *mode}
+	var err error
+	if p.Axes, err = embedding.ParseAxes(*axes); err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	projected, err := embedding.Project(e, *dims, p)
+	if err != nil {
+		return err
+	}
+	if err := projected.Write(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Projected %d-dimensional embedding to %d dimensions (%s), written to %s\n",
+		e.Dims, projected.Dims, p.Name(), *out)
+	return nil
+}
+
+// styleFlags registers the drawing options shared by the rendering commands
+func styleFlags(fs *flag.FlagSet) func() (render.Style, error) {
+	def := render.DefaultStyle()
+	width := fs.Int("width", def.Width, "image width in pixels")
+	height := fs.Int("height", def.Height, "image height in pixels")
+	background := fs.String("background", render.Hex(def.Background), "background color")
+	edgeColor := fs.String("edge-color", render.Hex(def.EdgeColor), "edge color")
+	vertexColor := fs.String("vertex-color", render.Hex(def.VertexColor), "vertex color")
+	vertexSize := fs.Float64("vertex-size", def.VertexSize, "vertex radius in pixels, 0 draws edges only")
+	margin := fs.Int("margin", def.Margin, "free space around the drawing in pixels")
+	edgeAlpha := fs.Float64("edge-alpha", def.EdgeAlpha, "edge opacity in (0, 1]")
+	antialias := fs.Bool("antialias", def.Antialias, "smooth edges and vertices in PNG images")
+	minEdge := fs.Float64("min-edge-length", def.MinEdgeLength, "merge or drop edges shorter than this many pixels in SVG and PDF output")
+	return func() (render.Style, error) {
+		style := render.Style{Width: *width, Height: *height, Margin: *margin, VertexSize: *vertexSize,
+			EdgeAlpha: *edgeAlpha, Antialias: *antialias, MinEdgeLength: *minEdge}
+		if style.Width < 1 || style.Height < 1 {
+			return style, fmt.Errorf("invalid image size %dx%d", style.Width, style.Height)
+		}
+		if style.Margin < 0 || 2*style.Margin >= min(style.Width, style.Height) {
+			return style, fmt.Errorf("margin %d does not fit a %dx%d image", style.Margin, style.Width, style.Height)
+		}
+		if !(style.EdgeAlpha > 0 && style.EdgeAlpha <= 1) {
+			return style, fmt.Errorf("edge alpha must be in (0, 1], got %g", style.EdgeAlpha)
+		}
+		if style.MinEdgeLength < 0 {
+			return style, fmt.Errorf("min edge length must not be negative, got %g", style.MinEdgeLength)
+		}
+		var err error
+		if style.Background, err = render.ParseColor(*background); err != nil {
+			return style, err
+		}
+		if style.EdgeColor, err = render.ParseColor(*edgeColor); err != nil {
+			return style, err
+		}
+		if style.VertexColor, err = render.ParseColor(*vertexColor); err != nil {
+			return style, err
+		}
+		return style, nil
+	}
+}
+
+// stylingFlags registers the options styling vertices and edges by graph
+// properties. The returned function applies them to a style, with legends.
+func stylingFlags(fs *flag.FlagSet) func(*render.Style, *graph.Graph, *embedding.Embedding) error {
+	colorBy := fs.String("color-by", "", "color vertices by degree, component, eigenvector, attribute, or a partition from -groups (cluster, bisection)")
+	groups := fs.String("groups", "", "partition file (lines \"vertex part\") to color vertices by")
+	eigenvectors := fs.String("eigenvectors", "eigenvectors.txt", "k-dimensional embedding for -color-by eigenvector")
+	eigenvector := fs.Int("eigenvector", 2, "eigenvector number for -color-by eigenvector, 2 is the Fiedler vector")
+	attributes := fs.String("attributes", "", "vertex values (lines \"vertex value\") for -color-by attribute")
+	sizeBy := fs.String("size-by", "", "size vertices by degree, up to -vertex-size")
+	edgeColorBy := fs.String("edge-color-by", "", "color edges by length or weight")
+	colormap := fs.String("colormap", "", "viridis, plasma or categorical; categorical for partitions and components, viridis otherwise")
+	labels := fs.String("labels", "", "label vertices by id: top:K for the K of highest degree, all, or comma separated vertex ids")
+	sides := fs.String("sides", "", "row count of a bipartite graph (sides.txt), to name vertices by row and column")
+	return func(st *render.Style, g *graph.Graph, e *embedding.Embedding) error {
+		cmap := func(categorical bool) (render.Colormap, error) {
+			name := *colormap
+			if name == "" && categorical {
+				name = render.ColormapCategorical
+			} else if name == "" {
+				name = render.ColormapViridis
+			}
+			return render.ParseColormap(name)
+		}
+		by := *colorBy
+		if by == "" && *groups != "" {
+			by = "group"
+		}
+		switch by {
+		case "":
+		case "group", "cluster", "bisection":
+			if *groups == "" {
+				return fmt.Errorf("-color-by %s needs a partition file in -groups", by)
+			}
+			p, err := cluster.Read(*groups, e.Len())
+			if err != nil {
+				return err
+			}
+			c, err := cmap(true)
+			if err != nil {
+				return err
+			}
+			render.ColorGroups(st, p.Assign, c, by)
+		case "component":
+			labels, _ := g.Components()
+			c, err := cmap(true)
+			if err != nil {
+				return err
+			}
+			render.ColorGroups(st, labels, c, by)
+		case "degree", "eigenvector", "attribute":
+			var values []float64
+			title := by
+			switch by {
+			case "degree":
+				values = render.Degrees(g, e)
+			case "eigenvector":
+				vecs, err := embedding.Read(*eigenvectors)
+				if err != nil {
+					return err
+				}
+				col := *eigenvector - 2
+				if col < 0 || col >= vecs.Dims {
+					return fmt.Errorf("eigenvector %d is not in %s (2..%d)", *eigenvector, *eigenvectors, vecs.Dims+1)
+				}
+				values = make([]float64, e.Len())
+				for v := range values {
+					values[v] = math.NaN()
+					if v < vecs.Len() {
+						values[v] = vecs.Coords[v][col]
+					}
+				}
+				title = fmt.Sprintf("eigenvector %d", *eigenvector)
+			case "attribute":
+				if *attributes == "" {
+					return fmt.Errorf("-color-by attribute needs a file in -attributes")
+				}
+				var err error
+				if values, err = graph.ReadAttributes(*attributes, e.Len()); err != nil {
+					return err
+				}
+			}
+			c, err := cmap(false)
+			if err != nil {
+				return err
+			}
+			render.ColorVertices(st, values, c, title)
+		default:
+			return fmt.Errorf("unknown -color-by %q", by)
+		}
+
+		switch *sizeBy {
+		case "":
+		case "degree":
+			maxRadius := st.VertexSize
+			if maxRadius == 0 {
+				maxRadius = 6
+			}
+			render.SizeVertices(st, render.Degrees(g, e), maxRadius/4, maxRadius, "degree")
+		default:
+			return fmt.Errorf("unknown -size-by %q", *sizeBy)
+		}
+		// vertices colored by a property are drawn even with the default size 0
+		if by != "" && st.VertexSize == 0 {
+			st.VertexSize = 3
+		}
+
+		if *edgeColorBy != "" {
+			var values []float64
+			switch *edgeColorBy {
+			case "length":
+				values = render.EdgeLengths(g, e)
+			case "weight":
+				values = render.EdgeWeights(g)
+			default:
+				return fmt.Errorf("unknown -edge-color-by %q", *edgeColorBy)
+			}
+			c, err := cmap(false)
+			if err != nil {
+				return err
+			}
+			render.ColorEdges(st, values, c, "edge "+*edgeColorBy)
+		}
+
+		if *sides != "" {
+			var err error
+			if st.Sides, err = graph.ReadSides(*sides); err != nil {
+				return err
+			}
+		}
+		if *labels != "" {
+			var err error
+			if st.Labels, err = render.SelectLabels(g, e, *labels, st.Sides); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func runSVG(args []string) error {
+	return runDrawing("svg", args, render.WriteSVG)
+}
+
+func runPNG(args []string) error {
+	return runDrawing("png", args, render.WritePNG)
+}
+
+func runPDF(args []string) error {
+	return runDrawing("pdf", args, render.WritePDF)
+}
+
+// runDrawing implements the commands drawing a 2D embedding to an image file
+func runDrawing(format string, args []string,
+	write func(io.Writer, *graph.Graph, *embedding.Embedding, render.Style) error) error {
+	fs := flag.NewFlagSet(format, flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "2D embedding")
+	out := fs.String("out", "out."+format, "output file")
+	style := styleFlags(fs)
+	styling := stylingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	if err := styling(&st, g, e); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := write(f, g, e, st); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%dx%d)\n", *out, st.Width, st.Height)
+	return nil
+}
+
+// maxAnimationDelay bounds the time a frame is shown, in milliseconds
+const maxAnimationDelay = 60000
+
+// runAnimate draws the layout frames saved while a drawing was computed, in
+// file name order, as one animation. Frames with more than two dimensions are
+// projected like the embedding, and every frame fills the image.
+func runAnimate(args []string) error {
+	fs := flag.NewFlagSet("animate", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	framesDirs := fs.String("frames-dir", "frames", "comma separated directories of frame-*.txt files, animated one after another")
+	out := fs.String("out", "out.animation.gif", "output file, or directory for -format frames")
+	format := fs.String("format", "gif", "gif, apng, or frames for a PNG per frame")
+	delay := fs.Int("delay", 100, "milliseconds each frame is shown")
+	maxFrames := fs.Int("max-frames", 200, "frames drawn at most, picked evenly from the saved ones")
+	mode := fs.String("mode", embedding.ModeFirst, "projection of frames with more than two dimensions: first, pca or axes")
+	axes := fs.String("axes", "", "comma separated eigenvector numbers for mode axes")
+	style := styleFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *delay < 1 || *delay > maxAnimationDelay {
+		return fmt.Errorf("delay must be between 1 and %d ms, got %d", maxAnimationDelay, *delay)
+	}
+	if *maxFrames < 2 {
+		return fmt.Errorf("max frames must be at least 2, got %d", *maxFrames)
+	}
+	if *format != "gif" && *format != "apng" && *format != "frames" {
+		return fmt.Errorf("unknown animation format %q, expected gif, apng or frames", *format)
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	p := embedding.Projection{Mode: *mode}
+	if p.Axes, err = embedding.ParseAxes(*axes); err != nil {
+		return err
+	}
+	var files []string
+	for _, dir := range strings.Split(*framesDirs, ",") {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, "frame-*.txt"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	if len(files) < 2 {
+		return fmt.Errorf("found %d frames in %s, at least 2 are needed", len(files), *framesDirs)
+	}
+	if len(files) > *maxFrames {
+		picked := make([]string, *maxFrames)
+		for i := range picked {
+			picked[i] = files[i*(len(files)-1)/(*maxFrames-1)]
+		}
+		files = picked
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	frames := make([]*image.RGBA, 0, len(files))
+	for _, file := range files {
+		e, err := embedding.Read(file)
+		if err != nil {
+			return err
+		}
+		if e.Dims > 2 {
+			if e, err = embedding.Project(e, 2, p); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		img, err := render.Rasterize(g, e, st)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, img)
+	}
+
+	if *format == "frames" {
+		for i, img := range frames {
+			if err := render.WriteTileFile(filepath.Join(*out, fmt.Sprintf("frame-%05d.png", i)), img); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Wrote %d frames to %s\n", len(frames), *out)
+		return nil
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if *format == "gif" {
+		err = render.WriteGIF(f, frames, *delay)
+	} else {
+		err = render.WriteAPNG(f, frames, *delay)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%d frames, %dx%d)\n", *out, len(frames), st.Width, st.Height)
+	return nil
+}
+
+// runSnapshot draws out.3d-<view>.png for every view of a 3D embedding
+func runSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding_3d.txt", "3D embedding")
+	outDir := fs.String("out-dir", ".", "directory of the snapshots")
+	views := fs.String("views", "iso", "comma separated views: front, side, top, iso or azimuth:elevation in degrees")
+	style := styleFlags(fs)
+	styling := stylingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	type view struct {
+		name string
+		cam  render.Camera
+	}
+	var list []view
+	for _, s := range strings.Split(*views, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		name, cam, err := render.ParseView(s)
+		if err != nil {
+			return err
+		}
+		list = append(list, view{name, cam})
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no views given")
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	if err := styling(&st, g, e); err != nil {
+		return err
+	}
+	for _, v := range list {
+		out := filepath.Join(*outDir, "out.3d-"+v.name+".png")
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		if err := render.WriteSnapshotPNG(f, g, e, st, v.cam); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (%dx%d, azimuth %g, elevation %.4g)\n", out, st.Width, st.Height, v.cam.Azimuth, v.cam.Elevation)
+	}
+	return nil
+}
+
+func runOBJ(args []string) error {
+	return runExport("obj", args, render.WriteOBJ)
+}
+
+func runPLY(args []string) error {
+	return runExport("ply", args, render.WritePLY)
+}
+
+func runGLB(args []string) error {
+	return runExport("glb", args, render.WriteGLB)
+}
+
+func runVTK(args []string) error {
+	return runExport("vtk", args, render.WriteVTK)
+}
+
+// runExport implements the commands exporting an embedding as a 3D model. Of
+// the drawing options only the colors apply.
+func runExport(format string, args []string,
+	write func(io.Writer, *graph.Graph, *embedding.Embedding, render.Style) error) error {
+	fs := flag.NewFlagSet(format, flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding_3d.txt", "3D (or 2D) embedding")
+	out := fs.String("out", "out."+format, "output file")
+	style := styleFlags(fs)
+	styling := stylingFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	if err := styling(&st, g, e); err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := write(f, g, e, st); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %s (%d vertices, %d edges)\n", *out, e.Len(), len(g.Edges))
+	return nil
+}
+
+// runDensity draws the density of a 2D embedding, for graphs too large for a line drawing
+func runDensity(args []string) error {
+	fs := flag.NewFlagSet("density", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "2D embedding")
+	out := fs.String("out", "out.png", "output file")
+	scale := fs.String("scale", render.ScaleEqHist, "density scale: linear, log or eq_hist")
+	colormap := fs.String("colormap", render.ColormapViridis, "viridis, plasma or categorical")
+	legend := fs.Bool("legend", true, "add a color ramp of the density")
+	style := styleFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	d := render.Density{Scale: *scale, Legend: *legend}
+	if d.Colormap, err = render.ParseColormap(*colormap); err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := render.WriteDensityPNG(f, g, e, st, d); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%dx%d, %s density)\n", *out, st.Width, st.Height, d.Scale)
+	return nil
+}
+
+// maxPyramidZoom bounds the pyramid written up front; deeper tiles are drawn on demand
+const maxPyramidZoom = 8
+
+// runTiles writes the tile pyramid z/x/y.png of a 2D embedding
+func runTiles(args []string) error {
+	fs := flag.NewFlagSet("tiles", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "2D embedding")
+	outDir := fs.String("out-dir", "tiles", "directory of the pyramid")
+	maxZoom := fs.Int("max-zoom", 3, fmt.Sprintf("deepest zoom level, at most %d", maxPyramidZoom))
+	style := styleFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *maxZoom < 0 || *maxZoom > maxPyramidZoom {
+		return fmt.Errorf("max zoom must be between 0 and %d, got %d", maxPyramidZoom, *maxZoom)
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	count, err := render.WriteTiles(*outDir, g, e, st, *maxZoom)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d tiles for zoom levels 0 to %d to %s\n", count, *maxZoom, *outDir)
+	return nil
+}
+
+// runThumbnail writes the square PNG preview of a 2D embedding shown in job lists
+func runThumbnail(args []string) error {
+	fs := flag.NewFlagSet("thumbnail", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "2D embedding")
+	out := fs.String("out", "thumbnail.png", "output file")
+	size := fs.Int("size", render.ThumbnailSize, "width and height in pixels")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	img, err := render.Thumbnail(g, e, *size)
+	if err != nil {
+		return err
+	}
+	return render.WriteTileFile(*out, img)
+}
+
+// partitionReport is written to partition.json and returned with the job
+type partitionReport struct {
+	Clusters  *cluster.Metrics `json:"clusters,omitempty"`
+	Bisection *cluster.Metrics `json:"bisection,omitempty"`
+}
+
+func runPartition(args []string) error {
+	fs := flag.NewFlagSet("partition", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "eigenvectors.txt", "k-dimensional embedding written by spectral_embed")
+	outDir := fs.String("out-dir", ".", "directory for out.clusters.txt, out.bisection.txt and partition.json")
+	k := fs.Int("clusters", 0, "number of k-means clusters, 0 to skip clustering")
+	bisection := fs.Bool("bisection", false, "split the graph by the sign of the Fiedler vector")
+	seed := fs.Int64("seed", 1, "seed of the k-means initialization")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	active := cluster.Active(g, e.Len())
+
+	var report partitionReport
+	if *k > 0 {
+		p, inertia, err := cluster.KMeans(e, active, *k, *seed)
+		if err != nil {
+			return err
+		}
+		if err := p.Write(filepath.Join(*outDir, "out.clusters.txt")); err != nil {
+			return err
+		}
+		m := cluster.Evaluate(g, p)
+		m.Inertia = inertia
+		report.Clusters = &m
+		fmt.Printf("Clustered into %d parts, sizes %v, %d cut edges\n", m.K, m.Sizes, m.CutEdges)
+	}
+	if *bisection {
+		p := cluster.Bisect(e.Column(0), active)
+		if err := p.Write(filepath.Join(*outDir, "out.bisection.txt")); err != nil {
+			return err
+		}
+		m := cluster.Evaluate(g, p)
+		report.Bisection = &m
+		fmt.Printf("Fiedler bisection sizes %v, %d cut edges, balance %.3f\n", m.Sizes, m.CutEdges, m.Balance)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(*outDir, "partition.json"), data, 0o644)
+}
+
+func runMetrics(args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "drawing coordinates")
+	out := fs.String("out", "metrics.json", "output file")
+	opts := metrics.DefaultOptions
+	fs.IntVar(&opts.Sources, "sources", opts.Sources, "BFS sources sampled for stress and neighborhood preservation, 0 for all vertices")
+	fs.IntVar(&opts.CrossingPairs, "crossing-pairs", opts.CrossingPairs, "edge pairs sampled for crossings, 0 to test all")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of the sampling")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	m, err := metrics.Compute(g, e, opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stress %.4f, edge length cv %.4f, neighborhood preservation %.4f, %.0f crossings\n",
+		m.Stress, m.EdgeLengthCV, m.NeighborhoodPreservation, m.Crossings)
+	return os.WriteFile(*out, data, 0o644)
+}
+
+func runRefine(args []string) error {
+	fs := flag.NewFlagSet("refine", flag.ContinueOnError)
+	graphPath := fs.String("graph", "graph.txt", "edge list")
+	in := fs.String("in", "embedding.txt", "initial coordinates")
+	out := fs.String("out", "embedding.txt", "refined coordinates, may be the input file")
+	method := fs.String("method", "stress", "refinement: stress or force")
+	iterations := fs.Int("iterations", 0, "number of iterations, 0 for the default of the method")
+	stress := layout.DefaultStressOptions
+	fs.IntVar(&stress.Pivots, "pivots", stress.Pivots, "pivots of sparse stress; at least the vertex count gives full stress")
+	force := layout.DefaultForceOptions
+	fs.Float64Var(&force.Gravity, "gravity", force.Gravity, "force layout: pull towards the center")
+	fs.Float64Var(&force.Repulsion, "repulsion", force.Repulsion, "force layout: repulsion strength")
+	fs.Float64Var(&force.Theta, "theta", force.Theta, "force layout: Barnes-Hut opening angle, 0 for exact forces")
+	framesDir := fs.String("frames-dir", "", "force layout: directory for intermediate drawings frame-NNNNN.txt")
+	fs.IntVar(&force.FrameEvery, "frame-every", 10, "force layout: iterations between saved frames")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	var refined *embedding.Embedding
+	var res layout.Result
+	switch *method {
+	case "stress":
+		if *iterations > 0 {
+			stress.Iterations = *iterations
+		}
+		refined, res, err = layout.Stress(g, e, stress)
+	case "force":
+		if *iterations > 0 {
+			force.Iterations = *iterations
+		}
+		if *framesDir != "" {
+			if err := os.MkdirAll(*framesDir, 0o755); err != nil {
+				return err
+			}
+			force.Frame = func(iteration int, frame *embedding.Embedding) error {
+				return frame.Write(filepath.Join(*framesDir, fmt.Sprintf("frame-%05d.txt", iteration)))
+			}
+		}
+		refined, res, err = layout.Force(g, e, force)
+	default:
+		return fmt.Errorf("unknown refinement %q", *method)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Refined %dD embedding by %s in %d iterations (converged: %v)\n", refined.Dims, *method, res.Iterations, res.Converged)
+	return refined.Write(*out)
+}
+
+func runAlign(args []string) error {
+	fs := flag.NewFlagSet("align", flag.ContinueOnError)
+	baseGraphPath := fs.String("base-graph", "", "edge list of the reference layout")
+	basePath := fs.String("base", "", "reference layout")
+	graphPath := fs.String("graph", "graph.txt", "edge list of the layout to align")
+	in := fs.String("in", "embedding.txt", "layout to align")
+	out := fs.String("out", "aligned.txt", "aligned layout")
+	reportPath := fs.String("report", "", "JSON file for the transform and residual")
+	svgPath := fs.String("svg", "", "SVG file overlaying both layouts")
+	scaling := fs.Bool("scale", true, "also fit a uniform scale")
+	baseLabel := fs.String("base-label", "base", "legend of the reference layout in the overlay")
+	label := fs.String("label", "aligned", "legend of the aligned layout in the overlay")
+	style := styleFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	baseGraph, err := graph.Read(*baseGraphPath)
+	if err != nil {
+		return err
+	}
+	base, err := embedding.Read(*basePath)
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	aligned, al, err := embedding.Procrustes(base, e, sharedVertices(baseGraph, base, g, e), *scaling)
+	if err != nil {
+		return err
+	}
+	if err := aligned.Write(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Aligned %d shared vertices: residual %.4f, rmsd %.4g, scale %.4g, reflection %v\n",
+		al.SharedVertices, al.Residual, al.RMSD, al.Scale, al.Reflection)
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(al, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*reportPath, data, 0o644); err != nil {
+			return err
+		}
+	}
+	if *svgPath == "" {
+		return nil
+	}
+	f, err := os.Create(*svgPath)
+	if err != nil {
+		return err
+	}
+	layers := []render.Layer{
+		{Label: *baseLabel, Graph: baseGraph, Embedding: base, Color: render.CategoryColor(0)},
+		{Label: *label, Graph: g, Embedding: aligned, Color: render.CategoryColor(1)},
+	}
+	if err := render.WriteOverlaySVG(f, layers, st); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sharedVertices returns the vertex ids drawn in both layouts; layouts of
+// versions of a graph are matched by id
+func sharedVertices(baseGraph *graph.Graph, base *embedding.Embedding, g *graph.Graph, e *embedding.Embedding) []int {
+	inBase := cluster.Active(baseGraph, base.Len())
+	inLayout := cluster.Active(g, e.Len())
+	var shared []int
+	for v := 0; v < len(inBase) && v < len(inLayout); v++ {
+		if inBase[v] && inLayout[v] {
+			shared = append(shared, v)
+		}
+	}
+	return shared
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	baseGraphPath := fs.String("base-graph", "", "edge list of the base version")
+	graphPath := fs.String("graph", "graph.txt", "edge list of the new version")
+	out := fs.String("out", "diff.json", "JSON file for the changes")
+	limit := fs.Int("limit", 1000, "longest vertex, edge and degree change list written")
+	basePath := fs.String("base", "", "layout of the base version, needed for -svg")
+	in := fs.String("in", "", "layout of the new version, aligned to -base for -svg")
+	svgPath := fs.String("svg", "", "SVG file drawing the added and removed edges")
+	scaling := fs.Bool("scale", true, "also fit a uniform scale when aligning the layouts")
+	style := styleFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := style()
+	if err != nil {
+		return err
+	}
+	baseGraph, err := graph.Read(*baseGraphPath)
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	d := graph.Diff(baseGraph, g, *limit)
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Edges: %d added, %d removed; vertices: %d added, %d removed; components %d -> %d\n",
+		d.Edges.Added, d.Edges.Removed, d.Vertices.Added, d.Vertices.Removed, d.Components.Base, d.Components.Other)
+	if *svgPath == "" {
+		return nil
+	}
+	if *basePath == "" || *in == "" {
+		return fmt.Errorf("-svg needs the layouts of both versions (-base and -in)")
+	}
+	base, err := embedding.Read(*basePath)
+	if err != nil {
+		return err
+	}
+	e, err := embedding.Read(*in)
+	if err != nil {
+		return err
+	}
+	aligned, _, err := embedding.Procrustes(base, e, sharedVertices(baseGraph, base, g, e), *scaling)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*svgPath)
+	if err != nil {
+		return err
+	}
+	if err := render.WriteDiffSVG(f, baseGraph, base, g, aligned, st); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runWarmStart(args []string) error {
+	fs := flag.NewFlagSet("warmstart", flag.ContinueOnError)
+	parentGraphPath := fs.String("parent-graph", "", "edge list of the earlier version of the graph")
+	parentPath := fs.String("parent", "", "eigenvectors of the earlier version")
+	graphPath := fs.String("graph", "graph.txt", "edge list of the new version")
+	out := fs.String("out", "warm_start.txt", "start vectors for spectral_embed --init")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	parentGraph, err := graph.Read(*parentGraphPath)
+	if err != nil {
+		return err
+	}
+	parent, err := embedding.Read(*parentPath)
+	if err != nil {
+		return err
+	}
+	g, err := graph.Read(*graphPath)
+	if err != nil {
+		return err
+	}
+	e, kept, err := layout.WarmStart(parentGraph, parent, g)
+	if err != nil {
+		return err
+	}
+	if err := e.Write(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Warm start keeps the positions of %d vertices\n", kept)
+	return nil
+}
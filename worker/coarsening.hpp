@@ -0,0 +1,205 @@
+// Multilevel coarsening used by spectral_embed.
+//
+// A hierarchy is built by repeatedly contracting the graph with heavy-edge
+// matching (HEM) or maximal-independent-set aggregation (MIS) until it is
+// small enough. Every level keeps the mapping of its vertices to the next
+// coarser level, so a solution computed on the coarsest graph can be
+// prolongated back level by level and refined on the way.
+#ifndef SPECTRA_COARSENING_HPP
+#define SPECTRA_COARSENING_HPP
+
+#include <Eigen/Sparse>
+#include <Eigen/Dense>
+#include <vector>
+#include <string>
+#include <utility>
+#include <algorithm>
+
+namespace coarsening {
+
+enum Strategy {
+  HEAVY_EDGE_MATCHING = 0,
+  MAXIMAL_INDEPENDENT_SET = 1
+};
+
+// One level of the hierarchy in CSR form with edge weights.
+struct Level {
+  long n;                             // number of vertices
+  std::vector<unsigned int> rowOffsets;
+  std::vector<unsigned int> adj;
+  std::vector<double> eweights;
+  std::vector<int> coarseID;          // mapping to the next coarser level (empty on the coarsest)
+};
+
+// levels[0] is the input graph, levels.back() is the coarsest graph.
+struct Hierarchy {
+  std::vector<Level> levels;
+
+  int numLevels() const { return (int) levels.size(); }
+  const Level& coarsest() const { return levels.back(); }
+};
+
+inline Strategy parseStrategy(const std::string& name) {
+  if (name == "mis")
+    return MAXIMAL_INDEPENDENT_SET;
+  return HEAVY_EDGE_MATCHING;
+}
+
+inline const char* strategyName(Strategy s) {
+  return s == MAXIMAL_INDEPENDENT_SET ? "mis" : "hem";
+}
+
+// ---------------------------------------------------------------------
+// HEAVY-EDGE MATCHING: every unmatched vertex is paired with its unmatched
+// neighbor of maximum edge weight. Returns the number of aggregates.
+inline int heavyEdgeMatching(const Level& l, std::vector<int>& agg) {
+  agg.assign(l.n, -1);
+  int nc = 0;
+  for (long u = 0; u < l.n; u++) {
+    if (agg[u] != -1)
+      continue;
+    long best = -1;
+    double bestWeight = -1.0;
+    for (unsigned int j = l.rowOffsets[u]; j < l.rowOffsets[u+1]; j++) {
+      unsigned int v = l.adj[j];
+      if ((long) v == u || agg[v] != -1)
+        continue;
+      if (l.eweights[j] > bestWeight) {
+        bestWeight = l.eweights[j];
+        best = v;
+      }
+    }
+    agg[u] = nc;
+    if (best >= 0)
+      agg[best] = nc;
+    nc++;
+  }
+  return nc;
+}
+
+// ---------------------------------------------------------------------
+// MIS AGGREGATION: a greedy maximal independent set selects the aggregate
+// roots, every other vertex joins the root it is most strongly connected to.
+// Returns the number of aggregates.
+inline int misAggregation(const Level& l, std::vector<int>& agg) {
+  agg.assign(l.n, -1);
+  std::vector<char> inSet(l.n, 0);
+  std::vector<char> excluded(l.n, 0);
+  int nc = 0;
+  for (long u = 0; u < l.n; u++) {
+    if (excluded[u])
+      continue;
+    inSet[u] = 1;
+    agg[u] = nc++;
+    for (unsigned int j = l.rowOffsets[u]; j < l.rowOffsets[u+1]; j++)
+      excluded[l.adj[j]] = 1;
+  }
+  for (long u = 0; u < l.n; u++) {
+    if (agg[u] != -1)
+      continue;
+    double bestWeight = -1.0;
+    for (unsigned int j = l.rowOffsets[u]; j < l.rowOffsets[u+1]; j++) {
+      unsigned int v = l.adj[j];
+      if (inSet[v] && l.eweights[j] > bestWeight) {
+        bestWeight = l.eweights[j];
+        agg[u] = agg[v];
+      }
+    }
+  }
+  return nc;
+}
+
+// ---------------------------------------------------------------------
+// Contract a level along the aggregates. Edges inside an aggregate are
+// dropped, parallel edges between two aggregates are merged by summing weights.
+inline Level contract(const Level& fine, const std::vector<int>& agg, int nc) {
+  std::vector< std::vector< std::pair<unsigned int, double> > > rows(nc);
+  for (long i = 0; i < fine.n; i++) {
+    int cu = agg[i];
+    for (unsigned int j = fine.rowOffsets[i]; j < fine.rowOffsets[i+1]; j++) {
+      int cv = agg[fine.adj[j]];
+      if (cu != cv)
+        rows[cu].push_back(std::make_pair((unsigned int) cv, fine.eweights[j]));
+    }
+  }
+
+  Level coarse;
+  coarse.n = nc;
+  coarse.rowOffsets.assign(nc + 1, 0);
+  for (int u = 0; u < nc; u++) {
+    std::vector< std::pair<unsigned int, double> >& r = rows[u];
+    std::sort(r.begin(), r.end());
+    for (size_t k = 0; k < r.size(); k++) {
+      if (k > 0 && r[k].first == r[k-1].first) {
+        coarse.eweights.back() += r[k].second;
+      } else {
+        coarse.adj.push_back(r[k].first);
+        coarse.eweights.push_back(r[k].second);
+      }
+    }
+    coarse.rowOffsets[u+1] = coarse.adj.size();
+  }
+  return coarse;
+}
+
+// ---------------------------------------------------------------------
+// Build the hierarchy from a weighted CSR graph. Coarsening stops
+// once the graph has at most targetSize vertices, after maxLevels coarsening
+// steps (0 means no limit) or when a step no longer shrinks the graph.
+inline Hierarchy buildHierarchy(long n, const unsigned int *rowOffsets, const unsigned int *adj, const double *weights,
+                                Strategy strategy, int maxLevels, long targetSize) {
+  Hierarchy h;
+  Level fine;
+  fine.n = n;
+  fine.rowOffsets.assign(rowOffsets, rowOffsets + n + 1);
+  fine.adj.assign(adj, adj + rowOffsets[n]);
+  fine.eweights.assign(weights, weights + rowOffsets[n]);
+  h.levels.push_back(fine);
+
+  while (h.coarsest().n > targetSize && (maxLevels <= 0 || h.numLevels() - 1 < maxLevels)) {
+    const Level& cur = h.coarsest();
+    std::vector<int> agg;
+    int nc;
+    if (strategy == MAXIMAL_INDEPENDENT_SET)
+      nc = misAggregation(cur, agg);
+    else
+      nc = heavyEdgeMatching(cur, agg);
+    if (nc >= 0.95 * cur.n)
+      break;
+    Level next = contract(cur, agg, nc);
+    h.levels.back().coarseID = agg;
+    h.levels.push_back(next);
+  }
+  return h;
+}
+
+// ---------------------------------------------------------------------
+// Load a level into a weighted adjacency matrix together with the weighted
+// degrees, from which the Laplacian operator of the level is built.
+inline void loadLevelAdjacency(const Level& l, Eigen::SparseMatrix<double,Eigen::RowMajor>& A, Eigen::VectorXd& degrees) {
+  typedef Eigen::Triplet<double> T;
+  std::vector<T> tripletList;
+  tripletList.reserve(l.adj.size());
+  degrees.setZero(l.n);
+  for (long i = 0; i < l.n; i++) {
+    for (unsigned int j = l.rowOffsets[i]; j < l.rowOffsets[i+1]; j++) {
+      tripletList.push_back(T(i, l.adj[j], l.eweights[j]));
+      degrees(i) += l.eweights[j];
+    }
+  }
+  A.resize(l.n, l.n);
+  A.setFromTriplets(tripletList.begin(), tripletList.end());
+}
+
+// ---------------------------------------------------------------------
+// Interpolate a vector from the next coarser level onto level l.
+inline Eigen::VectorXd prolongate(const Level& l, const Eigen::VectorXd& coarseVec) {
+  Eigen::VectorXd fineVec(l.n);
+  for (long i = 0; i < l.n; i++)
+    fineVec(i) = coarseVec(l.coarseID[i]);
+  return fineVec;
+}
+
+} // namespace coarsening
+
+#endif
@@ -0,0 +1,39 @@
+#!/bin/bash
+
+# Align the drawing of one finished job to the drawing of another with Procrustes.
+# Usage: compare.sh <base job dir> <job dir> <output dir> <s3 directory>
+# Writes the aligned coordinates (out.aligned.txt), an overlay of both drawings
+# (out.svg) and the transform with its residual (alignment.json).
+
+log_error() {
+    echo "[ERROR] $(date +'%Y-%m-%d %H:%M:%S') - $1" >> "$2/error.txt"
+    echo "[ERROR] $1" >&2
+}
+
+set -e
+
+mkdir -p "$3"
+
+for dir in "$1" "$2"; do
+    if [ ! -f "$dir/embedding.txt" ]; then
+        log_error "Job $(basename "$dir") has no stored drawing" "$3"
+        exit 1
+    fi
+done
+
+echo "Aligning layouts..."
+if ! ./spectra align -base-graph "$1/graph.txt" -base "$1/embedding.txt" -graph "$2/graph.txt" -in "$2/embedding.txt" \
+    -out "$3/out.aligned.txt" -report "$3/alignment.json" -svg "$3/out.svg" \
+    -base-label "${BASE_LABEL:-base}" -label "${LABEL:-aligned}"; then
+    log_error "Failed to align layouts" "$3"
+    exit 1
+fi
+
+echo "Uploading results to storage..."
+if ! /app/venv/bin/python ./upload_to_s3.py --local-path "$3" --s3-directory "$4"; then
+    log_error "Failed to upload files to storage" "$3"
+    exit 1
+fi
+
+echo "Comparison completed successfully!"
+exit 0
@@ -0,0 +1,97 @@
+// Seeded start vectors and canonical orientation of the computed eigenvectors
+// used by spectral_embed.
+//
+// Eigenvectors are only defined up to sign, and up to a rotation inside an
+// eigenspace of a repeated eigenvalue. Together with a fixed seed for the
+// start vectors this makes identical inputs produce identical coordinates.
+#ifndef SPECTRA_DETERMINISTIC_HPP
+#define SPECTRA_DETERMINISTIC_HPP
+
+#include <Eigen/Sparse>
+#include <Eigen/Dense>
+#include <vector>
+#include <random>
+#include <cmath>
+
+namespace deterministic {
+
+// Random start vector with entries in [-1, 1), sign-fixed and normalized.
+// Entries are built from the raw mt19937_64 output so the sequence does not
+// depend on the standard library's distribution implementation.
+inline Eigen::VectorXd randomStart(long n, std::mt19937_64& rng) {
+  Eigen::VectorXd v(n);
+  for (long i = 0; i < n; i++)
+    v(i) = 2.0 * ((rng() >> 11) * (1.0 / 9007199254740992.0)) - 1.0;
+  if (v(0) < 0)
+    v = -v;
+  v.normalize();
+  return v;
+}
+
+// Rayleigh quotient of the generalized problem: v'DMv / v'Dv.
+inline double rayleighQuotient(const Eigen::SparseMatrix<double,Eigen::RowMajor>& M, const Eigen::VectorXd& degrees,
+                               const Eigen::VectorXd& v) {
+  Eigen::VectorXd Dv = v.cwiseProduct(degrees);
+  double denom = v.dot(Dv);
+  if (denom == 0)
+    return 0;
+  Eigen::VectorXd Mv = M * v;
+  return Mv.dot(Dv) / denom;
+}
+
+// Flip the sign so that the third moment is positive; for symmetric vectors
+// fall back to making the entry of largest magnitude positive.
+inline void canonicalSign(Eigen::VectorXd& v) {
+  double skew = 0;
+  long maxIdx = 0;
+  for (long i = 0; i < v.size(); i++) {
+    skew += v(i) * v(i) * v(i);
+    if (fabs(v(i)) > fabs(v(maxIdx)))
+      maxIdx = i;
+  }
+  double scale = v.squaredNorm();
+  bool flip;
+  if (fabs(skew) > 1e-9 * scale * sqrt(scale))
+    flip = skew < 0;
+  else
+    flip = v(maxIdx) < 0;
+  if (flip)
+    v = -v;
+}
+
+// Rotate a pair of vectors spanning a repeated eigenvalue so that the first
+// vertex with a non-zero position lies on the positive first axis.
+inline void canonicalRotation(Eigen::VectorXd& a, Eigen::VectorXd& b) {
+  for (long i = 0; i < a.size(); i++) {
+    double r = sqrt(a(i) * a(i) + b(i) * b(i));
+    if (r < 1e-12)
+      continue;
+    double c = a(i) / r;
+    double s = b(i) / r;
+    Eigen::VectorXd ra = c * a + s * b;
+    Eigen::VectorXd rb = c * b - s * a;
+    a = ra;
+    b = rb;
+    return;
+  }
+}
+
+// Bring the computed eigenvectors into canonical orientation: pairs with
+// (numerically) equal eigenvalues are rotated first, then signs are fixed.
+inline void canonicalize(const Eigen::SparseMatrix<double,Eigen::RowMajor>& M, const Eigen::VectorXd& degrees,
+                         std::vector<Eigen::VectorXd*> vecs, double tol = 1e-6) {
+  for (size_t k = 0; k + 1 < vecs.size(); k++) {
+    double l1 = rayleighQuotient(M, degrees, *vecs[k]);
+    double l2 = rayleighQuotient(M, degrees, *vecs[k+1]);
+    if (fabs(l1 - l2) < tol) {
+      canonicalRotation(*vecs[k], *vecs[k+1]);
+      k++;
+    }
+  }
+  for (size_t k = 0; k < vecs.size(); k++)
+    canonicalSign(*vecs[k]);
+}
+
+} // namespace deterministic
+
+#endif
@@ -0,0 +1,38 @@
+#!/bin/bash
+
+# Compare the graphs of two finished jobs on the same vertex ids.
+# Usage: diff.sh <base job dir> <job dir> <output dir> <s3 directory>
+# Writes the added and removed vertices and edges, degree and component changes
+# (diff.json) and a drawing of the changes on the aligned layouts (out.svg).
+
+log_error() {
+    echo "[ERROR] $(date +'%Y-%m-%d %H:%M:%S') - $1" >> "$2/error.txt"
+    echo "[ERROR] $1" >&2
+}
+
+set -e
+
+mkdir -p "$3"
+
+for dir in "$1" "$2"; do
+    if [ ! -f "$dir/graph.txt" ] || [ ! -f "$dir/embedding.txt" ]; then
+        log_error "Job $(basename "$dir") has no stored graph and drawing" "$3"
+        exit 1
+    fi
+done
+
+echo "Comparing graphs..."
+if ! ./spectra diff -base-graph "$1/graph.txt" -graph "$2/graph.txt" -out "$3/diff.json" \
+    -base "$1/embedding.txt" -in "$2/embedding.txt" -svg "$3/out.svg"; then
+    log_error "Failed to compare graphs" "$3"
+    exit 1
+fi
+
+echo "Uploading results to storage..."
+if ! /app/venv/bin/python ./upload_to_s3.py --local-path "$3" --s3-directory "$4"; then
+    log_error "Failed to upload files to storage" "$3"
+    exit 1
+fi
+
+echo "Diff completed successfully!"
+exit 0
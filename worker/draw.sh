@@ -12,8 +12,8 @@ set -e
 echo "Starting processing pipeline..."
 
 echo "clearing mtx"
-if ! /app/venv/bin/python ./cleaner.py "$2"/example.mtx "$2"/graph.txt; then
-    log_error "Failed to upload files to storage" "$2"
+if ! /app/venv/bin/python ./cleaner.py "$2"/example.mtx "$2"/graph.txt "${WEIGHT_MODE:-ignore}" "${INTERPRETATION:-symmetric}"; then
+    log_error "Failed to clean matrix" "$2"
     exit 1
 fi
 
@@ -24,48 +24,149 @@ if ! g++ script.cpp -I/usr/local/include/eigen3 -I/usr/local/include/spectra -o
     exit 1
 fi
 
+# A new version of a graph starts from the eigenvectors of its parent job; Koren
+# runs on them directly, as Tutte smoothing would reshape the mapped layout
+INIT_ARGS=""
+SOLVER_REFINE=3
+if [ -n "${PARENT_DIR:-}" ] && [ -f "$PARENT_DIR/eigenvectors.txt" ]; then
+    echo "Mapping parent eigenvectors..."
+    if ./spectra warmstart -parent-graph "$PARENT_DIR/graph.txt" -parent "$PARENT_DIR/eigenvectors.txt" -graph "$1" -out "$2/warm_start.txt"; then
+        INIT_ARGS="--init $2/warm_start.txt"
+        SOLVER_REFINE=1
+    else
+        echo "No warm start possible, starting from scratch"
+    fi
+fi
+
+# An animated job keeps the layout every ANIMATION_EVERY solver steps, at most ANIMATION_MAX_FRAMES times
+FRAME_ARGS=""
+if [ -n "${ANIMATION_FORMAT:-}" ]; then
+    mkdir -p "$2/spectral_frames"
+    FRAME_ARGS="--frames $2/spectral_frames --frame-every ${ANIMATION_EVERY:-10} --max-frames ${ANIMATION_MAX_FRAMES:-200}"
+fi
+
 # Run executable with arguments
 echo "Running spectral embedding..."
-if ! ./spectral_embed "$1" 1 1 3 "$2"; then
+if ! ./spectral_embed "$1" "${COARSENING_TYPE:-1}" 1 "$SOLVER_REFINE" "$2" --strategy "${COARSENING_STRATEGY:-hem}" --levels "${COARSENING_LEVELS:-0}" --seed "${SEED:-1}" --laplacian "${LAPLACIAN:-generalized}" --vectors "${EIGENVECTORS:-3}" --report "$2/report.json" $INIT_ARGS $FRAME_ARGS; then
     log_error "Failed to run spectral embedding" "$2"
     exit 1
 fi
 
-# Compile and run C drawing code
-echo "Compiling drawing code..."
-if ! gcc -std=c99 -O2 -o draw draw.c -lm; then
-    log_error "Failed to compile drawing code" "$2"
+# Project the k-dimensional embedding to 2D and 3D
+echo "Projecting embedding..."
+if ! ./spectra project -in "$2/eigenvectors.txt" -out "$2/embedding.txt" -dims 2 -mode "${PROJECTION:-first}" -axes "${PROJECTION_AXES:-}" \
+    || ! ./spectra project -in "$2/eigenvectors.txt" -out "$2/embedding_3d.txt" -dims 3 -mode "${PROJECTION:-first}" -axes "${PROJECTION_AXES:-}"; then
+    log_error "Failed to project embedding" "$2"
+    exit 1
+fi
+
+# Partition the graph on the spectral embedding when requested
+if [ "${CLUSTERS:-0}" -gt 0 ] || [ "${BISECTION:-false}" = "true" ]; then
+    echo "Partitioning graph..."
+    if ! ./spectra partition -graph "$1" -in "$2/eigenvectors.txt" -out-dir "$2" -clusters "${CLUSTERS:-0}" -bisection="${BISECTION:-false}" -seed "${SEED:-1}"; then
+        log_error "Failed to partition graph" "$2"
+        exit 1
+    fi
+fi
+
+# Refine the projected drawings when requested
+if [ "${REFINEMENT:-none}" != "none" ]; then
+    echo "Refining layout..."
+    for emb in embedding.txt embedding_3d.txt; do
+        # intermediate frames are kept for the 2D drawing only
+        FRAMES_DIR=""
+        if [ "$emb" = "embedding.txt" ] && { [ "${REFINE_FRAMES:-0}" -gt 0 ] || [ -n "${ANIMATION_FORMAT:-}" ]; }; then
+            FRAMES_DIR="$2/frames"
+        fi
+        if ! ./spectra refine -graph "$1" -in "$2/$emb" -out "$2/$emb" -method "$REFINEMENT" \
+            -iterations "${REFINE_ITERATIONS:-0}" -pivots "${STRESS_PIVOTS:-50}" \
+            -gravity "${FORCE_GRAVITY:-0.05}" -repulsion "${FORCE_REPULSION:-1}" \
+            -frames-dir "$FRAMES_DIR" -frame-every "${REFINE_FRAMES:-${ANIMATION_EVERY:-10}}"; then
+            log_error "Failed to refine layout" "$2"
+            exit 1
+        fi
+    done
+fi
+
+# Keep the drawings of a new version in the orientation and scale of the parent's
+if [ -n "${PARENT_DIR:-}" ]; then
+    for emb in embedding.txt embedding_3d.txt; do
+        if [ ! -f "$PARENT_DIR/$emb" ]; then
+            continue
+        fi
+        echo "Aligning $emb to the parent job..."
+        if ! ./spectra align -base-graph "$PARENT_DIR/graph.txt" -base "$PARENT_DIR/$emb" -graph "$1" -in "$2/$emb" -out "$2/$emb"; then
+            echo "Could not align $emb to the parent job, keeping it as computed"
+        fi
+    done
+fi
+
+# Measure the quality of the 2D drawing
+echo "Computing layout metrics..."
+if ! ./spectra metrics -graph "$1" -in "$2/embedding.txt" -out "$2/metrics.json" -seed "${SEED:-1}"; then
+    log_error "Failed to compute layout metrics" "$2"
     exit 1
 fi
 
 echo "Generating visualization..."
-if ! ./draw "$1" "$2/embedding.txt" "$2/out.png"; then
+if ! ./spectra png -graph "$1" -in "$2/embedding.txt" -out "$2/out.png"; then
     log_error "Failed to generate visualization" "$2"
     exit 1
 fi
 
-echo "Compiling C++ code..."
-if ! g++ eigen3D.cpp -I/usr/local/include/eigen3 -I/usr/local/include/spectra -o spectral_embed_3d -O2; then
-    log_error "Failed to compile C++ code" "$2"
+# Sub-pixel edges are merged so the vector drawing of a large graph stays small
+echo "Generating .svg file..."
+if ! ./spectra svg -graph "$1" -in "$2/embedding.txt" -out "$2/out.svg" -min-edge-length 0.5; then
+    log_error "Failed to generate .svg file" "$2"
     exit 1
 fi
 
-echo "Running spectral embedding 3D..."
-if ! ./spectral_embed_3d "$1" 1 1 3 "$2"; then
-    log_error "Failed to run spectral embedding" "$2"
-    exit 1
+# The thumbnail previews the job in lists; it is drawn on demand without it
+echo "Generating thumbnail..."
+if ! ./spectra thumbnail -graph "$1" -in "$2/embedding.txt" -out "$2/thumbnail.png"; then
+    echo "Could not generate the thumbnail, it will be drawn on demand"
 fi
 
-# Generating .obj file
-echo "Generating .obj file..."
-if ! /app/venv/bin/python ./gen_obj.py "$2"; then
-    log_error "Failed to upload files to storage" "$2"
-    exit 1
+# Animate the layout as it was computed: the solver frames, then the force layout ones
+if [ -n "${ANIMATION_FORMAT:-}" ]; then
+    echo "Generating animation..."
+    case "$ANIMATION_FORMAT" in
+        gif) ANIMATION_OUT="$2/out.animation.gif" ;;
+        apng) ANIMATION_OUT="$2/out.animation.png" ;;
+        *) ANIMATION_OUT="$2/animation" ;;
+    esac
+    if ! ./spectra animate -graph "$1" -frames-dir "$2/spectral_frames,$2/frames" -out "$ANIMATION_OUT" \
+        -format "$ANIMATION_FORMAT" -delay "${ANIMATION_DELAY:-100}" -max-frames "${ANIMATION_MAX_FRAMES:-200}" -mode "${PROJECTION:-first}" -axes "${PROJECTION_AXES:-}" \
+        -width 600 -height 400 -antialias; then
+        echo "Could not generate the animation, the layout converged in too few steps"
+    fi
+fi
+
+# The tile pyramid is a convenience for the map view; tiles are drawn on demand without it
+echo "Generating map tiles..."
+if ! ./spectra tiles -graph "$1" -in "$2/embedding.txt" -out-dir "$2/tiles" -max-zoom "${TILE_ZOOM:-3}"; then
+    echo "Could not generate map tiles, they will be drawn on demand"
+    rm -rf "$2/tiles"
+fi
+
+# Export the 3D embedding in the model formats of the job
+for format in $(echo "${EXPORT_FORMATS:-obj}" | tr ',' ' '); do
+    echo "Generating .$format file..."
+    if ! ./spectra "$format" -graph "$1" -in "$2/embedding_3d.txt" -out "$2/out.$format"; then
+        log_error "Failed to generate .$format file" "$2"
+        exit 1
+    fi
+done
+
+# Snapshots of the 3D embedding preview it without a 3D viewer; the job is complete without them
+echo "Generating 3D snapshots..."
+if ! ./spectra snapshot -graph "$1" -in "$2/embedding_3d.txt" -out-dir "$2" -views "${SNAPSHOT_VIEWS:-iso}" -width 800 -height 800 -antialias; then
+    echo "Could not generate 3D snapshots, continuing without them"
 fi
 
 # Upload results to S3/MinIO
 echo "Uploading results to storage..."
-if ! /app/venv/bin/python ./upload_to_s3.py --local-path "$2" --s3-directory "$3"; then
+if ! /app/venv/bin/python ./upload_to_s3.py --local-path "$2" --s3-directory "$3" --tree tiles --tree animation; then
     log_error "Failed to upload files to storage" "$2"
     exit 1
 fi
@@ -20,6 +20,7 @@ type App struct {
 	logger                       *zap.Logger
 	isClosed                     atomic.Bool
 	allDone                      chan struct{}
+	tiles                        *tileCache
 }
 
 func NewApp(logger *zap.Logger) *App {
@@ -28,6 +29,7 @@ func NewApp(logger *zap.Logger) *App {
 		currentWorkingProcessesCount: 0,
 		m:                            &sync.Mutex{},
 		logger:                       logger,
+		tiles:                        newTileCache(),
 	}
 }
 
@@ -70,6 +72,19 @@ func (app *App) createJob(graph GraphDTO) {
 	}
 	_, _ = file.WriteString(*graph.Content)
 	cmd := exec.Command("sh", "draw.sh", fmt.Sprintf("%s/graph.txt", path), path, *graph.ID)
+	params := JobParams{}
+	if graph.Params != nil {
+		params = *graph.Params
+	}
+	cmd.Env = append(os.Environ(), params.Env()...)
+	if graph.ParentID != nil {
+		parentPath := fmt.Sprintf("/var/worker/graph-%s", *graph.ParentID)
+		if _, err := os.Stat(parentPath); err == nil {
+			cmd.Env = append(cmd.Env, "PARENT_DIR="+parentPath)
+		} else {
+			_, _ = logFile.WriteString(fmt.Sprintf("Parent job %s not found, starting from scratch\n", *graph.ParentID))
+		}
+	}
 	err = cmd.Start()
 	if err != nil {
 		_, _ = logFile.WriteString(fmt.Sprintf("Failed to start command: %v\n", err))
@@ -128,6 +143,9 @@ func (app *App) PingHandler(w http.ResponseWriter, r *http.Request) {
 				errMsg := fmt.Sprintf("Failed to read result file: %v", err)
 				res.Err = &errMsg
 			}
+			res.Report = readSpectralReport(path)
+			res.Partition = readPartition(path)
+			res.Metrics = readLayoutMetrics(path)
 
 			_ = encoder.Encode(res)
 			return
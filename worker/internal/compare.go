@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// CompareHandler aligns the drawing of one finished job to the drawing of another.
+// The result is stored below the base job, so comparing the same pair again
+// replaces it.
+func (app *App) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	app.comparePair(w, r, "comparisons", "compare.sh", func(req CompareRequest) []string {
+		return []string{"BASE_LABEL=job " + req.ID, "LABEL=job " + req.OtherID + " (aligned)"}
+	})
+}
+
+// DiffHandler lists the changes from the graph of one finished job to the graph
+// of another and draws them on the aligned layouts, below the base job like
+// comparisons
+func (app *App) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	app.comparePair(w, r, "diffs", "diff.sh", nil)
+}
+
+// comparePair runs script on the job directories of a CompareRequest, writing
+// into <base job>/<kind>/<other job>
+func (app *App) comparePair(w http.ResponseWriter, r *http.Request, kind, script string, env func(CompareRequest) []string) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.OtherID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	basePath := fmt.Sprintf("/var/worker/graph-%s", req.ID)
+	path := fmt.Sprintf("/var/worker/graph-%s", req.OtherID)
+	for _, p := range []string{basePath, path} {
+		if _, err := os.Stat(p); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+	dir := filepath.Join(kind, req.OtherID)
+	outPath := filepath.Join(basePath, dir)
+	_ = os.RemoveAll(outPath)
+	cmd := exec.Command("sh", script, basePath, path, outPath, req.ID+"/"+filepath.ToSlash(dir))
+	cmd.Env = os.Environ()
+	if env != nil {
+		cmd.Env = append(cmd.Env, env(req)...)
+	}
+	app.runSync(w, req.ID, outPath, cmd)
+}
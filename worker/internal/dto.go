@@ -1,13 +1,82 @@
 package internal
 
+import (
+	"encoding/json"
+
+	"worker/pkg/embedding"
+)
+
 type GraphDTO struct {
-	ID      *string `json:"id"`
-	Content *string `json:"content"`
+	ID      *string    `json:"id"`
+	Content *string    `json:"content"`
+	Params  *JobParams `json:"params"`
+	// ParentID is the job holding the previous version of the graph, whose
+	// eigenvectors warm-start the solver
+	ParentID *string `json:"parent_id"`
 }
 
 type TaskStatus struct {
-	ID     string  `json:"id"`
-	Status string  `json:"status"`
-	Err    *string `json:"err"`
-	Result *string `json:"result"`
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Err       *string         `json:"err"`
+	Result    *string         `json:"result"`
+	Report    json.RawMessage `json:"report,omitempty"`
+	Partition json.RawMessage `json:"partition,omitempty"`
+	Metrics   json.RawMessage `json:"metrics,omitempty"`
+	Alignment json.RawMessage `json:"alignment,omitempty"`
+	Diff      json.RawMessage `json:"diff,omitempty"`
+}
+
+// CompareRequest asks to align the drawing of job OtherID to the drawing of job ID,
+// or to compare their graphs
+type CompareRequest struct {
+	ID      string `json:"id"`
+	OtherID string `json:"other_id"`
+}
+
+// VertexRequest asks for one vertex of the drawing of a finished job
+type VertexRequest struct {
+	ID     string `json:"id"`
+	Vertex int    `json:"vertex"`
+}
+
+// VertexInfo locates a vertex in the 2D drawing of a finished job and lists
+// its neighbors, at most maxNeighbors of them. In a bipartite graph Side tells
+// whether the vertex is a "row" or a "column" of the matrix and Index its row
+// or column number; column j is vertex rows + j.
+type VertexInfo struct {
+	Vertex    int       `json:"vertex"`
+	Side      string    `json:"side,omitempty"`
+	Index     int       `json:"index"`
+	Position  []float64 `json:"position"`
+	Degree    int       `json:"degree"`
+	Neighbors []int     `json:"neighbors"`
+	Truncated bool      `json:"truncated"` // the neighbors were cut at the limit
+}
+
+// ThumbnailRequest asks for the thumbnail of a finished job
+type ThumbnailRequest struct {
+	ID string `json:"id"`
+}
+
+// TileRequest asks for one tile of the map of a finished job's drawing
+type TileRequest struct {
+	ID string `json:"id"`
+	Z  int    `json:"z"`
+	X  int    `json:"x"`
+	Y  int    `json:"y"`
+}
+
+// ProjectionRequest asks to re-project the stored embedding of a finished job
+type ProjectionRequest struct {
+	ID         string               `json:"id"`
+	Projection embedding.Projection `json:"projection"`
+	Refinement Refinement           `json:"refinement"`
+}
+
+// RenderRequest asks to draw the stored embedding of a finished job with new options
+type RenderRequest struct {
+	ID       string       `json:"id"`
+	RenderID string       `json:"render_id"`
+	Params   RenderParams `json:"params"`
 }
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+
+	"worker/pkg/embedding"
+)
+
+// JobParams mirrors the per-job options stored by the backend
+type JobParams struct {
+	Coarsening       string               `json:"coarsening"`
+	CoarseningLevels int                  `json:"coarsening_levels"`
+	Seed             *int64               `json:"seed"`
+	WeightMode       string               `json:"weight_mode"`
+	Interpretation   string               `json:"interpretation"`
+	Laplacian        string               `json:"laplacian"`
+	Eigenvectors     int                  `json:"eigenvectors"`
+	Projection       embedding.Projection `json:"projection"`
+	Clusters         int                  `json:"clusters"`
+	Bisection        bool                 `json:"bisection"`
+	Refinement       Refinement           `json:"refinement"`
+	Exports          []string             `json:"exports"`   // 3D model formats: obj, ply, glb or vtk
+	Snapshots        []string             `json:"snapshots"` // views of the 3D snapshots
+	Animation        Animation            `json:"animation"`
+}
+
+// Animation asks for the layout to be saved every Every solver steps and drawn
+// as an animation: a GIF, an animated PNG or a directory of PNG frames
+type Animation struct {
+	Format    string `json:"format"` // "gif", "apng" or "frames", empty for none
+	Every     int    `json:"every"`
+	Delay     int    `json:"delay"`      // milliseconds per frame
+	MaxFrames int    `json:"max_frames"` // solver frames kept at most
+}
+
+// Env returns the environment variables draw.sh reads the animation from
+func (a Animation) Env() []string {
+	if a.Format == "" {
+		return nil
+	}
+	env := []string{"ANIMATION_FORMAT=" + a.Format}
+	if a.Every > 0 {
+		env = append(env, "ANIMATION_EVERY="+strconv.Itoa(a.Every))
+	}
+	if a.Delay > 0 {
+		env = append(env, "ANIMATION_DELAY="+strconv.Itoa(a.Delay))
+	}
+	if a.MaxFrames > 0 {
+		env = append(env, "ANIMATION_MAX_FRAMES="+strconv.Itoa(a.MaxFrames))
+	}
+	return env
+}
+
+// Refinement selects the layout stage run on the projected spectral embedding
+type Refinement struct {
+	Method     string   `json:"method"` // "none", "stress" or "force"
+	Iterations int      `json:"iterations"`
+	Pivots     int      `json:"pivots"`
+	Gravity    *float64 `json:"gravity"`
+	Repulsion  float64  `json:"repulsion"`
+	Frames     int      `json:"frames"` // save the force layout every Frames iterations, 0 to skip
+}
+
+// Env returns the environment variables draw.sh and render.sh read the refinement from
+func (r Refinement) Env() []string {
+	if r.Method == "" {
+		return nil
+	}
+	env := []string{"REFINEMENT=" + r.Method}
+	if r.Iterations > 0 {
+		env = append(env, "REFINE_ITERATIONS="+strconv.Itoa(r.Iterations))
+	}
+	if r.Pivots > 0 {
+		env = append(env, "STRESS_PIVOTS="+strconv.Itoa(r.Pivots))
+	}
+	if r.Gravity != nil {
+		env = append(env, "FORCE_GRAVITY="+strconv.FormatFloat(*r.Gravity, 'g', -1, 64))
+	}
+	if r.Repulsion > 0 {
+		env = append(env, "FORCE_REPULSION="+strconv.FormatFloat(r.Repulsion, 'g', -1, 64))
+	}
+	if r.Frames > 0 {
+		env = append(env, "REFINE_FRAMES="+strconv.Itoa(r.Frames))
+	}
+	return env
+}
+
+// coarseningType maps a coarsening strategy to the spectral_embed coarsening argument
+func (p JobParams) coarseningType() string {
+	switch p.Coarsening {
+	case "none":
+		return "0"
+	case "hem", "mis":
+		return "3"
+	default:
+		return "1"
+	}
+}
+
+// Env returns the environment variables draw.sh reads the job options from
+func (p JobParams) Env() []string {
+	strategy := p.Coarsening
+	if strategy != "hem" && strategy != "mis" {
+		strategy = "hem"
+	}
+	env := []string{
+		"COARSENING_TYPE=" + p.coarseningType(),
+		"COARSENING_STRATEGY=" + strategy,
+		"COARSENING_LEVELS=" + strconv.Itoa(p.CoarseningLevels),
+	}
+	if p.WeightMode != "" {
+		env = append(env, "WEIGHT_MODE="+p.WeightMode)
+	}
+	if p.Interpretation != "" {
+		env = append(env, "INTERPRETATION="+p.Interpretation)
+	}
+	if p.Laplacian != "" {
+		env = append(env, "LAPLACIAN="+p.Laplacian)
+	}
+	if p.Seed != nil {
+		env = append(env, "SEED="+strconv.FormatInt(*p.Seed, 10))
+	}
+	if p.Eigenvectors > 0 {
+		env = append(env, "EIGENVECTORS="+strconv.Itoa(p.Eigenvectors))
+	}
+	if p.Clusters > 0 {
+		env = append(env, "CLUSTERS="+strconv.Itoa(p.Clusters))
+	}
+	if p.Bisection {
+		env = append(env, "BISECTION=true")
+	}
+	if len(p.Exports) > 0 {
+		env = append(env, "EXPORT_FORMATS="+strings.Join(p.Exports, ","))
+	}
+	if len(p.Snapshots) > 0 {
+		env = append(env, "SNAPSHOT_VIEWS="+strings.Join(p.Snapshots, ","))
+	}
+	env = append(env, p.Refinement.Env()...)
+	env = append(env, p.Animation.Env()...)
+	return append(env, projectionEnv(p.Projection)...)
+}
+
+// projectionEnv returns the environment variables draw.sh and render.sh read the projection from
+func projectionEnv(p embedding.Projection) []string {
+	var env []string
+	if p.Mode != "" {
+		env = append(env, "PROJECTION="+p.Mode)
+	}
+	if len(p.Axes) > 0 {
+		axes := make([]string, len(p.Axes))
+		for i, a := range p.Axes {
+			axes[i] = strconv.Itoa(a)
+		}
+		env = append(env, "PROJECTION_AXES="+strings.Join(axes, ","))
+	}
+	return env
+}
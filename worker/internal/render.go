@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"worker/pkg/embedding"
+)
+
+// RenderParams mirrors the drawing options of a render task stored by the backend
+type RenderParams struct {
+	Projection    embedding.Projection `json:"projection"`
+	Formats       []string             `json:"formats"`
+	Width         int                  `json:"width"`
+	Height        int                  `json:"height"`
+	Margin        int                  `json:"margin"`
+	EdgeColor     string               `json:"edge_color"`
+	EdgeAlpha     *float64             `json:"edge_alpha"`
+	Background    string               `json:"background"`
+	VertexColor   string               `json:"vertex_color"`
+	VertexSize    float64              `json:"vertex_size"`
+	Antialias     bool                 `json:"antialias"`
+	MinEdgeLength float64              `json:"min_edge_length"`
+	ColorBy       string               `json:"color_by"`
+	Eigenvector   int                  `json:"eigenvector"`
+	Attributes    map[string]float64   `json:"attributes"`
+	SizeBy        string               `json:"size_by"`
+	EdgeColorBy   string               `json:"edge_color_by"`
+	Colormap      string               `json:"colormap"`
+	Mode          string               `json:"mode"`
+	DensityScale  string               `json:"density_scale"`
+	Refinement    Refinement           `json:"refinement"`
+	Labels        *Labels              `json:"labels"`
+}
+
+// Labels selects the vertices named in the drawings: the Count of highest
+// degree for mode "top", all for "all" and the listed Vertices for "list"
+type Labels struct {
+	Mode     string `json:"mode"`
+	Count    int    `json:"count"`
+	Vertices []int  `json:"vertices"`
+}
+
+// spec returns the labels in the form of the spectra -labels flag
+func (l Labels) spec() string {
+	switch l.Mode {
+	case "top":
+		return "top:" + strconv.Itoa(l.Count)
+	case "list":
+		ids := make([]string, len(l.Vertices))
+		for i, v := range l.Vertices {
+			ids[i] = strconv.Itoa(v)
+		}
+		return strings.Join(ids, ",")
+	}
+	return l.Mode
+}
+
+// Env returns the environment variables render.sh reads the options from
+func (p RenderParams) Env() []string {
+	env := append(projectionEnv(p.Projection), p.Refinement.Env()...)
+	if len(p.Formats) > 0 {
+		env = append(env, "RENDER_FORMATS="+strings.Join(p.Formats, ","))
+	}
+	if p.Width > 0 && p.Height > 0 {
+		env = append(env, "RENDER_WIDTH="+strconv.Itoa(p.Width), "RENDER_HEIGHT="+strconv.Itoa(p.Height))
+	}
+	if p.Margin > 0 {
+		env = append(env, "RENDER_MARGIN="+strconv.Itoa(p.Margin))
+	}
+	if p.EdgeColor != "" {
+		env = append(env, "EDGE_COLOR="+p.EdgeColor)
+	}
+	if p.EdgeAlpha != nil {
+		env = append(env, "EDGE_ALPHA="+strconv.FormatFloat(*p.EdgeAlpha, 'g', -1, 64))
+	}
+	if p.Background != "" {
+		env = append(env, "BACKGROUND="+p.Background)
+	}
+	if p.VertexColor != "" {
+		env = append(env, "VERTEX_COLOR="+p.VertexColor)
+	}
+	if p.VertexSize > 0 {
+		env = append(env, "VERTEX_SIZE="+strconv.FormatFloat(p.VertexSize, 'g', -1, 64))
+	}
+	if p.Antialias {
+		env = append(env, "ANTIALIAS=true")
+	}
+	if p.MinEdgeLength > 0 {
+		env = append(env, "MIN_EDGE_LENGTH="+strconv.FormatFloat(p.MinEdgeLength, 'g', -1, 64))
+	}
+	if p.ColorBy != "" {
+		env = append(env, "COLOR_BY="+p.ColorBy)
+	}
+	if p.Eigenvector > 0 {
+		env = append(env, "EIGENVECTOR="+strconv.Itoa(p.Eigenvector))
+	}
+	if p.SizeBy != "" {
+		env = append(env, "SIZE_BY="+p.SizeBy)
+	}
+	if p.EdgeColorBy != "" {
+		env = append(env, "EDGE_COLOR_BY="+p.EdgeColorBy)
+	}
+	if p.Colormap != "" {
+		env = append(env, "COLORMAP="+p.Colormap)
+	}
+	if p.Mode != "" {
+		env = append(env, "RENDER_MODE="+p.Mode)
+	}
+	if p.DensityScale != "" {
+		env = append(env, "DENSITY_SCALE="+p.DensityScale)
+	}
+	if p.Labels != nil {
+		env = append(env, "LABELS="+p.Labels.spec())
+	}
+	return env
+}
+
+// writeAttributes stores vertex values as lines "vertex value" for spectra, in
+// vertex order
+func writeAttributes(path string, attrs map[string]float64) error {
+	type attribute struct {
+		vertex int
+		value  float64
+	}
+	list := make([]attribute, 0, len(attrs))
+	for key, value := range attrs {
+		v, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid vertex id %q", key)
+		}
+		list = append(list, attribute{v, value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].vertex < list[j].vertex })
+	var b strings.Builder
+	for _, a := range list {
+		fmt.Fprintf(&b, "%d %s\n", a.vertex, strconv.FormatFloat(a.value, 'g', -1, 64))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// RenderHandler draws a finished job again from its stored eigenvectors. It runs
+// synchronously, since no spectral computation is involved.
+func (app *App) RenderHandler(w http.ResponseWriter, r *http.Request) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req RenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.RenderID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	app.render(w, req.ID, filepath.Join("renders", req.RenderID), req.Params)
+}
+
+// ProjectHandler re-projects the eigenvectors of a finished job with the default drawing
+// options, refining the drawing as the job did
+func (app *App) ProjectHandler(w http.ResponseWriter, r *http.Request) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req ProjectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	app.render(w, req.ID, filepath.Join("projections", req.Projection.Name()), RenderParams{Projection: req.Projection, Refinement: req.Refinement})
+}
+
+// render runs render.sh for job id into the job subdirectory dir, which is also
+// used as the storage directory below the job's, and writes the task status
+func (app *App) render(w http.ResponseWriter, id string, dir string, params RenderParams) {
+	path := fmt.Sprintf("/var/worker/graph-%s", id)
+	if _, err := os.Stat(path); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	outPath := filepath.Join(path, dir)
+	_ = os.RemoveAll(outPath)
+	env := params.Env()
+	if len(params.Attributes) > 0 {
+		attrPath := filepath.Join(outPath, "attributes.txt")
+		if err := os.MkdirAll(outPath, 0755); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := writeAttributes(attrPath, params.Attributes); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		env = append(env, "ATTRIBUTES_FILE="+attrPath)
+	}
+	cmd := exec.Command("sh", "render.sh", path, outPath, id+"/"+filepath.ToSlash(dir))
+	cmd.Env = append(os.Environ(), env...)
+	app.runSync(w, id, outPath, cmd)
+}
+
+// runSync runs a script writing into outPath and answers with its outcome: the
+// uploaded artifacts from result.txt, or the error the script logged
+func (app *App) runSync(w http.ResponseWriter, id string, outPath string, cmd *exec.Cmd) {
+	output, runErr := cmd.CombinedOutput()
+
+	res := TaskStatus{
+		ID:     id,
+		Status: "completed",
+	}
+	if runErr != nil {
+		errMsg := fmt.Sprintf("%s failed: %v", cmd.Args[1], runErr)
+		if content, err := os.ReadFile(filepath.Join(outPath, "error.txt")); err == nil && len(content) > 0 {
+			errMsg = strings.TrimSpace(string(content))
+		}
+		fmt.Printf("%s for job %s failed:\n%s\n", cmd.Args[1], id, output)
+		res.Err = &errMsg
+	} else if content, err := os.ReadFile(filepath.Join(outPath, "result.txt")); err == nil {
+		result := string(content)
+		res.Result = &result
+		res.Metrics = readLayoutMetrics(outPath)
+		res.Alignment = readAlignment(outPath)
+		res.Diff = readDiff(outPath)
+	} else {
+		errMsg := fmt.Sprintf("Failed to read result file: %v", err)
+		res.Err = &errMsg
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
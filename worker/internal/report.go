@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// readSpectralReport returns the solver report of a job directory, or nil if none was written
+func readSpectralReport(path string) json.RawMessage {
+	return readJSON(filepath.Join(path, "report.json"))
+}
+
+// readPartition returns the cluster and bisection metrics of a job directory, or nil if
+// the job was not partitioned
+func readPartition(path string) json.RawMessage {
+	return readJSON(filepath.Join(path, "partition.json"))
+}
+
+// readLayoutMetrics returns the quality metrics of the drawing in a job or render
+// directory, or nil if they were not computed
+func readLayoutMetrics(path string) json.RawMessage {
+	return readJSON(filepath.Join(path, "metrics.json"))
+}
+
+// readAlignment returns the Procrustes transform and residual of a comparison
+// directory, or nil if there is none
+func readAlignment(path string) json.RawMessage {
+	return readJSON(filepath.Join(path, "alignment.json"))
+}
+
+// readDiff returns the graph changes of a diff directory, or nil if there are none
+func readDiff(path string) json.RawMessage {
+	return readJSON(filepath.Join(path, "diff.json"))
+}
+
+func readJSON(file string) json.RawMessage {
+	content, err := os.ReadFile(file)
+	if err != nil || !json.Valid(content) {
+		return nil
+	}
+	return content
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+	"worker/pkg/render"
+)
+
+// maxTileSources bounds the drawings kept in memory for tile requests
+const maxTileSources = 4
+
+// tileSource is the graph and 2D drawing of a job, loaded once for its tiles
+// and vertex lookups
+type tileSource struct {
+	graph    *graph.Graph
+	emb      *embedding.Embedding
+	sides    graph.Sides
+	modTime  time.Time // of embedding.txt, to notice a redrawn job
+	lastUsed time.Time
+}
+
+// tileCache keeps the drawings of the jobs whose tiles or vertices were asked
+// for last
+type tileCache struct {
+	m       sync.Mutex
+	sources map[string]*tileSource
+}
+
+func newTileCache() *tileCache {
+	return &tileCache{sources: make(map[string]*tileSource)}
+}
+
+// get returns the drawing of the job in dir, reading it when it is not cached
+// or changed on disk
+func (c *tileCache) get(dir string) (*tileSource, error) {
+	embPath := filepath.Join(dir, "embedding.txt")
+	info, err := os.Stat(embPath)
+	if err != nil {
+		return nil, err
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if src, ok := c.sources[dir]; ok && src.modTime.Equal(info.ModTime()) {
+		src.lastUsed = time.Now()
+		return src, nil
+	}
+	g, err := graph.Read(filepath.Join(dir, "graph.txt"))
+	if err != nil {
+		return nil, err
+	}
+	e, err := embedding.Read(embPath)
+	if err != nil {
+		return nil, err
+	}
+	sides, err := graph.ReadSides(filepath.Join(dir, graph.SidesFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.sources) >= maxTileSources {
+		var oldest string
+		for key, src := range c.sources {
+			if oldest == "" || src.lastUsed.Before(c.sources[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		delete(c.sources, oldest)
+	}
+	src := &tileSource{graph: g, emb: e, sides: sides, modTime: info.ModTime(), lastUsed: time.Now()}
+	c.sources[dir] = src
+	return src, nil
+}
+
+// TileHandler answers with one PNG tile of the map of a finished job. Tiles of
+// the pyramid drawn with the job are served as they are; others are drawn with
+// the default style, kept next to them for the next request and uploaded to
+// tiles/z/x/y.png under the job's artifact prefix, like the pyramid.
+func (app *App) TileHandler(w http.ResponseWriter, r *http.Request) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req TileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if req.Z < 0 || req.Z > render.MaxTileZoom || req.X < 0 || req.Y < 0 || req.X >= 1<<req.Z || req.Y >= 1<<req.Z {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	path := fmt.Sprintf("/var/worker/graph-%s", req.ID)
+	tilePath := filepath.Join(path, "tiles", strconv.Itoa(req.Z), strconv.Itoa(req.X), strconv.Itoa(req.Y)+".png")
+	if _, err := os.Stat(tilePath); err != nil {
+		src, err := app.tiles.get(path)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		img, err := render.RasterizeTile(src.graph, src.emb, render.DefaultStyle(), req.Z, req.X, req.Y)
+		if err != nil {
+			fmt.Printf("Tile %d/%d/%d of job %s failed: %v\n", req.Z, req.X, req.Y, req.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := render.WriteTileFile(tilePath, img); err != nil {
+			fmt.Printf("Tile %d/%d/%d of job %s failed: %v\n", req.Z, req.X, req.Y, req.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		go uploadArtifact(path, req.ID, tilePath)
+	}
+	content, err := os.ReadFile(tilePath)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(content)
+}
+
+// uploadArtifact stores a file drawn after the job finished under the job's
+// artifact prefix, at its path relative to the job directory
+func uploadArtifact(dir, prefix, file string) {
+	relative, err := filepath.Rel(dir, file)
+	if err != nil {
+		fmt.Printf("Upload of %s failed: %v\n", file, err)
+		return
+	}
+	cmd := exec.Command("/app/venv/bin/python", "./upload_to_s3.py", "--local-path", dir, "--s3-directory", prefix, "--file", relative)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf("Upload of %s for job %s failed: %v\n%s\n", relative, prefix, err, output)
+	}
+}
+
+// ThumbnailHandler answers with the PNG thumbnail of a finished job. Jobs
+// drawn before thumbnails existed get theirs drawn on the first request.
+func (app *App) ThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req ThumbnailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	path := fmt.Sprintf("/var/worker/graph-%s", req.ID)
+	thumbPath := filepath.Join(path, "thumbnail.png")
+	if _, err := os.Stat(thumbPath); err != nil {
+		g, err := graph.Read(filepath.Join(path, "graph.txt"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		e, err := embedding.Read(filepath.Join(path, "embedding.txt"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		img, err := render.Thumbnail(g, e, render.ThumbnailSize)
+		if err == nil {
+			err = render.WriteTileFile(thumbPath, img)
+		}
+		if err != nil {
+			fmt.Printf("Thumbnail of job %s failed: %v\n", req.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	content, err := os.ReadFile(thumbPath)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxNeighbors bounds the neighbors listed for a vertex
+const maxNeighbors = 1000
+
+// VertexHandler answers with the position of a vertex in the 2D drawing of a
+// finished job, its degree and its neighbors. The drawing is read through the
+// tile cache, so looking up several vertices of a job reads it once. Vertices
+// without edges are not drawn and are not found. Vertices are asked for by id,
+// so column j of a bipartite graph is vertex rows + j; the answer names its side.
+func (app *App) VertexHandler(w http.ResponseWriter, r *http.Request) {
+	if app.isClosed.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer r.Body.Close()
+	var req VertexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.Vertex < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	src, err := app.tiles.get(fmt.Sprintf("/var/worker/graph-%s", req.ID))
+	if err != nil || req.Vertex >= src.emb.Len() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	nbrs := src.graph.Neighbors(req.Vertex)
+	if len(nbrs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	side, index := src.sides.Locate(req.Vertex)
+	info := VertexInfo{
+		Vertex:    req.Vertex,
+		Side:      side,
+		Index:     index,
+		Position:  src.emb.Coords[req.Vertex],
+		Degree:    len(nbrs),
+		Neighbors: nbrs,
+	}
+	if len(nbrs) > maxNeighbors {
+		info.Neighbors, info.Truncated = nbrs[:maxNeighbors], true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(info)
+}
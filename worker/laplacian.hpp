@@ -0,0 +1,215 @@
+// Laplacian variants used by spectral_embed.
+//
+// The power iteration finds dominant eigenvectors, so every operator exposes
+// an iteration matrix M whose largest eigenvalues correspond to the wanted
+// (smallest) eigenvalues of its Laplacian, together with the weights of the
+// inner product its eigenvectors are orthogonal in.
+#ifndef SPECTRA_LAPLACIAN_HPP
+#define SPECTRA_LAPLACIAN_HPP
+
+#include <Eigen/Sparse>
+#include <Eigen/Dense>
+#include <vector>
+#include <string>
+#include <memory>
+#include <cmath>
+#include <iostream>
+
+namespace laplacian {
+
+typedef Eigen::SparseMatrix<double,Eigen::RowMajor> SpMat;
+
+class Operator {
+public:
+  Operator(const SpMat& A, const Eigen::VectorXd& degrees) : A_(A), degrees_(degrees) {}
+  virtual ~Operator() {}
+
+  virtual const char* name() const = 0;
+  // Matrix iterated by the power method.
+  const SpMat& matrix() const { return M_; }
+  // Weights w of the inner product <x, y> = sum w_i x_i y_i.
+  const Eigen::VectorXd& weights() const { return W_; }
+  // Eigenvector of the smallest Laplacian eigenvalue, deflated before the
+  // layout vectors are computed.
+  virtual Eigen::VectorXd firstVector() const = 0;
+  // Laplacian eigenvalue belonging to an eigenvalue mu of matrix().
+  virtual double eigenvalue(double mu) const = 0;
+
+  const SpMat& adjacency() const { return A_; }
+  const Eigen::VectorXd& degrees() const { return degrees_; }
+
+protected:
+  SpMat A_;
+  Eigen::VectorXd degrees_;
+  SpMat M_;
+  Eigen::VectorXd W_;
+
+  // Upper bound of the spectrum of D - A and D + A (Gershgorin).
+  double spectralBound() const {
+    double dmax = 0;
+    for (long i = 0; i < degrees_.size(); i++)
+      dmax = std::max(dmax, degrees_(i));
+    return dmax > 0 ? 2.0 * dmax : 1.0;
+  }
+
+  // M = I + s * A, with the diagonal of A (self loops) kept.
+  SpMat identityPlus(const SpMat& S) const {
+    typedef Eigen::Triplet<double> T;
+    std::vector<T> tripletList;
+    tripletList.reserve(S.nonZeros() + S.rows());
+    for (long i = 0; i < S.rows(); i++) {
+      tripletList.push_back(T(i, i, 1.0));
+      for (SpMat::InnerIterator it(S, i); it; ++it)
+        tripletList.push_back(T(i, it.col(), it.value()));
+    }
+    SpMat M(S.rows(), S.cols());
+    M.setFromTriplets(tripletList.begin(), tripletList.end());
+    return M;
+  }
+
+  // Row i of A scaled by rowScale(i) and column j by colScale(j).
+  SpMat scaled(const Eigen::VectorXd& rowScale, const Eigen::VectorXd& colScale, double factor) const {
+    typedef Eigen::Triplet<double> T;
+    std::vector<T> tripletList;
+    tripletList.reserve(A_.nonZeros());
+    for (long i = 0; i < A_.rows(); i++) {
+      for (SpMat::InnerIterator it(A_, i); it; ++it)
+        tripletList.push_back(T(i, it.col(), factor * rowScale(i) * it.value() * colScale(it.col())));
+    }
+    SpMat S(A_.rows(), A_.cols());
+    S.setFromTriplets(tripletList.begin(), tripletList.end());
+    return S;
+  }
+
+  Eigen::VectorXd inverseOf(const Eigen::VectorXd& v) const {
+    Eigen::VectorXd inv(v.size());
+    for (long i = 0; i < v.size(); i++)
+      inv(i) = v(i) > 0 ? 1.0 / v(i) : 0.0;
+    return inv;
+  }
+};
+
+// ---------------------------------------------------------------------
+// Degree-normalized generalized problem L x = lambda D x, solved with
+// M = (I + D^-1 A) / 2 and D-orthogonalization. Since L x = lambda D x is
+// equivalent to (I - D^-1 A) x = lambda x, the random-walk Laplacian uses
+// the same operator and has the same eigenvectors.
+class Generalized : public Operator {
+public:
+  Generalized(const SpMat& A, const Eigen::VectorXd& degrees, const char *label = "generalized")
+      : Operator(A, degrees), label_(label) {
+    Eigen::VectorXd ones = Eigen::VectorXd::Ones(degrees.size());
+    M_ = identityPlus(scaled(inverseOf(degrees), ones, 1.0)) * 0.5;
+    W_ = degrees;
+  }
+  const char* name() const { return label_; }
+  Eigen::VectorXd firstVector() const {
+    Eigen::VectorXd v = Eigen::VectorXd::Ones(degrees_.size());
+    v.normalize();
+    return v;
+  }
+  double eigenvalue(double mu) const { return 2.0 * (1.0 - mu); }
+
+private:
+  const char *label_;
+};
+
+// ---------------------------------------------------------------------
+// Combinatorial Laplacian L = D - A, solved with M = I - L / c where c
+// bounds the spectrum of L.
+class Combinatorial : public Operator {
+public:
+  Combinatorial(const SpMat& A, const Eigen::VectorXd& degrees) : Operator(A, degrees) {
+    c_ = spectralBound();
+    Eigen::VectorXd ones = Eigen::VectorXd::Ones(degrees.size());
+    M_ = identityPlus(scaled(ones, ones, 1.0 / c_));
+    for (long i = 0; i < degrees.size(); i++)
+      M_.coeffRef(i, i) -= degrees(i) / c_;
+    W_ = ones;
+  }
+  const char* name() const { return "combinatorial"; }
+  Eigen::VectorXd firstVector() const {
+    Eigen::VectorXd v = Eigen::VectorXd::Ones(degrees_.size());
+    v.normalize();
+    return v;
+  }
+  double eigenvalue(double mu) const { return c_ * (1.0 - mu); }
+
+private:
+  double c_;
+};
+
+// ---------------------------------------------------------------------
+// Symmetric normalized Laplacian I - D^-1/2 A D^-1/2, solved with
+// M = (I + D^-1/2 A D^-1/2) / 2.
+class SymmetricNormalized : public Operator {
+public:
+  SymmetricNormalized(const SpMat& A, const Eigen::VectorXd& degrees) : Operator(A, degrees) {
+    Eigen::VectorXd invSqrt = inverseOf(degrees.cwiseSqrt());
+    M_ = identityPlus(scaled(invSqrt, invSqrt, 1.0)) * 0.5;
+    W_ = Eigen::VectorXd::Ones(degrees.size());
+  }
+  const char* name() const { return "symmetric"; }
+  Eigen::VectorXd firstVector() const {
+    Eigen::VectorXd v = degrees_.cwiseSqrt();
+    v.normalize();
+    return v;
+  }
+  double eigenvalue(double mu) const { return 2.0 * (1.0 - mu); }
+};
+
+// ---------------------------------------------------------------------
+// Signless Laplacian Q = D + A, solved with M = I - Q / c. Q has no known
+// trivial eigenvector, so the first one is found by plain power iteration.
+class Signless : public Operator {
+public:
+  Signless(const SpMat& A, const Eigen::VectorXd& degrees) : Operator(A, degrees) {
+    c_ = spectralBound();
+    Eigen::VectorXd ones = Eigen::VectorXd::Ones(degrees.size());
+    M_ = identityPlus(scaled(ones, ones, -1.0 / c_));
+    for (long i = 0; i < degrees.size(); i++)
+      M_.coeffRef(i, i) -= degrees(i) / c_;
+    W_ = ones;
+  }
+  const char* name() const { return "signless"; }
+  Eigen::VectorXd firstVector() const {
+    long n = degrees_.size();
+    Eigen::VectorXd v(n);
+    for (long i = 0; i < n; i++)
+      v(i) = (i % 2 == 0) ? 1.0 : -1.0;
+    v.normalize();
+    for (int iter = 0; iter < 10000; iter++) {
+      Eigen::VectorXd next = M_ * v;
+      next.normalize();
+      double diff = (next - v).norm();
+      v = next;
+      if (diff < 1e-9)
+        break;
+    }
+    return v;
+  }
+  double eigenvalue(double mu) const { return c_ * (1.0 - mu); }
+
+private:
+  double c_;
+};
+
+// ---------------------------------------------------------------------
+// Build the operator selected by name from a weighted adjacency matrix.
+inline std::unique_ptr<Operator> make(const std::string& name, const SpMat& A, const Eigen::VectorXd& degrees) {
+  if (name == "combinatorial")
+    return std::unique_ptr<Operator>(new Combinatorial(A, degrees));
+  if (name == "symmetric")
+    return std::unique_ptr<Operator>(new SymmetricNormalized(A, degrees));
+  if (name == "random-walk")
+    return std::unique_ptr<Operator>(new Generalized(A, degrees, "random-walk"));
+  if (name == "signless")
+    return std::unique_ptr<Operator>(new Signless(A, degrees));
+  if (name != "generalized")
+    std::cout << "Unknown Laplacian " << name << ", using generalized" << std::endl;
+  return std::unique_ptr<Operator>(new Generalized(A, degrees));
+}
+
+} // namespace laplacian
+
+#endif
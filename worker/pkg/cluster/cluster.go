@@ -0,0 +1,307 @@
+// Package cluster partitions a graph using its spectral embedding: k-means
+// on the layout eigenvectors and bisection by the sign of the Fiedler vector.
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Unassigned marks vertex ids that are not part of the graph (no edges)
+const Unassigned = -1
+
+// Partition assigns every vertex a part in 0..K-1, or Unassigned
+type Partition struct {
+	Assign []int
+	K      int
+}
+
+// Metrics describes the quality of a partition
+type Metrics struct {
+	K         int     `json:"k"`
+	Sizes     []int   `json:"sizes"`
+	CutEdges  int     `json:"cut_edges"`
+	CutWeight float64 `json:"cut_weight"`
+	Balance   float64 `json:"balance"` // smallest part size divided by the largest, 1 when perfectly balanced
+	Inertia   float64 `json:"inertia,omitempty"`
+}
+
+// Active marks the vertices that have at least one edge
+func Active(g *graph.Graph, n int) []bool {
+	active := make([]bool, n)
+	for _, e := range g.Edges {
+		if e.U < n && e.V < n {
+			active[e.U], active[e.V] = true, true
+		}
+	}
+	return active
+}
+
+// KMeans clusters the active rows of e into k parts with k-means++ seeding and
+// Lloyd iterations. The seed makes the result reproducible; parts are numbered
+// in order of their lowest vertex id. Returns the partition and its inertia
+// (sum of squared distances to the cluster centers).
+func KMeans(e *embedding.Embedding, active []bool, k int, seed int64) (Partition, float64, error) {
+	var points []int
+	for v := range e.Coords {
+		if active[v] {
+			points = append(points, v)
+		}
+	}
+	if k < 1 {
+		return Partition{}, 0, fmt.Errorf("invalid number of clusters %d", k)
+	}
+	if k > len(points) {
+		return Partition{}, 0, fmt.Errorf("cannot split %d vertices into %d clusters", len(points), k)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	centers := seedCenters(e, points, k, rng)
+
+	assign := make([]int, e.Len())
+	for v := range assign {
+		assign[v] = Unassigned
+	}
+	var inertia float64
+	for iter := 0; iter < 300; iter++ {
+		changed := false
+		inertia = 0
+		for _, v := range points {
+			best, bestDist := 0, math.Inf(1)
+			for c, center := range centers {
+				if d := sqDist(e.Coords[v], center); d < bestDist {
+					best, bestDist = c, d
+				}
+			}
+			if assign[v] != best {
+				assign[v] = best
+				changed = true
+			}
+			inertia += bestDist
+		}
+		if !changed {
+			break
+		}
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, e.Dims)
+		}
+		for _, v := range points {
+			c := assign[v]
+			counts[c]++
+			for d, x := range e.Coords[v] {
+				sums[c][d] += x
+			}
+		}
+		// clusters emptied in the same pass restart at different points
+		restarted := make(map[int]bool)
+		for c := range centers {
+			if counts[c] == 0 {
+				// restart an empty cluster at the point farthest from its center
+				far := farthestPoint(e, points, assign, centers, restarted)
+				restarted[far] = true
+				centers[c] = append([]float64(nil), e.Coords[far]...)
+				continue
+			}
+			for d := range centers[c] {
+				centers[c][d] = sums[c][d] / float64(counts[c])
+			}
+		}
+	}
+	p := Partition{Assign: assign, K: k}
+	p.relabel()
+	return p, inertia, nil
+}
+
+// seedCenters picks k initial centers with k-means++
+func seedCenters(e *embedding.Embedding, points []int, k int, rng *rand.Rand) [][]float64 {
+	centers := [][]float64{append([]float64(nil), e.Coords[points[rng.Intn(len(points))]]...)}
+	dist := make([]float64, len(points))
+	for len(centers) < k {
+		total := 0.0
+		for i, v := range points {
+			d := math.Inf(1)
+			for _, center := range centers {
+				d = math.Min(d, sqDist(e.Coords[v], center))
+			}
+			dist[i] = d
+			total += d
+		}
+		next := points[len(centers)%len(points)]
+		if total > 0 {
+			r := rng.Float64() * total
+			for i, d := range dist {
+				r -= d
+				if r <= 0 {
+					next = points[i]
+					break
+				}
+			}
+		}
+		centers = append(centers, append([]float64(nil), e.Coords[next]...))
+	}
+	return centers
+}
+
+// farthestPoint returns the point farthest from the center of its cluster,
+// leaving out the points in skip
+func farthestPoint(e *embedding.Embedding, points []int, assign []int, centers [][]float64, skip map[int]bool) int {
+	far, farDist := points[0], -1.0
+	for _, v := range points {
+		if skip[v] {
+			continue
+		}
+		if d := sqDist(e.Coords[v], centers[assign[v]]); d > farDist {
+			far, farDist = v, d
+		}
+	}
+	return far
+}
+
+func sqDist(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		s += d * d
+	}
+	return s
+}
+
+// Bisect splits the active vertices by the sign of the Fiedler vector. If all
+// entries share a sign the split falls back to the median.
+func Bisect(fiedler []float64, active []bool) Partition {
+	assign := make([]int, len(fiedler))
+	var values []float64
+	for v, x := range fiedler {
+		if active[v] {
+			values = append(values, x)
+		}
+	}
+	threshold := 0.0
+	negative, positive := 0, 0
+	for _, x := range values {
+		if x < 0 {
+			negative++
+		} else {
+			positive++
+		}
+	}
+	if (negative == 0 || positive == 0) && len(values) > 1 {
+		sort.Float64s(values)
+		threshold = values[len(values)/2]
+	}
+	for v, x := range fiedler {
+		switch {
+		case !active[v]:
+			assign[v] = Unassigned
+		case x < threshold:
+			assign[v] = 0
+		default:
+			assign[v] = 1
+		}
+	}
+	p := Partition{Assign: assign, K: 2}
+	p.relabel()
+	return p
+}
+
+// relabel numbers the parts in order of their lowest vertex id, so equal
+// partitions get equal labels
+func (p *Partition) relabel() {
+	mapping := make(map[int]int)
+	for v, c := range p.Assign {
+		if c == Unassigned {
+			continue
+		}
+		if _, ok := mapping[c]; !ok {
+			mapping[c] = len(mapping)
+		}
+		p.Assign[v] = mapping[c]
+	}
+}
+
+// Evaluate computes part sizes, the edges and weight crossing parts, and the balance
+func Evaluate(g *graph.Graph, p Partition) Metrics {
+	m := Metrics{K: p.K, Sizes: make([]int, p.K)}
+	for _, c := range p.Assign {
+		if c != Unassigned {
+			m.Sizes[c]++
+		}
+	}
+	for _, e := range g.Edges {
+		if e.U >= len(p.Assign) || e.V >= len(p.Assign) {
+			continue
+		}
+		cu, cv := p.Assign[e.U], p.Assign[e.V]
+		if cu != cv && cu != Unassigned && cv != Unassigned {
+			m.CutEdges++
+			m.CutWeight += e.W
+		}
+	}
+	smallest, largest := math.MaxInt, 0
+	for _, size := range m.Sizes {
+		smallest, largest = min(smallest, size), max(largest, size)
+	}
+	if largest > 0 {
+		m.Balance = float64(smallest) / float64(largest)
+	}
+	return m
+}
+
+// Write stores the partition as lines "vertex part", skipping unassigned vertices
+func (p Partition) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	for v, c := range p.Assign {
+		if c != Unassigned {
+			fmt.Fprintf(bw, "%d %d\n", v, c)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read loads a partition written by Write for a graph with n vertex ids
+func Read(path string, n int) (Partition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Partition{}, err
+	}
+	defer f.Close()
+	p := Partition{Assign: make([]int, n)}
+	for v := range p.Assign {
+		p.Assign[v] = Unassigned
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err1 := strconv.Atoi(fields[0])
+		c, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || v < 0 || c < 0 {
+			return Partition{}, fmt.Errorf("invalid partition line %q", scanner.Text())
+		}
+		if v < n {
+			p.Assign[v] = c
+			p.K = max(p.K, c+1)
+		}
+	}
+	return p, scanner.Err()
+}
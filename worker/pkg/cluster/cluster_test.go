@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// blobs returns three tight groups of size vertices around distant centers,
+// vertex 0 left without edges as in 1-based Matrix Market files
+func blobs(size int) (*embedding.Embedding, []bool, []int) {
+	rng := rand.New(rand.NewSource(7))
+	centers := [][2]float64{{0, 0}, {50, 0}, {0, 50}}
+	n := 1 + 3*size
+	e := embedding.New(n, 2)
+	active := make([]bool, n)
+	truth := make([]int, n)
+	truth[0] = Unassigned
+	// the groups are interleaved so relabeling has work to do
+	for v := 1; v < n; v++ {
+		c := (v - 1) % 3
+		e.Coords[v][0] = centers[c][0] + rng.NormFloat64()
+		e.Coords[v][1] = centers[c][1] + rng.NormFloat64()
+		active[v] = true
+		truth[v] = c
+	}
+	return e, active, truth
+}
+
+func TestKMeansBlobs(t *testing.T) {
+	e, active, truth := blobs(20)
+	for seed := int64(1); seed <= 5; seed++ {
+		p, inertia, err := KMeans(e, active, 3, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// parts are numbered by their lowest vertex, which here is the true group
+		if !reflect.DeepEqual(p.Assign, truth) {
+			t.Fatalf("seed %d: assignment %v, want %v", seed, p.Assign, truth)
+		}
+		if inertia <= 0 || inertia > 3*60 {
+			t.Errorf("seed %d: inertia %g out of range", seed, inertia)
+		}
+	}
+}
+
+func TestKMeansDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	e := embedding.New(200, 3)
+	active := make([]bool, 200)
+	for v, row := range e.Coords {
+		for d := range row {
+			row[d] = rng.Float64()
+		}
+		active[v] = v > 0
+	}
+	a, inertiaA, err := KMeans(e, active, 6, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, inertiaB, _ := KMeans(e, active, 6, 42)
+	if !reflect.DeepEqual(a, b) || inertiaA != inertiaB {
+		t.Error("equal seeds give different partitions")
+	}
+	m := Evaluate(&graph.Graph{}, a)
+	for c, size := range m.Sizes {
+		if size == 0 {
+			t.Errorf("part %d is empty: %v", c, m.Sizes)
+		}
+	}
+	if a.Assign[0] != Unassigned || a.Assign[1] != 0 {
+		t.Errorf("vertex 0 in part %d and vertex 1 in part %d, want unassigned and 0", a.Assign[0], a.Assign[1])
+	}
+}
+
+func TestKMeansInvalid(t *testing.T) {
+	e, active, _ := blobs(2)
+	if _, _, err := KMeans(e, active, 0, 1); err == nil {
+		t.Error("k = 0: expected an error")
+	}
+	if _, _, err := KMeans(e, active, 7, 1); err == nil {
+		t.Error("more clusters than vertices: expected an error")
+	}
+}
+
+func TestFarthestPointSkipsRestarts(t *testing.T) {
+	e := &embedding.Embedding{Coords: [][]float64{{0}, {1}, {9}, {10}}, Dims: 1}
+	points := []int{0, 1, 2, 3}
+	assign := []int{0, 0, 0, 0}
+	centers := [][]float64{{0}, {0}, {0}}
+	skip := make(map[int]bool)
+	var got []int
+	for i := 0; i < 3; i++ {
+		far := farthestPoint(e, points, assign, centers, skip)
+		skip[far] = true
+		got = append(got, far)
+	}
+	if want := []int{3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("restart points %v, want %v", got, want)
+	}
+}
+
+func TestBisect(t *testing.T) {
+	active := []bool{false, true, true, true, true, true}
+	tests := []struct {
+		name    string
+		fiedler []float64
+		want    []int
+	}{
+		{"by sign", []float64{0, -0.5, 0.2, -0.1, 0.4, 0.3}, []int{Unassigned, 0, 1, 0, 1, 1}},
+		// the active entries are all positive, so the median splits them
+		{"median fallback", []float64{-9, 0.5, 0.1, 0.3, 0.2, 0.4}, []int{Unassigned, 0, 1, 0, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Bisect(tt.fiedler, active)
+			if p.K != 2 || !reflect.DeepEqual(p.Assign, tt.want) {
+				t.Errorf("assignment %v, want %v", p.Assign, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	g := &graph.Graph{N: 5, Edges: []graph.Edge{{U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 2.5}, {U: 3, V: 4, W: 1}, {U: 0, V: 1, W: 7}}}
+	p := Partition{Assign: []int{Unassigned, 0, 0, 1, 1}, K: 2}
+	m := Evaluate(g, p)
+	if !reflect.DeepEqual(m.Sizes, []int{2, 2}) || m.CutEdges != 1 || m.CutWeight != 2.5 || m.Balance != 1 {
+		t.Errorf("metrics %+v", m)
+	}
+}
@@ -0,0 +1,138 @@
+package embedding
+
+import (
+	"fmt"
+	"math"
+)
+
+// Alignment describes the similarity transform x -> Scale * (x - from) * Rotation + to
+// that maps one layout onto another, and how well the shared vertices agree afterwards
+type Alignment struct {
+	SharedVertices int         `json:"shared_vertices"`
+	Scale          float64     `json:"scale"`
+	Rotation       [][]float64 `json:"rotation"`
+	Reflection     bool        `json:"reflection"`
+	From           []float64   `json:"from"`
+	To             []float64   `json:"to"`
+	// Residual is the distance left between the shared vertices relative to the
+	// spread of the base layout: 0 for identical drawings up to rotation,
+	// reflection and scale, 1 when nothing of the base layout is explained
+	Residual float64 `json:"residual"`
+	RMSD     float64 `json:"rmsd"`
+}
+
+// Procrustes aligns e to base over the shared vertex ids with orthogonal
+// Procrustes: the rotation (possibly a reflection) minimizing the squared
+// distances between corresponding vertices, after centering both layouts on the
+// shared vertices. When scaling is set the optimal uniform scale is applied too.
+// The whole of e is transformed, including vertices missing from base.
+func Procrustes(base, e *Embedding, shared []int, scaling bool) (*Embedding, Alignment, error) {
+	if base.Dims != e.Dims {
+		return nil, Alignment{}, fmt.Errorf("cannot align a %dD layout to a %dD layout", e.Dims, base.Dims)
+	}
+	if len(shared) < 2 {
+		return nil, Alignment{}, fmt.Errorf("layouts share %d vertices, at least 2 are needed", len(shared))
+	}
+	dims := e.Dims
+	for _, v := range shared {
+		if v < 0 || v >= base.Len() || v >= e.Len() {
+			return nil, Alignment{}, fmt.Errorf("shared vertex %d is missing from a layout", v)
+		}
+	}
+	al := Alignment{SharedVertices: len(shared), From: make([]float64, dims), To: make([]float64, dims), Scale: 1}
+	for _, v := range shared {
+		for d := 0; d < dims; d++ {
+			al.To[d] += base.Coords[v][d] / float64(len(shared))
+			al.From[d] += e.Coords[v][d] / float64(len(shared))
+		}
+	}
+	// cross covariance of the centered layouts, e^T * base
+	cov := make([][]float64, dims)
+	for i := range cov {
+		cov[i] = make([]float64, dims)
+	}
+	spread, baseSpread := 0.0, 0.0
+	for _, v := range shared {
+		for i := 0; i < dims; i++ {
+			x := e.Coords[v][i] - al.From[i]
+			spread += x * x
+			baseSpread += (base.Coords[v][i] - al.To[i]) * (base.Coords[v][i] - al.To[i])
+			for j := 0; j < dims; j++ {
+				cov[i][j] += x * (base.Coords[v][j] - al.To[j])
+			}
+		}
+	}
+	u, s, v := svd(cov)
+	al.Rotation = make([][]float64, dims)
+	for i := range al.Rotation {
+		al.Rotation[i] = make([]float64, dims)
+		for j := range al.Rotation[i] {
+			for k := 0; k < dims; k++ {
+				al.Rotation[i][j] += u[i][k] * v[j][k]
+			}
+		}
+	}
+	al.Reflection = determinant(al.Rotation) < 0
+	if scaling && spread > 0 {
+		trace := 0.0
+		for _, x := range s {
+			trace += x
+		}
+		al.Scale = trace / spread
+	}
+
+	aligned := New(e.Len(), dims)
+	for i, row := range e.Coords {
+		for j := 0; j < dims; j++ {
+			x := al.To[j]
+			for k := 0; k < dims; k++ {
+				x += al.Scale * (row[k] - al.From[k]) * al.Rotation[k][j]
+			}
+			aligned.Coords[i][j] = x
+		}
+	}
+	sq := 0.0
+	for _, v := range shared {
+		for d := 0; d < dims; d++ {
+			diff := aligned.Coords[v][d] - base.Coords[v][d]
+			sq += diff * diff
+		}
+	}
+	al.RMSD = math.Sqrt(sq / float64(len(shared)))
+	if baseSpread > 0 {
+		al.Residual = math.Sqrt(sq / baseSpread)
+	}
+	return aligned, al, nil
+}
+
+// determinant by Gaussian elimination with partial pivoting
+func determinant(m [][]float64) float64 {
+	a := make([][]float64, len(m))
+	for i := range m {
+		a[i] = append([]float64(nil), m[i]...)
+	}
+	det := 1.0
+	for c := range a {
+		p := c
+		for r := c + 1; r < len(a); r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if a[p][c] == 0 {
+			return 0
+		}
+		if p != c {
+			a[p], a[c] = a[c], a[p]
+			det = -det
+		}
+		det *= a[c][c]
+		for r := c + 1; r < len(a); r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < len(a); k++ {
+				a[r][k] -= f * a[c][k]
+			}
+		}
+	}
+	return det
+}
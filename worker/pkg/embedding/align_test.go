@@ -0,0 +1,164 @@
+package embedding
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomLayout returns n vertices at random positions in dims dimensions
+func randomLayout(n, dims int, seed int64) *Embedding {
+	rng := rand.New(rand.NewSource(seed))
+	e := New(n, dims)
+	for _, row := range e.Coords {
+		for d := range row {
+			row[d] = rng.NormFloat64()
+		}
+	}
+	return e
+}
+
+// transform returns scale * x * q + shift for every row x of e
+func transform(e *Embedding, q [][]float64, scale float64, shift []float64) *Embedding {
+	out := New(e.Len(), e.Dims)
+	for i, row := range e.Coords {
+		for j := 0; j < e.Dims; j++ {
+			out.Coords[i][j] = shift[j]
+			for k := 0; k < e.Dims; k++ {
+				out.Coords[i][j] += scale * row[k] * q[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func rotation2D(deg float64) [][]float64 {
+	a := deg * math.Pi / 180
+	return [][]float64{{math.Cos(a), math.Sin(a)}, {-math.Sin(a), math.Cos(a)}}
+}
+
+func allVertices(n int) []int {
+	shared := make([]int, n)
+	for i := range shared {
+		shared[i] = i
+	}
+	return shared
+}
+
+func TestProcrustes(t *testing.T) {
+	// a rotation about the z axis followed by a swap of x and y, which reflects
+	c, s := math.Cos(0.7), math.Sin(0.7)
+	reflection3D := [][]float64{{s, c, 0}, {c, -s, 0}, {0, 0, 1}}
+	tests := []struct {
+		name       string
+		dims       int
+		q          [][]float64
+		scale      float64
+		scaling    bool
+		reflection bool
+	}{
+		{"2D rotation", 2, rotation2D(30), 1, false, false},
+		{"2D rotation and scale", 2, rotation2D(-115), 2.5, true, false},
+		{"2D reflection", 2, [][]float64{{1, 0}, {0, -1}}, 1, false, true},
+		{"3D reflection and scale", 3, reflection3D, 0.4, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shift := make([]float64, tt.dims)
+			for d := range shift {
+				shift[d] = float64(d+1) * 3
+			}
+			e := randomLayout(40, tt.dims, 1)
+			base := transform(e, tt.q, tt.scale, shift)
+			aligned, al, err := Procrustes(base, e, allVertices(e.Len()), tt.scaling)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if al.Reflection != tt.reflection {
+				t.Errorf("reflection = %v, want %v", al.Reflection, tt.reflection)
+			}
+			if math.Abs(al.Scale-tt.scale) > 1e-9 {
+				t.Errorf("scale = %g, want %g", al.Scale, tt.scale)
+			}
+			for i := range tt.q {
+				for j := range tt.q[i] {
+					if math.Abs(al.Rotation[i][j]-tt.q[i][j]) > 1e-9 {
+						t.Fatalf("rotation = %v, want %v", al.Rotation, tt.q)
+					}
+				}
+			}
+			if al.Residual > 1e-9 || al.RMSD > 1e-9 {
+				t.Errorf("residual %g, rmsd %g, want 0", al.Residual, al.RMSD)
+			}
+			for v, row := range aligned.Coords {
+				for d, x := range row {
+					if math.Abs(x-base.Coords[v][d]) > 1e-9 {
+						t.Fatalf("vertex %d aligned to %v, want %v", v, row, base.Coords[v])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProcrustesPartialOverlap(t *testing.T) {
+	e := randomLayout(30, 2, 2)
+	base := transform(e, rotation2D(75), 1, []float64{-1, 4})
+	// vertices outside the shared set are moved along with the others
+	aligned, al, err := Procrustes(base, e, []int{0, 3, 5, 8, 13}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if al.SharedVertices != 5 || al.Residual > 1e-9 {
+		t.Errorf("shared %d, residual %g", al.SharedVertices, al.Residual)
+	}
+	if d := math.Hypot(aligned.Coords[29][0]-base.Coords[29][0], aligned.Coords[29][1]-base.Coords[29][1]); d > 1e-9 {
+		t.Errorf("unshared vertex is %g away from its place", d)
+	}
+}
+
+func TestProcrustesDegenerate(t *testing.T) {
+	e := randomLayout(10, 2, 3)
+	if _, _, err := Procrustes(e, e, []int{4}, false); err == nil {
+		t.Error("one shared vertex: expected an error")
+	}
+	if _, _, err := Procrustes(e, e, []int{1, 12}, false); err == nil {
+		t.Error("shared vertex out of range: expected an error")
+	}
+	if _, _, err := Procrustes(randomLayout(10, 3, 3), e, allVertices(10), false); err == nil {
+		t.Error("different dimensions: expected an error")
+	}
+
+	// collinear vertices fix the rotation only up to a reflection across their
+	// line; the result must still be orthogonal and map the line onto the base
+	line := New(6, 2)
+	for v := range line.Coords {
+		line.Coords[v][0] = float64(v)
+	}
+	base := transform(line, rotation2D(40), 2, []float64{1, 1})
+	aligned, al, err := Procrustes(base, line, allVertices(6), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := al.Rotation
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			dot := r[i][0]*r[j][0] + r[i][1]*r[j][1]
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if math.IsNaN(dot) || math.Abs(dot-want) > 1e-9 {
+				t.Fatalf("rotation %v is not orthogonal", r)
+			}
+		}
+	}
+	if math.Abs(al.Scale-2) > 1e-9 || al.Residual > 1e-9 {
+		t.Errorf("scale %g, residual %g, want 2 and 0", al.Scale, al.Residual)
+	}
+	for v, row := range aligned.Coords {
+		if math.Hypot(row[0]-base.Coords[v][0], row[1]-base.Coords[v][1]) > 1e-9 {
+			t.Fatalf("vertex %d aligned to %v, want %v", v, row, base.Coords[v])
+		}
+	}
+}
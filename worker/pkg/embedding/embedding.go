@@ -0,0 +1,121 @@
+// Package embedding reads, writes and transforms vertex coordinate files.
+//
+// An embedding file holds one vertex per line with whitespace separated
+// coordinates; line i belongs to vertex i of graph.txt. The solver writes the
+// k layout eigenvectors this way (eigenvectors.txt, column c holding
+// eigenvector c+2), and the drawing tools read 2D or 3D files of the same form.
+package embedding
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Embedding holds the coordinates of every vertex, all rows having Dims entries
+type Embedding struct {
+	Coords [][]float64
+	Dims   int
+}
+
+// New returns an embedding of n vertices in dims dimensions placed at the origin
+func New(n, dims int) *Embedding {
+	coords := make([][]float64, n)
+	for i := range coords {
+		coords[i] = make([]float64, dims)
+	}
+	return &Embedding{Coords: coords, Dims: dims}
+}
+
+// Len returns the number of vertices
+func (e *Embedding) Len() int {
+	return len(e.Coords)
+}
+
+// Column returns a copy of coordinate c of all vertices
+func (e *Embedding) Column(c int) []float64 {
+	col := make([]float64, len(e.Coords))
+	for i, row := range e.Coords {
+		col[i] = row[c]
+	}
+	return col
+}
+
+// Read parses an embedding file
+func Read(path string) (*Embedding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads an embedding from r, skipping empty lines
+func Parse(r io.Reader) (*Embedding, error) {
+	e := &Embedding{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if e.Dims == 0 {
+			e.Dims = len(fields)
+		} else if len(fields) != e.Dims {
+			return nil, fmt.Errorf("line %d: expected %d coordinates, got %d", line, e.Dims, len(fields))
+		}
+		row := make([]float64, len(fields))
+		for c, field := range fields {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			row[c] = v
+		}
+		e.Coords = append(e.Coords, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(e.Coords) == 0 {
+		return nil, fmt.Errorf("embedding is empty")
+	}
+	return e, nil
+}
+
+// Write stores the embedding in path
+func (e *Embedding) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := e.Format(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Format writes the embedding to w in the file format read by Parse
+func (e *Embedding) Format(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, 32)
+	for _, row := range e.Coords {
+		for c, v := range row {
+			if c > 0 {
+				_ = bw.WriteByte(' ')
+			}
+			buf = strconv.AppendFloat(buf[:0], v, 'g', 12, 64)
+			_, _ = bw.Write(buf)
+		}
+		_ = bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,200 @@
+package embedding
+
+import (
+	"math"
+	"sort"
+)
+
+// symmetricEigen diagonalizes a small dense symmetric matrix with cyclic Jacobi
+// rotations. Eigenvalues are returned in decreasing order; vectors[j] is the
+// unit eigenvector of values[j].
+func symmetricEigen(a [][]float64) (values []float64, vectors [][]float64) {
+	n := len(a)
+	m := make([][]float64, n)
+	v := make([][]float64, n)
+	for i := range m {
+		m[i] = append([]float64(nil), a[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += m[p][q] * m[p][q]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(m[p][q]) < 1e-300 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool { return m[order[x]][order[x]] > m[order[y]][order[y]] })
+	values = make([]float64, n)
+	vectors = make([][]float64, n)
+	for j, idx := range order {
+		values[j] = m[idx][idx]
+		vectors[j] = make([]float64, n)
+		for k := 0; k < n; k++ {
+			vectors[j][k] = v[k][idx]
+		}
+	}
+	return values, vectors
+}
+
+// canonicalSign flips x so that its third moment is positive, falling back to
+// making the entry of largest magnitude positive, the same rule the solver
+// applies to eigenvectors.
+func canonicalSign(x []float64) {
+	skew, scale := 0.0, 0.0
+	maxIdx := 0
+	for i, v := range x {
+		skew += v * v * v
+		scale += v * v
+		if math.Abs(v) > math.Abs(x[maxIdx]) {
+			maxIdx = i
+		}
+	}
+	var flip bool
+	if math.Abs(skew) > 1e-9*scale*math.Sqrt(scale) {
+		flip = skew < 0
+	} else {
+		flip = len(x) > 0 && x[maxIdx] < 0
+	}
+	if flip {
+		for i := range x {
+			x[i] = -x[i]
+		}
+	}
+}
+
+// svd computes the singular value decomposition a = u * diag(s) * v^T of a small
+// square matrix with one-sided Jacobi rotations. u and v are orthogonal even when
+// a is singular: columns of u for zero singular values complete the basis.
+func svd(a [][]float64) (u [][]float64, s []float64, v [][]float64) {
+	n := len(a)
+	w := make([][]float64, n)
+	v = make([][]float64, n)
+	for i := range w {
+		w[i] = append([]float64(nil), a[i]...)
+		v[i] = make([]float64, n)
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		rotated := false
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				alpha, beta, gamma := 0.0, 0.0, 0.0
+				for k := 0; k < n; k++ {
+					alpha += w[k][p] * w[k][p]
+					beta += w[k][q] * w[k][q]
+					gamma += w[k][p] * w[k][q]
+				}
+				if math.Abs(gamma) <= 1e-15*math.Sqrt(alpha*beta) || gamma == 0 {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := 1 / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				if zeta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				for k := 0; k < n; k++ {
+					wkp, wkq := w[k][p], w[k][q]
+					w[k][p] = c*wkp - sn*wkq
+					w[k][q] = sn*wkp + c*wkq
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - sn*vkq
+					v[k][q] = sn*vkp + c*vkq
+				}
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+	s = make([]float64, n)
+	u = make([][]float64, n)
+	for i := range u {
+		u[i] = make([]float64, n)
+	}
+	var zero []int
+	for j := 0; j < n; j++ {
+		norm := 0.0
+		for k := 0; k < n; k++ {
+			norm += w[k][j] * w[k][j]
+		}
+		s[j] = math.Sqrt(norm)
+		if s[j] <= 1e-300 {
+			zero = append(zero, j)
+			continue
+		}
+		for k := 0; k < n; k++ {
+			u[k][j] = w[k][j] / s[j]
+		}
+	}
+	// Complete u with unit vectors orthogonalized against the columns found so far.
+	for _, j := range zero {
+		for e := 0; e < n; e++ {
+			col := make([]float64, n)
+			col[e] = 1
+			for c := 0; c < n; c++ {
+				if c == j || (s[c] <= 1e-300 && c > j) {
+					continue
+				}
+				dot := 0.0
+				for k := 0; k < n; k++ {
+					dot += col[k] * u[k][c]
+				}
+				for k := 0; k < n; k++ {
+					col[k] -= dot * u[k][c]
+				}
+			}
+			norm := 0.0
+			for _, x := range col {
+				norm += x * x
+			}
+			if norm > 1e-6 {
+				for k := 0; k < n; k++ {
+					u[k][j] = col[k] / math.Sqrt(norm)
+				}
+				break
+			}
+		}
+	}
+	return u, s, v
+}
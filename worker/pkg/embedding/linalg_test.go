@@ -0,0 +1,63 @@
+package embedding
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSymmetricEigen(t *testing.T) {
+	a := [][]float64{{4, 1, 0}, {1, 3, 1}, {0, 1, 2}}
+	values, vectors := symmetricEigen(a)
+	for j := 1; j < len(values); j++ {
+		if values[j] > values[j-1] {
+			t.Errorf("eigenvalues %v are not decreasing", values)
+		}
+	}
+	for j, x := range vectors {
+		for i := range a {
+			ax := 0.0
+			for k := range a {
+				ax += a[i][k] * x[k]
+			}
+			if math.Abs(ax-values[j]*x[i]) > 1e-9 {
+				t.Fatalf("vector %d is not an eigenvector of %g", j, values[j])
+			}
+		}
+	}
+	// the trace is the sum of the eigenvalues
+	if sum := values[0] + values[1] + values[2]; math.Abs(sum-9) > 1e-9 {
+		t.Errorf("eigenvalues sum to %g, want 9", sum)
+	}
+}
+
+func TestSVD(t *testing.T) {
+	for name, a := range map[string][][]float64{
+		"full rank": {{2, -1, 0.5}, {0.3, 1, 4}, {-2, 0, 1}},
+		"rank one":  {{1, 2, 3}, {2, 4, 6}, {-1, -2, -3}},
+		"zero":      {{0, 0}, {0, 0}},
+	} {
+		u, s, v := svd(a)
+		n := len(a)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				// a = u diag(s) v^T, and u and v are orthogonal
+				x, uu, vv := 0.0, 0.0, 0.0
+				for k := 0; k < n; k++ {
+					x += u[i][k] * s[k] * v[j][k]
+					uu += u[k][i] * u[k][j]
+					vv += v[k][i] * v[k][j]
+				}
+				want := 0.0
+				if i == j {
+					want = 1
+				}
+				if math.Abs(x-a[i][j]) > 1e-9 {
+					t.Errorf("%s: u s v^T [%d][%d] = %g, want %g", name, i, j, x, a[i][j])
+				}
+				if math.Abs(uu-want) > 1e-9 || math.Abs(vv-want) > 1e-9 {
+					t.Errorf("%s: u or v is not orthogonal at [%d][%d]", name, i, j)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package embedding
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Projection modes
+const (
+	ModeFirst = "first" // leading layout eigenvectors
+	ModePCA   = "pca"   // principal components of the k-dimensional embedding
+	ModeAxes  = "axes"  // eigenvectors chosen by number
+)
+
+// Projection selects how a k-dimensional spectral embedding is mapped to 2D or 3D.
+// Axes holds eigenvector numbers as in the spectral report: 2 is the Fiedler
+// vector, 3 the next one and so on, so column c of the embedding is eigenvector c+2.
+type Projection struct {
+	Mode string `json:"mode"`
+	Axes []int  `json:"axes,omitempty"`
+}
+
+// Name returns a short identifier of the projection usable in file names
+func (p Projection) Name() string {
+	name := p.Mode
+	if name == "" {
+		name = ModeFirst
+	}
+	if p.Mode == ModeAxes {
+		for _, a := range p.Axes {
+			name += fmt.Sprintf("-%d", a)
+		}
+	}
+	return name
+}
+
+// Project maps e to dims dimensions. The result has fewer columns than dims when
+// the embedding itself has fewer.
+func Project(e *Embedding, dims int, p Projection) (*Embedding, error) {
+	if dims < 1 {
+		return nil, fmt.Errorf("invalid projection dimension %d", dims)
+	}
+	if dims > e.Dims {
+		dims = e.Dims
+	}
+	switch p.Mode {
+	case "", ModeFirst:
+		cols := make([]int, dims)
+		for c := range cols {
+			cols[c] = c
+		}
+		return selectColumns(e, cols), nil
+	case ModeAxes:
+		cols, err := axesColumns(p.Axes, e.Dims, dims)
+		if err != nil {
+			return nil, err
+		}
+		return selectColumns(e, cols), nil
+	case ModePCA:
+		return principalComponents(e, dims), nil
+	default:
+		return nil, fmt.Errorf("unknown projection mode %q", p.Mode)
+	}
+}
+
+// axesColumns turns eigenvector numbers into embedding columns. When fewer axes
+// than dims are given, the lowest remaining eigenvectors fill the rest.
+func axesColumns(axes []int, k, dims int) ([]int, error) {
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("projection mode %q needs axes", ModeAxes)
+	}
+	used := make(map[int]bool)
+	var cols []int
+	for _, a := range axes {
+		c := a - 2
+		if c < 0 || c >= k {
+			return nil, fmt.Errorf("eigenvector %d is not in the embedding (2..%d)", a, k+1)
+		}
+		if used[c] {
+			return nil, fmt.Errorf("eigenvector %d is selected twice", a)
+		}
+		used[c] = true
+		if len(cols) < dims {
+			cols = append(cols, c)
+		}
+	}
+	for c := 0; len(cols) < dims && c < k; c++ {
+		if !used[c] {
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
+func selectColumns(e *Embedding, cols []int) *Embedding {
+	out := New(e.Len(), len(cols))
+	for i, row := range e.Coords {
+		for j, c := range cols {
+			out.Coords[i][j] = row[c]
+		}
+	}
+	return out
+}
+
+// principalComponents projects the centered embedding onto the dims directions of
+// largest variance. Component signs are fixed so the result is deterministic.
+func principalComponents(e *Embedding, dims int) *Embedding {
+	n, k := e.Len(), e.Dims
+	mean := make([]float64, k)
+	for _, row := range e.Coords {
+		for c, v := range row {
+			mean[c] += v
+		}
+	}
+	for c := range mean {
+		mean[c] /= float64(n)
+	}
+	cov := make([][]float64, k)
+	for a := range cov {
+		cov[a] = make([]float64, k)
+	}
+	for _, row := range e.Coords {
+		for a := 0; a < k; a++ {
+			da := row[a] - mean[a]
+			for b := a; b < k; b++ {
+				cov[a][b] += da * (row[b] - mean[b])
+			}
+		}
+	}
+	for a := 0; a < k; a++ {
+		for b := a; b < k; b++ {
+			cov[a][b] /= float64(n)
+			cov[b][a] = cov[a][b]
+		}
+	}
+	_, vectors := symmetricEigen(cov)
+
+	out := New(n, dims)
+	for j := 0; j < dims; j++ {
+		col := make([]float64, n)
+		for i, row := range e.Coords {
+			s := 0.0
+			for c := 0; c < k; c++ {
+				s += (row[c] - mean[c]) * vectors[j][c]
+			}
+			col[i] = s
+		}
+		canonicalSign(col)
+		for i := range col {
+			out.Coords[i][j] = col[i]
+		}
+	}
+	return out
+}
+
+// ParseAxes reads a comma separated list of eigenvector numbers such as "2,4"
+func ParseAxes(s string) ([]int, error) {
+	var axes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid eigenvector number %q", part)
+		}
+		axes = append(axes, a)
+	}
+	return axes, nil
+}
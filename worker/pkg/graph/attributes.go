@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadAttributes loads per-vertex values from lines "vertex value" for a graph
+// with n vertex ids. Vertices without a line get NaN; ids outside the graph
+// are ignored.
+func ReadAttributes(path string, n int) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make([]float64, n)
+	for v := range values {
+		values[v] = math.NaN()
+	}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.Atoi(fields[0])
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("line %d: invalid vertex %q", line, fields[0])
+		}
+		x, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("line %d: invalid value %q", line, fields[1])
+		}
+		if v < n {
+			values[v] = x
+		}
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,236 @@
+package graph
+
+import "sort"
+
+// Delta describes how a graph changed from a base version, with vertices
+// matched by id. Vertices count when they have at least one edge.
+type Delta struct {
+	Vertices        Change         `json:"vertices"`
+	Edges           Change         `json:"edges"`
+	ReweightedEdges int            `json:"reweighted_edges"`
+	AddedVertices   []int          `json:"added_vertices"`
+	RemovedVertices []int          `json:"removed_vertices"`
+	AddedEdges      [][2]int       `json:"added_edges"`
+	RemovedEdges    [][2]int       `json:"removed_edges"`
+	DegreeChanges   []DegreeChange `json:"degree_changes"` // largest changes first
+	DegreesChanged  int            `json:"degrees_changed"`
+	Components      ComponentDelta `json:"components"`
+	Truncated       bool           `json:"truncated"` // some lists were cut at the limit
+}
+
+// Change counts the elements of both versions and those only in one of them
+type Change struct {
+	Base    int `json:"base"`
+	Other   int `json:"other"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// DegreeChange is the degree of a vertex before and after
+type DegreeChange struct {
+	Vertex int `json:"vertex"`
+	Before int `json:"before"`
+	After  int `json:"after"`
+}
+
+// ComponentDelta compares the connected components of both versions. A base
+// component is split when its remaining vertices lie in several new components,
+// and a new component is merged when it holds vertices of several base ones.
+type ComponentDelta struct {
+	Base     int `json:"base"`
+	Other    int `json:"other"`
+	Split    int `json:"split"`
+	Merged   int `json:"merged"`
+	Appeared int `json:"appeared"` // new components without any vertex of the base version
+	Vanished int `json:"vanished"` // base components without any vertex left
+}
+
+// EdgeSet returns the undirected edges as (smaller id, larger id) with their
+// weights; self loops are skipped and repeated edges add up
+func (g *Graph) EdgeSet() map[[2]int]float64 {
+	set := make(map[[2]int]float64, len(g.Edges))
+	for _, e := range g.Edges {
+		if e.U == e.V {
+			continue
+		}
+		set[edgeKey(e.U, e.V)] += e.W
+	}
+	return set
+}
+
+func edgeKey(u, v int) [2]int {
+	if u > v {
+		u, v = v, u
+	}
+	return [2]int{u, v}
+}
+
+// Components labels the vertices with edges by connected component, numbered
+// in order of their lowest vertex id; vertices without edges get -1. It also
+// returns the number of components.
+func (g *Graph) Components() ([]int, int) {
+	adj := g.Adjacency()
+	label := make([]int, g.N)
+	for v := range label {
+		label[v] = -1
+	}
+	count := 0
+	for s := range adj {
+		if label[s] >= 0 || len(adj[s]) == 0 {
+			continue
+		}
+		label[s] = count
+		stack := []int{s}
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, v := range adj[u] {
+				if label[v] < 0 {
+					label[v] = count
+					stack = append(stack, v)
+				}
+			}
+		}
+		count++
+	}
+	return label, count
+}
+
+// Diff compares g to the base version of the graph. The vertex, edge and
+// degree lists hold at most limit entries each.
+func Diff(base, g *Graph, limit int) Delta {
+	d := Delta{
+		AddedVertices:   []int{},
+		RemovedVertices: []int{},
+		AddedEdges:      [][2]int{},
+		RemovedEdges:    [][2]int{},
+		DegreeChanges:   []DegreeChange{},
+	}
+	baseEdges, edges := base.EdgeSet(), g.EdgeSet()
+	d.Edges.Base, d.Edges.Other = len(baseEdges), len(edges)
+	for key, w := range edges {
+		if bw, ok := baseEdges[key]; !ok {
+			d.Edges.Added++
+			d.AddedEdges = append(d.AddedEdges, key)
+		} else if bw != w {
+			d.ReweightedEdges++
+		}
+	}
+	for key := range baseEdges {
+		if _, ok := edges[key]; !ok {
+			d.Edges.Removed++
+			d.RemovedEdges = append(d.RemovedEdges, key)
+		}
+	}
+	SortEdges(d.AddedEdges)
+	SortEdges(d.RemovedEdges)
+
+	n := max(base.N, g.N)
+	before, after := degrees(baseEdges, n), degrees(edges, n)
+	for v := 0; v < n; v++ {
+		if before[v] > 0 {
+			d.Vertices.Base++
+		}
+		if after[v] > 0 {
+			d.Vertices.Other++
+		}
+		switch {
+		case before[v] == 0 && after[v] > 0:
+			d.Vertices.Added++
+			d.AddedVertices = append(d.AddedVertices, v)
+		case before[v] > 0 && after[v] == 0:
+			d.Vertices.Removed++
+			d.RemovedVertices = append(d.RemovedVertices, v)
+		}
+		if before[v] != after[v] {
+			d.DegreesChanged++
+			d.DegreeChanges = append(d.DegreeChanges, DegreeChange{Vertex: v, Before: before[v], After: after[v]})
+		}
+	}
+	sort.SliceStable(d.DegreeChanges, func(i, j int) bool {
+		return abs(d.DegreeChanges[i].After-d.DegreeChanges[i].Before) > abs(d.DegreeChanges[j].After-d.DegreeChanges[j].Before)
+	})
+	d.Components = compareComponents(base, g)
+
+	if limit >= 0 {
+		d.AddedVertices = truncate(d.AddedVertices, limit, &d.Truncated)
+		d.RemovedVertices = truncate(d.RemovedVertices, limit, &d.Truncated)
+		d.AddedEdges = truncate(d.AddedEdges, limit, &d.Truncated)
+		d.RemovedEdges = truncate(d.RemovedEdges, limit, &d.Truncated)
+		d.DegreeChanges = truncate(d.DegreeChanges, limit, &d.Truncated)
+	}
+	return d
+}
+
+func compareComponents(base, g *Graph) ComponentDelta {
+	baseLabel, baseCount := base.Components()
+	label, count := g.Components()
+	cd := ComponentDelta{Base: baseCount, Other: count}
+	// the distinct components on the other side of every component
+	into := make([]map[int]bool, baseCount)
+	from := make([]map[int]bool, count)
+	for v := 0; v < len(baseLabel) && v < len(label); v++ {
+		b, c := baseLabel[v], label[v]
+		if b < 0 || c < 0 {
+			continue
+		}
+		if into[b] == nil {
+			into[b] = make(map[int]bool)
+		}
+		if from[c] == nil {
+			from[c] = make(map[int]bool)
+		}
+		into[b][c], from[c][b] = true, true
+	}
+	for _, m := range into {
+		switch {
+		case len(m) == 0:
+			cd.Vanished++
+		case len(m) > 1:
+			cd.Split++
+		}
+	}
+	for _, m := range from {
+		switch {
+		case len(m) == 0:
+			cd.Appeared++
+		case len(m) > 1:
+			cd.Merged++
+		}
+	}
+	return cd
+}
+
+func degrees(edges map[[2]int]float64, n int) []int {
+	deg := make([]int, n)
+	for key := range edges {
+		deg[key[0]]++
+		deg[key[1]]++
+	}
+	return deg
+}
+
+// SortEdges orders edge keys by their first and then their second vertex
+func SortEdges(edges [][2]int) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+}
+
+func truncate[T any](list []T, limit int, truncated *bool) []T {
+	if len(list) > limit {
+		*truncated = true
+		return list[:limit]
+	}
+	return list
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+)
+
+// versions returns two versions of a graph. From base to other the path
+// 1-2-3-4 loses 2-3 and splits, 5-6 and 7-8 merge through 6-7, 9-10 vanishes,
+// 11-12-13 appears with 11 gaining two edges, and 1-2 changes its weight.
+func versions() (base, other *Graph) {
+	base = &Graph{N: 11, Edges: []Edge{
+		{U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 1}, {U: 3, V: 4, W: 1},
+		{U: 5, V: 6, W: 1}, {U: 8, V: 7, W: 1}, {U: 9, V: 10, W: 1},
+	}}
+	other = &Graph{N: 14, Edges: []Edge{
+		{U: 2, V: 1, W: 2}, {U: 3, V: 4, W: 1},
+		{U: 5, V: 6, W: 1}, {U: 6, V: 7, W: 1}, {U: 7, V: 8, W: 1},
+		{U: 11, V: 12, W: 1}, {U: 13, V: 11, W: 1}, {U: 13, V: 13, W: 1},
+	}}
+	return base, other
+}
+
+func TestDiff(t *testing.T) {
+	base, other := versions()
+	want := Delta{
+		Vertices:        Change{Base: 10, Other: 11, Added: 3, Removed: 2},
+		Edges:           Change{Base: 6, Other: 7, Added: 3, Removed: 2},
+		ReweightedEdges: 1,
+		AddedVertices:   []int{11, 12, 13},
+		RemovedVertices: []int{9, 10},
+		AddedEdges:      [][2]int{{6, 7}, {11, 12}, {11, 13}},
+		RemovedEdges:    [][2]int{{2, 3}, {9, 10}},
+		DegreeChanges: []DegreeChange{
+			{Vertex: 11, Before: 0, After: 2},
+			{Vertex: 2, Before: 2, After: 1}, {Vertex: 3, Before: 2, After: 1},
+			{Vertex: 6, Before: 1, After: 2}, {Vertex: 7, Before: 1, After: 2},
+			{Vertex: 9, Before: 1, After: 0}, {Vertex: 10, Before: 1, After: 0},
+			{Vertex: 12, Before: 0, After: 1}, {Vertex: 13, Before: 0, After: 1},
+		},
+		DegreesChanged: 9,
+		Components:     ComponentDelta{Base: 4, Other: 4, Split: 1, Merged: 1, Appeared: 1, Vanished: 1},
+	}
+	if got := Diff(base, other, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffLimit(t *testing.T) {
+	base, other := versions()
+	d := Diff(base, other, 2)
+	if !d.Truncated {
+		t.Error("lists longer than the limit are not marked truncated")
+	}
+	if !reflect.DeepEqual(d.AddedEdges, [][2]int{{6, 7}, {11, 12}}) || len(d.AddedVertices) != 2 || len(d.DegreeChanges) != 2 {
+		t.Errorf("lists were not cut to 2 entries: %+v", d)
+	}
+	// the counts still cover everything
+	if d.Edges.Added != 3 || d.Vertices.Added != 3 || d.DegreesChanged != 9 {
+		t.Errorf("counts changed with the limit: %+v", d)
+	}
+	if len(d.RemovedEdges) != 2 || d.DegreeChanges[0].Vertex != 11 {
+		t.Errorf("kept the wrong entries: %+v", d)
+	}
+
+	same := Diff(base, base, 0)
+	if same.Truncated || same.Edges.Added+same.Edges.Removed+same.DegreesChanged != 0 || same.Components.Split+same.Components.Merged != 0 {
+		t.Errorf("a graph differs from itself: %+v", same)
+	}
+}
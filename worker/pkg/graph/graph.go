@@ -0,0 +1,113 @@
+// Package graph reads the cleaned edge list (graph.txt) written by cleaner.py.
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Edge is an undirected edge with its weight, 1 for unweighted graphs
+type Edge struct {
+	U, V int
+	W    float64
+}
+
+// Graph is an edge list over vertices 0..N-1
+type Graph struct {
+	N     int
+	Edges []Edge
+}
+
+// Read parses a graph file with lines "u v" or "u v w"
+func Read(path string) (*Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads an edge list from r. N is one more than the largest vertex id,
+// matching the numbering used by spectral_embed.
+func Parse(r io.Reader) (*Graph, error) {
+	g := &Graph{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		u, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		v, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if u < 0 || v < 0 {
+			return nil, fmt.Errorf("line %d: negative vertex id", line)
+		}
+		w := 1.0
+		if len(fields) >= 3 {
+			if w, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		g.Edges = append(g.Edges, Edge{U: u, V: v, W: w})
+		if u >= g.N {
+			g.N = u + 1
+		}
+		if v >= g.N {
+			g.N = v + 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Adjacency returns the neighbor lists of all vertices, self loops omitted
+func (g *Graph) Adjacency() [][]int {
+	adj := make([][]int, g.N)
+	for _, e := range g.Edges {
+		if e.U == e.V {
+			continue
+		}
+		adj[e.U] = append(adj[e.U], e.V)
+		adj[e.V] = append(adj[e.V], e.U)
+	}
+	return adj
+}
+
+// Neighbors returns the distinct neighbors of v in increasing order, self
+// loops omitted
+func (g *Graph) Neighbors(v int) []int {
+	seen := make(map[int]bool)
+	var nbrs []int
+	for _, e := range g.Edges {
+		u := -1
+		switch {
+		case e.U == e.V:
+		case e.U == v:
+			u = e.V
+		case e.V == v:
+			u = e.U
+		}
+		if u >= 0 && !seen[u] {
+			seen[u] = true
+			nbrs = append(nbrs, u)
+		}
+	}
+	sort.Ints(nbrs)
+	return nbrs
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SidesFile is written by cleaner.py next to graph.txt for bipartite graphs,
+// holding the number of matrix rows
+const SidesFile = "sides.txt"
+
+// Sides tells the vertex sets of a bipartite graph made from a matrix apart:
+// row i is vertex i and column j is vertex Rows + j, both numbered from 1 as
+// in the file. Rows is 0 for other graphs, whose vertices are the matrix
+// indices themselves.
+type Sides struct {
+	Rows int
+}
+
+// ReadSides loads the sides written by cleaner.py; a missing file means the
+// graph is not bipartite
+func ReadSides(path string) (Sides, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Sides{}, nil
+	}
+	if err != nil {
+		return Sides{}, err
+	}
+	rows, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || rows < 1 {
+		return Sides{}, fmt.Errorf("invalid row count %q in %s", strings.TrimSpace(string(content)), path)
+	}
+	return Sides{Rows: rows}, nil
+}
+
+// Locate returns the side of vertex v, "row" or "column", and its index in the
+// matrix; the side is empty when the graph is not bipartite
+func (s Sides) Locate(v int) (string, int) {
+	switch {
+	case s.Rows == 0:
+		return "", v
+	case v > s.Rows:
+		return "column", v - s.Rows
+	default:
+		return "row", v
+	}
+}
+
+// Name returns the label of vertex v: its id, or "r" or "c" and its row or
+// column index in a bipartite graph
+func (s Sides) Name(v int) string {
+	switch side, index := s.Locate(v); side {
+	case "row":
+		return "r" + strconv.Itoa(index)
+	case "column":
+		return "c" + strconv.Itoa(index)
+	default:
+		return strconv.Itoa(index)
+	}
+}
+
+// Vertex returns the vertex named name, as Name writes it. Plain ids are
+// accepted in bipartite graphs too.
+func (s Sides) Vertex(name string) (int, error) {
+	id, offset, limit := name, 0, 0
+	if s.Rows > 0 && len(name) > 1 {
+		switch name[0] {
+		case 'r', 'R':
+			id, limit = name[1:], s.Rows
+		case 'c', 'C':
+			id, offset = name[1:], s.Rows
+		}
+	}
+	index, err := strconv.Atoi(id)
+	if err != nil || index < 0 || (id != name && index < 1) || (limit > 0 && index > limit) {
+		return 0, fmt.Errorf("invalid vertex %q", name)
+	}
+	return offset + index, nil
+}
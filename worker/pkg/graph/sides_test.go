@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSides(t *testing.T) {
+	// a 3 x 2 matrix: rows 1..3 are vertices 1..3, columns 1..2 vertices 4..5
+	bipartite := Sides{Rows: 3}
+	tests := []struct {
+		sides Sides
+		v     int
+		side  string
+		index int
+		name  string
+	}{
+		{bipartite, 1, "row", 1, "r1"},
+		{bipartite, 3, "row", 3, "r3"},
+		{bipartite, 4, "column", 1, "c1"},
+		{bipartite, 5, "column", 2, "c2"},
+		{Sides{}, 5, "", 5, "5"},
+	}
+	for _, tt := range tests {
+		side, index := tt.sides.Locate(tt.v)
+		if side != tt.side || index != tt.index {
+			t.Errorf("%+v: vertex %d is %s %d, want %s %d", tt.sides, tt.v, side, index, tt.side, tt.index)
+		}
+		if name := tt.sides.Name(tt.v); name != tt.name {
+			t.Errorf("%+v: vertex %d is named %q, want %q", tt.sides, tt.v, name, tt.name)
+		}
+		if v, err := tt.sides.Vertex(tt.name); err != nil || v != tt.v {
+			t.Errorf("%+v: %q is vertex %d (%v), want %d", tt.sides, tt.name, v, err, tt.v)
+		}
+	}
+	if v, err := bipartite.Vertex("4"); err != nil || v != 4 {
+		t.Errorf("plain id 4 is vertex %d (%v)", v, err)
+	}
+	for _, name := range []string{"r4", "r0", "c0", "c", "x1", "-1"} {
+		if _, err := bipartite.Vertex(name); err == nil {
+			t.Errorf("%q was accepted", name)
+		}
+	}
+	if _, err := (Sides{}).Vertex("c1"); err == nil {
+		t.Error("a column name was accepted for a graph that is not bipartite")
+	}
+}
+
+func TestReadSides(t *testing.T) {
+	dir := t.TempDir()
+	if s, err := ReadSides(filepath.Join(dir, SidesFile)); err != nil || s != (Sides{}) {
+		t.Errorf("missing file gives %+v, %v", s, err)
+	}
+	path := filepath.Join(dir, SidesFile)
+	if err := os.WriteFile(path, []byte("18469\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := ReadSides(path); err != nil || s.Rows != 18469 {
+		t.Errorf("read %+v, %v", s, err)
+	}
+	if err := os.WriteFile(path, []byte("rows\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSides(path); err == nil {
+		t.Error("an invalid row count was accepted")
+	}
+}
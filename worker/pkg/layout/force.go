@@ -0,0 +1,262 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// ForceOptions configures the force-directed refinement
+type ForceOptions struct {
+	Iterations int
+	Gravity    float64 // pull towards the center, keeps components together
+	Repulsion  float64 // strength of the repulsion between all pairs of vertices
+	Theta      float64 // Barnes–Hut opening angle; 0 computes the exact forces
+	// Frame, when set, receives the drawing every FrameEvery iterations and at the end
+	Frame      func(iteration int, e *embedding.Embedding) error
+	FrameEvery int
+}
+
+// DefaultForceOptions suits graphs up to a few million edges
+var DefaultForceOptions = ForceOptions{Iterations: 200, Gravity: 0.05, Repulsion: 1, Theta: 1.2}
+
+// Force refines e with a Fruchterman–Reingold style spring embedder starting
+// from its coordinates: edges attract with d^2, all pairs repel with
+// Repulsion/d and gravity pulls towards the center with Gravity*d. Distances
+// are in mean edge lengths of the initial drawing. Repulsion is approximated
+// with a Barnes–Hut quadtree (octree in 3D), and the largest step a vertex may
+// take cools linearly over the iterations.
+func Force(g *graph.Graph, e *embedding.Embedding, opts ForceOptions) (*embedding.Embedding, Result, error) {
+	if opts.Iterations < 1 {
+		return nil, Result{}, fmt.Errorf("force layout needs at least one iteration")
+	}
+	if e.Dims != 2 && e.Dims != 3 {
+		return nil, Result{}, fmt.Errorf("force layout works in 2 or 3 dimensions, got %d", e.Dims)
+	}
+	if opts.Gravity < 0 || opts.Repulsion <= 0 || opts.Theta < 0 {
+		return nil, Result{}, fmt.Errorf("gravity and theta must not be negative and repulsion must be positive")
+	}
+	s := newState(g, e)
+	if len(s.active) == 0 {
+		return nil, Result{}, fmt.Errorf("graph has no edges to lay out")
+	}
+
+	// The natural size of a drawing with unit edges grows with sqrt(n); the first
+	// steps may move a vertex across a good part of it.
+	start := math.Max(1, math.Sqrt(float64(len(s.active)))/10)
+	disp := make([][]float64, len(s.pos))
+	for _, v := range s.active {
+		disp[v] = make([]float64, s.dims)
+	}
+	res := Result{}
+	for res.Iterations < opts.Iterations {
+		res.Iterations++
+		temperature := start * (1 - float64(res.Iterations-1)/float64(opts.Iterations))
+		tree := s.buildTree()
+		center := s.centroid()
+		for _, v := range s.active {
+			f := disp[v]
+			for d := range f {
+				f[d] = 0
+			}
+			tree.repulse(s.pos[v], v, opts.Repulsion, opts.Theta, f)
+			for d := range f {
+				f[d] -= opts.Gravity * (s.pos[v][d] - center[d])
+			}
+			for _, u := range s.adj[v] {
+				dist := distance(s.pos[v], s.pos[u])
+				for d := range f {
+					f[d] -= dist * (s.pos[v][d] - s.pos[u][d])
+				}
+			}
+		}
+		for _, v := range s.active {
+			norm := 0.0
+			for _, x := range disp[v] {
+				norm += x * x
+			}
+			norm = math.Sqrt(norm)
+			if norm == 0 {
+				continue
+			}
+			step := math.Min(norm, temperature) / norm
+			for d := range disp[v] {
+				s.pos[v][d] += disp[v][d] * step
+			}
+		}
+		if opts.Frame != nil && (res.Iterations == opts.Iterations ||
+			(opts.FrameEvery > 0 && res.Iterations%opts.FrameEvery == 0)) {
+			if err := opts.Frame(res.Iterations, s.embedding()); err != nil {
+				return nil, res, err
+			}
+		}
+	}
+	// The schedule always runs to the end, at which point the steps have cooled to zero.
+	res.Converged = true
+	return s.embedding(), res, nil
+}
+
+// tree is a Barnes–Hut quadtree (octree in 3D) stored as a flat slice of
+// cells; the children of a split cell are consecutive
+type tree struct {
+	cells []cell
+	dims  int
+	stack []int
+}
+
+// cell is a cube holding the total mass and the center of mass of the vertices inside it
+type cell struct {
+	min    [3]float64
+	size   float64
+	mass   float64
+	center [3]float64
+	body   int // the vertex of a leaf holding a single vertex, -1 otherwise
+	first  int // index of the first child, 0 for leaves
+}
+
+// maxDepth stops the subdivision of vertices at (nearly) the same position
+const maxDepth = 40
+
+func (s *state) buildTree() *tree {
+	var lo, hi [3]float64
+	copy(lo[:], s.pos[s.active[0]])
+	hi = lo
+	for _, v := range s.active {
+		for d, x := range s.pos[v] {
+			lo[d], hi[d] = math.Min(lo[d], x), math.Max(hi[d], x)
+		}
+	}
+	size := 0.0
+	for d := 0; d < s.dims; d++ {
+		size = math.Max(size, hi[d]-lo[d])
+	}
+	t := &tree{dims: s.dims, cells: make([]cell, 1, 2*len(s.active))}
+	t.cells[0] = cell{min: lo, size: size*1.0001 + 1e-9, body: -1}
+	for _, v := range s.active {
+		t.insert(s.pos, v)
+	}
+	return t
+}
+
+func (t *tree) insert(pos [][]float64, v int) {
+	var p [3]float64
+	copy(p[:], pos[v])
+	c := 0
+	for depth := 0; ; depth++ {
+		cl := &t.cells[c]
+		for d := 0; d < t.dims; d++ {
+			cl.center[d] = (cl.center[d]*cl.mass + p[d]) / (cl.mass + 1)
+		}
+		cl.mass++
+		if cl.mass == 1 {
+			cl.body = v
+			return
+		}
+		if depth >= maxDepth {
+			// coincident vertices stay together in one leaf
+			cl.body = -1
+			return
+		}
+		if cl.first == 0 {
+			old := cl.body
+			cl.body = -1
+			t.split(c)
+			if old >= 0 {
+				// move the single vertex of the former leaf one level down
+				var q [3]float64
+				copy(q[:], pos[old])
+				child := &t.cells[t.child(c, q)]
+				child.center, child.mass, child.body = q, 1, old
+			}
+		}
+		c = t.child(c, p)
+	}
+}
+
+// split creates the empty children of cell c
+func (t *tree) split(c int) {
+	first := len(t.cells)
+	half := t.cells[c].size / 2
+	for index := 0; index < 1<<t.dims; index++ {
+		child := cell{min: t.cells[c].min, size: half, body: -1}
+		for d := 0; d < t.dims; d++ {
+			if index&(1<<d) != 0 {
+				child.min[d] += half
+			}
+		}
+		t.cells = append(t.cells, child)
+	}
+	t.cells[c].first = first
+}
+
+// child returns the index of the child of c containing p
+func (t *tree) child(c int, p [3]float64) int {
+	cl := &t.cells[c]
+	half := cl.size / 2
+	index := 0
+	for d := 0; d < t.dims; d++ {
+		if p[d] >= cl.min[d]+half {
+			index |= 1 << d
+		}
+	}
+	return cl.first + index
+}
+
+// repulse adds to f the repulsion of all other vertices on vertex v at pos
+func (t *tree) repulse(pos []float64, v int, strength, theta float64, f []float64) {
+	var p [3]float64
+	copy(p[:], pos)
+	stack := append(t.stack[:0], 0)
+	defer func() { t.stack = stack }()
+	for len(stack) > 0 {
+		c := &t.cells[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if c.mass == 0 || c.body == v {
+			continue
+		}
+		dist2 := 0.0
+		for d := 0; d < t.dims; d++ {
+			dist2 += (p[d] - c.center[d]) * (p[d] - c.center[d])
+		}
+		if c.first != 0 && (c.size*c.size >= theta*theta*dist2 || t.contains(c, p)) {
+			for index := 0; index < 1<<t.dims; index++ {
+				stack = append(stack, c.first+index)
+			}
+			continue
+		}
+		if c.body < 0 && t.contains(c, p) {
+			// v is one of the coincident vertices of a leaf at the maximal depth,
+			// whose center may be off by rounding: the others push it along one axis
+			sign := 1.0
+			if v%2 == 1 {
+				sign = -1
+			}
+			f[v%t.dims] += sign * strength * (c.mass - 1)
+			continue
+		}
+		if dist2 == 0 {
+			// vertices at the same position are pushed apart along one axis
+			sign := 1.0
+			if v > c.body {
+				sign = -1
+			}
+			f[v%t.dims] += sign * strength * c.mass
+			continue
+		}
+		scale := strength * c.mass / dist2
+		for d := 0; d < t.dims; d++ {
+			f[d] += scale * (p[d] - c.center[d])
+		}
+	}
+}
+
+func (t *tree) contains(c *cell, p [3]float64) bool {
+	for d := 0; d < t.dims; d++ {
+		if p[d] < c.min[d] || p[d] >= c.min[d]+c.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package layout
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// randomState places n active vertices uniformly in the unit cube
+func randomState(n, dims int, seed int64) *state {
+	rng := rand.New(rand.NewSource(seed))
+	s := &state{dims: dims, pos: make([][]float64, n)}
+	for v := range s.pos {
+		s.pos[v] = make([]float64, dims)
+		for d := range s.pos[v] {
+			s.pos[v][d] = rng.Float64()
+		}
+		s.active = append(s.active, v)
+	}
+	return s
+}
+
+// exactRepulsion sums the repulsion of every other vertex on v directly
+func exactRepulsion(s *state, v int, strength float64) []float64 {
+	f := make([]float64, s.dims)
+	for _, u := range s.active {
+		if u == v {
+			continue
+		}
+		dist2 := 0.0
+		for d := range f {
+			dist2 += (s.pos[v][d] - s.pos[u][d]) * (s.pos[v][d] - s.pos[u][d])
+		}
+		for d := range f {
+			f[d] += strength * (s.pos[v][d] - s.pos[u][d]) / dist2
+		}
+	}
+	return f
+}
+
+func norm(f []float64) float64 {
+	sum := 0.0
+	for _, x := range f {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+func TestRepulsionMatchesExact(t *testing.T) {
+	for _, dims := range []int{2, 3} {
+		s := randomState(300, dims, int64(dims))
+		tree := s.buildTree()
+		if got := tree.cells[0].mass; got != float64(len(s.active)) {
+			t.Fatalf("%dD: root holds mass %g, want %d", dims, got, len(s.active))
+		}
+		var errSum, forceSum float64
+		for _, v := range s.active {
+			want := exactRepulsion(s, v, 1.5)
+			exact := make([]float64, dims)
+			tree.repulse(s.pos[v], v, 1.5, 0, exact)
+			approx := make([]float64, dims)
+			tree.repulse(s.pos[v], v, 1.5, DefaultForceOptions.Theta, approx)
+			diff, approxDiff := make([]float64, dims), make([]float64, dims)
+			for d := range want {
+				diff[d] = exact[d] - want[d]
+				approxDiff[d] = approx[d] - want[d]
+			}
+			// theta 0 opens every cell, so only rounding differs
+			if norm(diff) > 1e-9*math.Max(1, norm(want)) {
+				t.Fatalf("%dD vertex %d: theta 0 force %v, exact %v", dims, v, exact, want)
+			}
+			errSum += norm(approxDiff)
+			forceSum += norm(want)
+		}
+		if rel := errSum / forceSum; rel > 0.1 {
+			t.Errorf("%dD: Barnes–Hut forces are off by %.1f%% on average", dims, 100*rel)
+		}
+	}
+}
+
+func TestRepulsionCoincident(t *testing.T) {
+	for _, dims := range []int{2, 3} {
+		s := randomState(20, dims, 5)
+		// five vertices at one position end in a leaf at the maximal depth
+		for v := 1; v <= 5; v++ {
+			copy(s.pos[v], s.pos[0])
+		}
+		tree := s.buildTree()
+		deepest := -1
+		for i, c := range tree.cells {
+			if c.first == 0 && c.mass == 6 {
+				deepest = i
+			}
+		}
+		if deepest < 0 {
+			t.Fatalf("%dD: no leaf holds the six coincident vertices", dims)
+		}
+		if tree.cells[deepest].body != -1 {
+			t.Errorf("%dD: the coincident leaf names vertex %d", dims, tree.cells[deepest].body)
+		}
+		for v := 0; v <= 5; v++ {
+			f := make([]float64, dims)
+			tree.repulse(s.pos[v], v, 1, 0, f)
+			// the others at the same place push v along one axis, as a single
+			// coincident vertex would, the rest of the graph as usual
+			far := exactRepulsion(&state{dims: dims, pos: s.pos, active: s.active[6:]}, v, 1)
+			for d := range far {
+				far[d] = f[d] - far[d]
+			}
+			if math.IsNaN(norm(f)) || math.Abs(norm(far)-5) > 1e-9 {
+				t.Errorf("%dD vertex %d: coincident push %v, want magnitude 5", dims, v, far)
+			}
+		}
+	}
+}
+
+func TestForceSeparatesCoincident(t *testing.T) {
+	// a star whose leaves all start on the center
+	g := &graph.Graph{N: 7}
+	e := embedding.New(7, 2)
+	for v := 2; v < 7; v++ {
+		g.Edges = append(g.Edges, graph.Edge{U: 1, V: v, W: 1})
+	}
+	out, _, err := Force(g, e, ForceOptions{Iterations: 50, Gravity: 0.05, Repulsion: 1, Theta: 1.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for u := 1; u < 7; u++ {
+		for v := u + 1; v < 7; v++ {
+			if d := math.Hypot(out.Coords[u][0]-out.Coords[v][0], out.Coords[u][1]-out.Coords[v][1]); !(d > 1e-3) {
+				t.Errorf("vertices %d and %d are %g apart", u, v, d)
+			}
+		}
+	}
+}
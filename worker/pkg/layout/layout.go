@@ -0,0 +1,151 @@
+// Package layout refines a spectral embedding into a final drawing. The
+// refinements start from the spectral coordinates and work in any dimension,
+// reading and writing the same embedding files as the rest of the pipeline.
+package layout
+
+import (
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Result describes a finished refinement
+type Result struct {
+	Iterations int  `json:"iterations"`
+	Converged  bool `json:"converged"`
+}
+
+// state is the working copy of a drawing: the vertices with at least one edge,
+// their neighbor lists and coordinates scaled so that edges have unit mean length
+type state struct {
+	pos    [][]float64
+	adj    [][]int
+	active []int
+	dims   int
+}
+
+func newState(g *graph.Graph, e *embedding.Embedding) *state {
+	n := e.Len()
+	s := &state{dims: e.Dims, adj: make([][]int, n), pos: make([][]float64, n)}
+	for _, edge := range g.Edges {
+		if edge.U == edge.V || edge.U >= n || edge.V >= n {
+			continue
+		}
+		s.adj[edge.U] = append(s.adj[edge.U], edge.V)
+		s.adj[edge.V] = append(s.adj[edge.V], edge.U)
+	}
+	for v := range s.pos {
+		s.pos[v] = append([]float64(nil), e.Coords[v]...)
+		if len(s.adj[v]) > 0 {
+			s.active = append(s.active, v)
+		}
+	}
+	s.normalize()
+	return s
+}
+
+// normalize centers the active vertices and scales them to unit mean edge
+// length, the scale of graph distances. Coincident vertices, which spectral
+// layouts of symmetric graphs produce, are spread apart deterministically.
+func (s *state) normalize() {
+	center := s.centroid()
+	for _, v := range s.active {
+		for d := range s.pos[v] {
+			s.pos[v][d] -= center[d]
+		}
+	}
+	mean := s.meanEdgeLength()
+	if mean == 0 {
+		for i, v := range s.active {
+			for d := range s.pos[v] {
+				// golden-ratio spiral keeps the spread reproducible
+				s.pos[v][d] = math.Sqrt(float64(i)) * math.Cos(float64(i)*2.399963+float64(d)*math.Pi/2)
+			}
+		}
+		mean = s.meanEdgeLength()
+	}
+	if mean > 0 {
+		for _, v := range s.active {
+			for d := range s.pos[v] {
+				s.pos[v][d] /= mean
+			}
+		}
+	}
+}
+
+func (s *state) centroid() []float64 {
+	center := make([]float64, s.dims)
+	if len(s.active) == 0 {
+		return center
+	}
+	for _, v := range s.active {
+		for d, x := range s.pos[v] {
+			center[d] += x
+		}
+	}
+	for d := range center {
+		center[d] /= float64(len(s.active))
+	}
+	return center
+}
+
+func (s *state) meanEdgeLength() float64 {
+	sum, count := 0.0, 0
+	for u, nbrs := range s.adj {
+		for _, v := range nbrs {
+			if u < v {
+				sum += distance(s.pos[u], s.pos[v])
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// embedding returns the refined coordinates. Vertices without edges are not
+// drawn; they are placed at the centroid so they do not stretch bounding boxes.
+func (s *state) embedding() *embedding.Embedding {
+	e := embedding.New(len(s.pos), s.dims)
+	center := s.centroid()
+	for v := range s.pos {
+		if len(s.adj[v]) == 0 {
+			copy(e.Coords[v], center)
+			continue
+		}
+		copy(e.Coords[v], s.pos[v])
+	}
+	return e
+}
+
+// bfs returns the hop distances from src, -1 for unreachable vertices
+func (s *state) bfs(src int) []int32 {
+	hops := make([]int32, len(s.adj))
+	for i := range hops {
+		hops[i] = -1
+	}
+	hops[src] = 0
+	queue := []int{src}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, v := range s.adj[u] {
+			if hops[v] < 0 {
+				hops[v] = hops[u] + 1
+				queue = append(queue, v)
+			}
+		}
+	}
+	return hops
+}
+
+func distance(a, b []float64) float64 {
+	sum := 0.0
+	for d := range a {
+		sum += (a[d] - b[d]) * (a[d] - b[d])
+	}
+	return math.Sqrt(sum)
+}
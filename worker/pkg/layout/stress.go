@@ -0,0 +1,138 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// StressOptions configures stress majorization
+type StressOptions struct {
+	Iterations int
+	// Pivots is the number of vertices whose graph distances to all others are
+	// kept (sparse stress). With at least as many pivots as vertices every pair
+	// is used, which is full stress majorization.
+	Pivots    int
+	Tolerance float64 // stop when no vertex moves farther, in mean edge lengths
+}
+
+// DefaultStressOptions suits graphs up to a few million edges
+var DefaultStressOptions = StressOptions{Iterations: 100, Pivots: 50, Tolerance: 1e-4}
+
+// Stress refines e by stress majorization, starting from its coordinates. The
+// stress terms are the edges (target length 1) and the pairs of every vertex
+// with the pivots, weighted by 1/d^2 for graph distance d. Pivots are picked
+// by max-min distance, so every connected component gets at least one.
+func Stress(g *graph.Graph, e *embedding.Embedding, opts StressOptions) (*embedding.Embedding, Result, error) {
+	if opts.Iterations < 1 || opts.Pivots < 1 {
+		return nil, Result{}, fmt.Errorf("stress needs at least one iteration and one pivot")
+	}
+	s := newState(g, e)
+	if len(s.active) == 0 {
+		return nil, Result{}, fmt.Errorf("graph has no edges to lay out")
+	}
+	pivots, hops := s.pivots(opts.Pivots)
+	pivotIndex := make([]int, len(s.pos))
+	for v := range pivotIndex {
+		pivotIndex[v] = -1
+	}
+	for q, p := range pivots {
+		pivotIndex[p] = q
+	}
+
+	num := make([]float64, s.dims)
+	res := Result{}
+	for res.Iterations < opts.Iterations {
+		res.Iterations++
+		maxMove := 0.0
+		for _, i := range s.active {
+			for d := range num {
+				num[d] = 0
+			}
+			den := 0.0
+			term := func(j int, target, weight float64) {
+				dist := distance(s.pos[i], s.pos[j])
+				for d := range num {
+					x := s.pos[j][d]
+					if dist > 0 {
+						x += target * (s.pos[i][d] - s.pos[j][d]) / dist
+					}
+					num[d] += weight * x
+				}
+				den += weight
+			}
+			for _, j := range s.adj[i] {
+				term(j, 1, 1)
+			}
+			for q, p := range pivots {
+				if h := float64(hops[q][i]); p != i && h > 1 {
+					term(p, h, 1/(h*h))
+				}
+			}
+			if q := pivotIndex[i]; q >= 0 {
+				// pairs with other pivots are already covered from their side
+				for _, j := range s.active {
+					if h := float64(hops[q][j]); pivotIndex[j] < 0 && h > 1 {
+						term(j, h, 1/(h*h))
+					}
+				}
+			}
+			move := 0.0
+			for d := range num {
+				x := num[d] / den
+				move += (x - s.pos[i][d]) * (x - s.pos[i][d])
+				s.pos[i][d] = x
+			}
+			maxMove = math.Max(maxMove, math.Sqrt(move))
+		}
+		if maxMove < opts.Tolerance {
+			res.Converged = true
+			break
+		}
+	}
+	return s.embedding(), res, nil
+}
+
+// pivots picks up to k pivots by max-min distance, starting at the vertex of
+// highest degree, and returns them with their BFS distances
+func (s *state) pivots(k int) ([]int, [][]int32) {
+	if k > len(s.active) {
+		k = len(s.active)
+	}
+	first := s.active[0]
+	for _, v := range s.active {
+		if len(s.adj[v]) > len(s.adj[first]) {
+			first = v
+		}
+	}
+	nearest := make([]int32, len(s.pos))
+	for v := range nearest {
+		nearest[v] = math.MaxInt32 // not reached from any pivot yet
+	}
+	pivots := []int{first}
+	var hops [][]int32
+	for {
+		h := s.bfs(pivots[len(pivots)-1])
+		hops = append(hops, h)
+		for _, v := range s.active {
+			if h[v] >= 0 && h[v] < nearest[v] {
+				nearest[v] = h[v]
+			}
+		}
+		if len(pivots) == k {
+			return pivots, hops
+		}
+		next := -1
+		for _, v := range s.active {
+			if nearest[v] > 0 && (next < 0 || nearest[v] > nearest[next]) {
+				next = v
+			}
+		}
+		if next < 0 {
+			return pivots, hops
+		}
+		pivots = append(pivots, next)
+	}
+}
@@ -0,0 +1,99 @@
+package layout
+
+import (
+	"math/rand"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// grid returns the w by h grid graph on vertices 1..w*h, drawn at its grid
+// coordinates moved by up to noise in each direction
+func grid(w, h int, noise float64, seed int64) (*graph.Graph, *embedding.Embedding) {
+	rng := rand.New(rand.NewSource(seed))
+	g := &graph.Graph{N: w*h + 1}
+	e := embedding.New(g.N, 2)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 1 + y*w + x
+			e.Coords[v][0] = float64(x) + noise*(2*rng.Float64()-1)
+			e.Coords[v][1] = float64(y) + noise*(2*rng.Float64()-1)
+			if x > 0 {
+				g.Edges = append(g.Edges, graph.Edge{U: v - 1, V: v, W: 1})
+			}
+			if y > 0 {
+				g.Edges = append(g.Edges, graph.Edge{U: v - w, V: v, W: 1})
+			}
+		}
+	}
+	return g, e
+}
+
+// fullStress is the stress of e over all connected pairs at the best scale, so
+// that drawings of different sizes compare
+func fullStress(g *graph.Graph, e *embedding.Embedding) float64 {
+	s := newState(g, e)
+	var xd, xx float64
+	pairs := 0
+	for _, u := range s.active {
+		hops := s.bfs(u)
+		for _, v := range s.active {
+			if d := float64(hops[v]); d > 0 {
+				x := distance(e.Coords[u], e.Coords[v])
+				xd += x / d
+				xx += x * x / (d * d)
+				pairs++
+			}
+		}
+	}
+	return (float64(pairs) - xd*xd/xx) / float64(pairs)
+}
+
+func TestStressLowersStress(t *testing.T) {
+	g, e := grid(6, 5, 0.8, 1)
+	before := fullStress(g, e)
+	for _, pivots := range []int{5, g.N} {
+		out, res, err := Stress(g, e, StressOptions{Iterations: 200, Pivots: pivots, Tolerance: 1e-6})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Iterations < 1 {
+			t.Errorf("%d pivots: no iteration ran", pivots)
+		}
+		if after := fullStress(g, out); after >= before/2 {
+			t.Errorf("%d pivots: stress went from %g to %g", pivots, before, after)
+		}
+	}
+}
+
+func TestPivotsCoverComponents(t *testing.T) {
+	// a path 1..8 with a leaf 9 on vertex 4, a triangle 10-11-12 and an edge
+	// 13-14; the first pivot is on the path, the highest degree vertex
+	edges := [][2]int{{1, 2}, {2, 3}, {3, 4}, {4, 5}, {5, 6}, {6, 7}, {7, 8}, {4, 9},
+		{10, 11}, {11, 12}, {12, 10}, {13, 14}}
+	g := &graph.Graph{N: 15}
+	for _, edge := range edges {
+		g.Edges = append(g.Edges, graph.Edge{U: edge[0], V: edge[1], W: 1})
+	}
+	e := embedding.New(g.N, 2)
+	for v := range e.Coords {
+		e.Coords[v][0], e.Coords[v][1] = float64(v), float64(v%3)
+	}
+	s := newState(g, e)
+	for _, k := range []int{3, 4, 20} {
+		pivots, hops := s.pivots(k)
+		if pivots[0] != 4 || len(hops) != len(pivots) {
+			t.Fatalf("k=%d: pivots %v with %d distance rows", k, pivots, len(hops))
+		}
+		for _, v := range s.active {
+			reached := false
+			for q := range pivots {
+				reached = reached || hops[q][v] >= 0
+			}
+			if !reached {
+				t.Errorf("k=%d: vertex %d is not reached from pivots %v", k, v, pivots)
+			}
+		}
+	}
+}
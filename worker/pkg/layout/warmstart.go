@@ -0,0 +1,89 @@
+package layout
+
+import (
+	"fmt"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// WarmStart maps the embedding of an earlier version of a graph onto g by
+// vertex id, as the start of the spectral solver. Vertices drawn in the
+// earlier version keep their coordinates. The others start at the mean of
+// their placed neighbors, filled in breadth-first from the kept part, and
+// vertices not connected to it start at the centroid of the kept ones.
+// It returns one row per vertex of g and the number of vertices with edges in
+// both versions.
+func WarmStart(parentGraph *graph.Graph, parent *embedding.Embedding, g *graph.Graph) (*embedding.Embedding, int, error) {
+	e := embedding.New(g.N, parent.Dims)
+	placed := make([]bool, g.N)
+	queued := make([]bool, g.N)
+	inParent := make([]bool, parent.Len())
+	for _, edge := range parentGraph.Edges {
+		if edge.U != edge.V && edge.U < len(inParent) && edge.V < len(inParent) {
+			inParent[edge.U], inParent[edge.V] = true, true
+		}
+	}
+	adj := g.Adjacency()
+	var queue []int
+	kept := 0
+	center := make([]float64, parent.Dims)
+	for v := 0; v < g.N && v < len(inParent); v++ {
+		if !inParent[v] {
+			continue
+		}
+		copy(e.Coords[v], parent.Coords[v])
+		placed[v], queued[v] = true, true
+		queue = append(queue, v)
+		for d, x := range parent.Coords[v] {
+			center[d] += x
+		}
+		if len(adj[v]) > 0 {
+			kept++
+		}
+	}
+	if kept == 0 {
+		return nil, 0, fmt.Errorf("no vertex of the graph is drawn in the earlier version")
+	}
+	for d := range center {
+		center[d] /= float64(len(queue))
+	}
+
+	// Breadth-first layers: a vertex is placed once all vertices closer to the
+	// kept part are, at the mean of the neighbors placed before its layer.
+	for len(queue) > 0 {
+		var next []int
+		for _, u := range queue {
+			for _, v := range adj[u] {
+				if !queued[v] {
+					queued[v] = true
+					next = append(next, v)
+				}
+			}
+		}
+		for _, v := range next {
+			count := 0
+			for _, u := range adj[v] {
+				if placed[u] {
+					for d, x := range e.Coords[u] {
+						e.Coords[v][d] += x
+					}
+					count++
+				}
+			}
+			for d := range e.Coords[v] {
+				e.Coords[v][d] /= float64(count)
+			}
+		}
+		for _, v := range next {
+			placed[v] = true
+		}
+		queue = next
+	}
+	for v := range e.Coords {
+		if !placed[v] {
+			copy(e.Coords[v], center)
+		}
+	}
+	return e, kept, nil
+}
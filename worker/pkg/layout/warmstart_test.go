@@ -0,0 +1,53 @@
+package layout
+
+import (
+	"math"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+func TestWarmStart(t *testing.T) {
+	// the path 1-2-3, vertex 0 has no edges and is not drawn
+	parentGraph := &graph.Graph{N: 4, Edges: []graph.Edge{{U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 1}}}
+	parent := &embedding.Embedding{Coords: [][]float64{{9, 9}, {0, 0}, {2, 0}, {2, 2}}, Dims: 2}
+	// 4 joins 1 and 3, 6 hangs off 2, 5 joins 4 and 6, and 7-8 is a separate component
+	g := &graph.Graph{N: 9, Edges: []graph.Edge{
+		{U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 1}, {U: 3, V: 4, W: 1}, {U: 1, V: 4, W: 1},
+		{U: 4, V: 5, W: 1}, {U: 5, V: 6, W: 1}, {U: 6, V: 2, W: 1}, {U: 7, V: 8, W: 1},
+	}}
+	e, kept, err := WarmStart(parentGraph, parent, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != 3 {
+		t.Errorf("kept %d vertices, want 3", kept)
+	}
+	centroid := []float64{4.0 / 3, 2.0 / 3}
+	want := [][]float64{
+		centroid,               // no edges in either version
+		{0, 0}, {2, 0}, {2, 2}, // kept
+		{1, 1},             // first layer, between 1 and 3
+		{1.5, 0.5},         // second layer, between 4 and 6 of the first
+		{2, 0},             // first layer, only 2 is placed before it
+		centroid, centroid, // not connected to the kept part
+	}
+	if e.Len() != g.N || e.Dims != 2 {
+		t.Fatalf("got %d rows of %d dimensions", e.Len(), e.Dims)
+	}
+	for v, p := range want {
+		if math.Abs(e.Coords[v][0]-p[0]) > 1e-12 || math.Abs(e.Coords[v][1]-p[1]) > 1e-12 {
+			t.Errorf("vertex %d starts at %v, want %v", v, e.Coords[v], p)
+		}
+	}
+}
+
+func TestWarmStartDisjoint(t *testing.T) {
+	parentGraph := &graph.Graph{N: 3, Edges: []graph.Edge{{U: 1, V: 2, W: 1}}}
+	parent := &embedding.Embedding{Coords: [][]float64{{0, 0}, {1, 0}, {0, 1}}, Dims: 2}
+	g := &graph.Graph{N: 5, Edges: []graph.Edge{{U: 3, V: 4, W: 1}}}
+	if _, _, err := WarmStart(parentGraph, parent, g); err == nil {
+		t.Error("a graph sharing no vertex with its parent was warm-started")
+	}
+}
@@ -0,0 +1,346 @@
+// Package metrics measures the quality of a drawing from the graph and its
+// coordinates, so that layouts produced with different parameters can be
+// compared by numbers rather than by eye.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Options bounds the work spent on the metrics of large graphs
+type Options struct {
+	Sources       int   // BFS sources for stress and neighborhood preservation
+	CrossingPairs int   // edge pairs tested for crossings; all pairs are tested when there are fewer
+	Seed          int64 // seed of the sampling, so metrics are reproducible
+}
+
+// DefaultOptions keeps the metrics of a million-edge graph within a few seconds
+var DefaultOptions = Options{Sources: 100, CrossingPairs: 1000000, Seed: 1}
+
+// Metrics describes a drawing. Stress and neighborhood preservation are
+// estimated from BFS sources, crossings from sampled edge pairs when the graph
+// is large.
+type Metrics struct {
+	Vertices int `json:"vertices"`
+	Edges    int `json:"edges"`
+	// Stress is the normalized stress sum((a*|xi-xj| - dij)^2 / dij^2) / pairs
+	// with the scale a that minimizes it; 0 means distances match the graph exactly
+	Stress        float64 `json:"stress"`
+	StressSources int     `json:"stress_sources"`
+	// EdgeLengthCV is the standard deviation of the edge lengths divided by their mean
+	EdgeLengthCV float64 `json:"edge_length_cv"`
+	// NeighborhoodPreservation is the mean Jaccard similarity between the graph
+	// neighbors of a vertex and its deg(v) nearest vertices in the drawing
+	NeighborhoodPreservation float64 `json:"neighborhood_preservation"`
+	Crossings                float64 `json:"crossings"`
+	CrossingsSampled         bool    `json:"crossings_sampled"`
+	// AngularResolution is the mean over vertices of the smallest angle between
+	// incident edges divided by the ideal 360/deg(v) degrees
+	AngularResolution float64 `json:"angular_resolution"`
+	MinAngleDegrees   float64 `json:"min_angle_degrees"`
+}
+
+// drawing is the graph reduced to the vertices and edges that are drawn:
+// vertices with at least one edge, without self loops and duplicate edges
+type drawing struct {
+	pos    [][]float64
+	active []int
+	edges  [][2]int
+	adj    [][]int
+}
+
+func newDrawing(g *graph.Graph, e *embedding.Embedding) *drawing {
+	n := e.Len()
+	d := &drawing{pos: e.Coords, adj: make([][]int, n)}
+	seen := make(map[[2]int]bool)
+	for _, edge := range g.Edges {
+		u, v := edge.U, edge.V
+		if u == v || u >= n || v >= n {
+			continue
+		}
+		if u > v {
+			u, v = v, u
+		}
+		if seen[[2]int{u, v}] {
+			continue
+		}
+		seen[[2]int{u, v}] = true
+		d.edges = append(d.edges, [2]int{u, v})
+		d.adj[u] = append(d.adj[u], v)
+		d.adj[v] = append(d.adj[v], u)
+	}
+	for v := range d.adj {
+		if len(d.adj[v]) > 0 {
+			d.active = append(d.active, v)
+		}
+	}
+	return d
+}
+
+// Compute measures the drawing of g given by the first two coordinates of e
+// (all coordinates for stress, neighborhood preservation and edge lengths)
+func Compute(g *graph.Graph, e *embedding.Embedding, opts Options) (Metrics, error) {
+	if e.Dims < 2 {
+		return Metrics{}, fmt.Errorf("metrics need at least 2 dimensions, got %d", e.Dims)
+	}
+	d := newDrawing(g, e)
+	m := Metrics{Vertices: len(d.active), Edges: len(d.edges)}
+	if len(d.edges) == 0 {
+		return m, fmt.Errorf("graph has no edges to measure")
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	sources := sample(d.active, opts.Sources, rng)
+	m.StressSources = len(sources)
+	m.Stress = d.stress(sources)
+	m.NeighborhoodPreservation = d.neighborhoodPreservation(sources)
+	m.EdgeLengthCV = d.edgeLengthCV()
+	m.Crossings, m.CrossingsSampled = d.crossings(opts.CrossingPairs, rng)
+	m.AngularResolution, m.MinAngleDegrees = d.angularResolution()
+	return m, nil
+}
+
+// sample returns up to k distinct elements of xs in random order
+func sample(xs []int, k int, rng *rand.Rand) []int {
+	if k <= 0 || k >= len(xs) {
+		return append([]int(nil), xs...)
+	}
+	perm := rng.Perm(len(xs))[:k]
+	out := make([]int, k)
+	for i, p := range perm {
+		out[i] = xs[p]
+	}
+	return out
+}
+
+func dist(a, b []float64) float64 {
+	s := 0.0
+	for i := range a {
+		s += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(s)
+}
+
+// bfs returns the hop distances from s, -1 for unreachable vertices
+func (d *drawing) bfs(s int) []int {
+	hops := make([]int, len(d.adj))
+	for i := range hops {
+		hops[i] = -1
+	}
+	hops[s] = 0
+	queue := []int{s}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for _, v := range d.adj[u] {
+			if hops[v] < 0 {
+				hops[v] = hops[u] + 1
+				queue = append(queue, v)
+			}
+		}
+	}
+	return hops
+}
+
+func (d *drawing) stress(sources []int) float64 {
+	// With weights 1/d^2 the stress at scale a is a^2*sum(x^2/d^2) - 2a*sum(x/d) + pairs,
+	// minimized by a = sum(x/d) / sum(x^2/d^2), so the pairs need not be stored.
+	var xd, xx float64
+	pairs := 0
+	for _, s := range sources {
+		hops := d.bfs(s)
+		for _, t := range d.active {
+			if t == s || hops[t] < 0 {
+				continue
+			}
+			x, h := dist(d.pos[s], d.pos[t]), float64(hops[t])
+			xd += x / h
+			xx += x * x / (h * h)
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	sum := float64(pairs)
+	if xx > 0 {
+		sum -= xd * xd / xx
+	}
+	return math.Max(sum, 0) / float64(pairs)
+}
+
+func (d *drawing) neighborhoodPreservation(sources []int) float64 {
+	if len(sources) == 0 || len(d.active) < 2 {
+		return 0
+	}
+	total := 0.0
+	dists := make([]float64, len(d.active))
+	scratch := make([]float64, len(d.active))
+	for _, s := range sources {
+		for i, t := range d.active {
+			dists[i] = dist(d.pos[s], d.pos[t])
+			if t == s {
+				dists[i] = math.Inf(1)
+			}
+		}
+		k := len(d.adj[s])
+		if k > len(d.active)-1 {
+			k = len(d.active) - 1
+		}
+		// the k nearest vertices are those closer than the k-th distance,
+		// topped up with vertices at exactly that distance
+		copy(scratch, dists)
+		radius := kthSmallest(scratch, k-1)
+		near := make(map[int]bool, k)
+		for i, t := range d.active {
+			if dists[i] < radius {
+				near[t] = true
+			}
+		}
+		for i, t := range d.active {
+			if len(near) >= k {
+				break
+			}
+			if dists[i] == radius {
+				near[t] = true
+			}
+		}
+		common := 0
+		for _, t := range d.adj[s] {
+			if near[t] {
+				common++
+			}
+		}
+		total += float64(common) / float64(len(d.adj[s])+k-common)
+	}
+	return total / float64(len(sources))
+}
+
+// kthSmallest returns the k-th smallest element (0-based) of xs, reordering xs
+func kthSmallest(xs []float64, k int) float64 {
+	lo, hi := 0, len(xs)-1
+	for lo < hi {
+		pivot := xs[(lo+hi)/2]
+		i, j := lo, hi
+		for i <= j {
+			for xs[i] < pivot {
+				i++
+			}
+			for xs[j] > pivot {
+				j--
+			}
+			if i <= j {
+				xs[i], xs[j] = xs[j], xs[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return xs[k]
+		}
+	}
+	return xs[k]
+}
+
+func (d *drawing) edgeLengthCV() float64 {
+	var sum, sq float64
+	for _, e := range d.edges {
+		l := dist(d.pos[e[0]], d.pos[e[1]])
+		sum += l
+		sq += l * l
+	}
+	n := float64(len(d.edges))
+	mean := sum / n
+	if mean == 0 {
+		return 0
+	}
+	variance := math.Max(sq/n-mean*mean, 0)
+	return math.Sqrt(variance) / mean
+}
+
+// crossings counts pairs of edges that cross in the plane, testing every pair
+// when there are at most maxPairs of them and extrapolating from maxPairs
+// random pairs otherwise
+func (d *drawing) crossings(maxPairs int, rng *rand.Rand) (float64, bool) {
+	m := len(d.edges)
+	total := float64(m) * float64(m-1) / 2
+	if maxPairs <= 0 || total <= float64(maxPairs) {
+		count := 0
+		for i := 0; i < m; i++ {
+			for j := i + 1; j < m; j++ {
+				if d.cross(d.edges[i], d.edges[j]) {
+					count++
+				}
+			}
+		}
+		return float64(count), false
+	}
+	count := 0
+	for k := 0; k < maxPairs; k++ {
+		i, j := rng.Intn(m), rng.Intn(m-1)
+		if j >= i {
+			j++
+		}
+		if d.cross(d.edges[i], d.edges[j]) {
+			count++
+		}
+	}
+	return math.Round(float64(count) / float64(maxPairs) * total), true
+}
+
+// cross reports whether two edges without a common endpoint properly intersect
+func (d *drawing) cross(a, b [2]int) bool {
+	if a[0] == b[0] || a[0] == b[1] || a[1] == b[0] || a[1] == b[1] {
+		return false
+	}
+	p1, p2 := d.pos[a[0]], d.pos[a[1]]
+	q1, q2 := d.pos[b[0]], d.pos[b[1]]
+	d1 := orient(q1, q2, p1)
+	d2 := orient(q1, q2, p2)
+	d3 := orient(p1, p2, q1)
+	d4 := orient(p1, p2, q2)
+	return d1*d2 < 0 && d3*d4 < 0
+}
+
+// orient is the sign of the turn a -> b -> c in the first two coordinates
+func orient(a, b, c []float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func (d *drawing) angularResolution() (float64, float64) {
+	total, count := 0.0, 0
+	minAngle := 2 * math.Pi
+	angles := make([]float64, 0)
+	for _, v := range d.active {
+		deg := len(d.adj[v])
+		if deg < 2 {
+			continue
+		}
+		angles = angles[:0]
+		for _, u := range d.adj[v] {
+			angles = append(angles, math.Atan2(d.pos[u][1]-d.pos[v][1], d.pos[u][0]-d.pos[v][0]))
+		}
+		sort.Float64s(angles)
+		smallest := angles[0] + 2*math.Pi - angles[deg-1]
+		for i := 1; i < deg; i++ {
+			smallest = math.Min(smallest, angles[i]-angles[i-1])
+		}
+		total += smallest / (2 * math.Pi / float64(deg))
+		count++
+		minAngle = math.Min(minAngle, smallest)
+	}
+	if count == 0 {
+		return 1, 0
+	}
+	return total / float64(count), minAngle * 180 / math.Pi
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// drawn returns the drawing of the given edges at the given positions
+func drawn(pos [][]float64, edges ...[2]int) *drawing {
+	g := &graph.Graph{N: len(pos)}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, graph.Edge{U: e[0], V: e[1], W: 1})
+	}
+	return newDrawing(g, &embedding.Embedding{Coords: pos, Dims: len(pos[0])})
+}
+
+// randomDrawing places n vertices at random and joins them by m random edges
+func randomDrawing(n, m int, seed int64) *drawing {
+	rng := rand.New(rand.NewSource(seed))
+	pos := make([][]float64, n)
+	for v := range pos {
+		pos[v] = []float64{rng.Float64(), rng.Float64()}
+	}
+	var edges [][2]int
+	for v := 1; v < n; v++ {
+		// a random tree keeps the graph connected
+		edges = append(edges, [2]int{rng.Intn(v), v})
+	}
+	for len(edges) < m {
+		edges = append(edges, [2]int{rng.Intn(n), rng.Intn(n)})
+	}
+	return drawn(pos, edges...)
+}
+
+func TestStressScale(t *testing.T) {
+	// a path drawn straight at three times its graph distances has no stress
+	line := drawn([][]float64{{0, 0}, {3, 0}, {6, 0}, {9, 0}}, [2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3})
+	if s := line.stress(line.active); math.Abs(s) > 1e-12 {
+		t.Errorf("stress of a scaled path is %g, want 0", s)
+	}
+
+	// the closed form matches the stress at the best of many scales
+	d := randomDrawing(30, 60, 1)
+	var pairs [][2]float64
+	for _, s := range d.active {
+		hops := d.bfs(s)
+		for _, u := range d.active {
+			if u != s && hops[u] > 0 {
+				pairs = append(pairs, [2]float64{dist(d.pos[s], d.pos[u]), float64(hops[u])})
+			}
+		}
+	}
+	stressAt := func(a float64) float64 {
+		sum := 0.0
+		for _, p := range pairs {
+			sum += (a*p[0] - p[1]) * (a*p[0] - p[1]) / (p[1] * p[1])
+		}
+		return sum / float64(len(pairs))
+	}
+	best := math.Inf(1)
+	for a := 0.01; a < 20; a *= 1.001 {
+		best = math.Min(best, stressAt(a))
+	}
+	got := d.stress(d.active)
+	if got > best+1e-12 || best-got > 1e-4 {
+		t.Errorf("closed form stress %g, best sampled scale gives %g", got, best)
+	}
+}
+
+func TestKthSmallest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		xs := make([]float64, 1+rng.Intn(20))
+		for i := range xs {
+			// few distinct values, so most arrays have ties
+			xs[i] = float64(rng.Intn(5))
+		}
+		sorted := append([]float64(nil), xs...)
+		sort.Float64s(sorted)
+		for k := range xs {
+			if got := kthSmallest(append([]float64(nil), xs...), k); got != sorted[k] {
+				t.Fatalf("kthSmallest(%v, %d) = %g, want %g", xs, k, got, sorted[k])
+			}
+		}
+	}
+}
+
+func TestCrossings(t *testing.T) {
+	// K4 on a square crosses once, in the diagonals
+	k4 := drawn([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		[2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}, [2]int{3, 0}, [2]int{0, 2}, [2]int{1, 3})
+	if count, sampled := k4.crossings(DefaultOptions.CrossingPairs, nil); count != 1 || sampled {
+		t.Errorf("K4 on a square: %g crossings (sampled %v), want 1", count, sampled)
+	}
+
+	d := randomDrawing(60, 150, 2)
+	exact, sampled := d.crossings(0, nil)
+	if sampled {
+		t.Fatal("no limit on the pairs still sampled them")
+	}
+	pairs := len(d.edges) * (len(d.edges) - 1) / 2
+	if count, sampled := d.crossings(pairs, nil); count != exact || sampled {
+		t.Errorf("a limit of all %d pairs gives %g crossings (sampled %v), want %g", pairs, count, sampled, exact)
+	}
+	estimate, sampled := d.crossings(pairs/4, rand.New(rand.NewSource(1)))
+	if !sampled {
+		t.Fatal("a quarter of the pairs was not sampled")
+	}
+	if math.Abs(estimate-exact) > 0.15*exact {
+		t.Errorf("sampled estimate %g is far from the %g crossings", estimate, exact)
+	}
+}
+
+func TestAngularResolution(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        *drawing
+		ratio    float64
+		minAngle float64
+	}{
+		{"cross", drawn([][]float64{{0, 0}, {1, 0}, {0, 1}, {-1, 0}, {0, -1}},
+			[2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}, [2]int{0, 4}), 1, 90},
+		// 90 degrees of the ideal 120 at the center, the leaves do not count
+		{"half star", drawn([][]float64{{0, 0}, {1, 0}, {0, 1}, {-1, 0}},
+			[2]int{0, 1}, [2]int{0, 2}, [2]int{0, 3}), 0.75, 90},
+		{"straight path", drawn([][]float64{{0, 0}, {1, 0}, {2, 0}}, [2]int{0, 1}, [2]int{1, 2}), 1, 180},
+		// a right angle at each inner vertex of the bent path
+		{"bent path", drawn([][]float64{{0, 0}, {1, 0}, {1, 1}, {2, 1}},
+			[2]int{0, 1}, [2]int{1, 2}, [2]int{2, 3}), 0.5, 90},
+		{"single edge", drawn([][]float64{{0, 0}, {1, 0}}, [2]int{0, 1}), 1, 0},
+	}
+	for _, tt := range tests {
+		ratio, minAngle := tt.d.angularResolution()
+		if math.Abs(ratio-tt.ratio) > 1e-9 || math.Abs(minAngle-tt.minAngle) > 1e-9 {
+			t.Errorf("%s: resolution %g with smallest angle %g, want %g and %g", tt.name, ratio, minAngle, tt.ratio, tt.minAngle)
+		}
+	}
+}
+
+func TestComputeSeed(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	g := &graph.Graph{N: 200}
+	e := embedding.New(200, 2)
+	for v := 1; v < 200; v++ {
+		g.Edges = append(g.Edges, graph.Edge{U: rng.Intn(v), V: v, W: 1})
+		e.Coords[v][0], e.Coords[v][1] = rng.Float64(), rng.Float64()
+	}
+	opts := Options{Sources: 20, CrossingPairs: 1000, Seed: 7}
+	a, err := Compute(g, e, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Compute(g, e, opts)
+	if a != b {
+		t.Errorf("equal seeds give different metrics:\n%+v\n%+v", a, b)
+	}
+	if a.StressSources != 20 || !a.CrossingsSampled {
+		t.Errorf("expected 20 sources and sampled crossings, got %+v", a)
+	}
+}
@@ -0,0 +1,191 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"sort"
+)
+
+// finalHold is how long animations show their last frame, in milliseconds,
+// so the finished layout can be seen before the loop starts over
+const finalHold = 2000
+
+// WriteGIF writes the frames as a looping animated GIF showing every frame for
+// delay milliseconds. GIF frames have at most 256 colors: the palette holds the
+// most frequent colors of all frames, and other colors take the nearest one.
+func WriteGIF(w io.Writer, frames []*image.RGBA, delay int) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to animate")
+	}
+	pal := framePalette(frames)
+	anim := &gif.GIF{}
+	index := make(map[color.RGBA]uint8)
+	for i, frame := range frames {
+		b := frame.Bounds()
+		img := image.NewPaletted(b, pal)
+		last, k := pal[0].(color.RGBA), uint8(0)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				// most pixels repeat the one before, usually the background
+				if c := frame.RGBAAt(x, y); c != last {
+					var ok bool
+					if k, ok = index[c]; !ok {
+						k = uint8(pal.Index(c))
+						index[c] = k
+					}
+					last = c
+				}
+				img.SetColorIndex(x, y, k)
+			}
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, frameDelay(i, len(frames), delay)/10)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// paletteSample is the distance between the pixels counted for the palette
+const paletteSample = 3
+
+// framePalette returns the up to 256 most frequent colors of the frames
+func framePalette(frames []*image.RGBA) color.Palette {
+	counts := make(map[color.RGBA]int)
+	for _, frame := range frames {
+		// a sample of the pixels finds the colors that matter
+		for i := 0; i+3 < len(frame.Pix); i += 4 * paletteSample {
+			counts[color.RGBA{frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2], 255}]++
+		}
+	}
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+		return Hex(colors[i]) < Hex(colors[j])
+	})
+	pal := make(color.Palette, 0, 256)
+	for _, c := range colors[:min(len(colors), 256)] {
+		pal = append(pal, c)
+	}
+	return pal
+}
+
+// frameDelay returns how long frame i of n is shown, in milliseconds
+func frameDelay(i, n, delay int) int {
+	if i == n-1 {
+		return max(delay, finalHold)
+	}
+	return delay
+}
+
+// WriteAPNG writes the frames as a looping animated PNG showing every frame
+// for delay milliseconds. Unlike GIF it keeps all colors. All frames must have
+// the size of the first one and be either all opaque or all translucent.
+func WriteAPNG(w io.Writer, frames []*image.RGBA, delay int) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to animate")
+	}
+	size := frames[0].Bounds().Size()
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\x89PNG\r\n\x1a\n")
+	seq := uint32(0)
+	var header []byte
+	for i, frame := range frames {
+		if frame.Bounds().Size() != size {
+			return errors.New("animation frames differ in size")
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return err
+		}
+		chunks, err := pngChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		var ihdr []byte
+		for _, c := range chunks {
+			if c.kind == "IHDR" {
+				ihdr = c.data
+			}
+		}
+		if i > 0 && !bytes.Equal(ihdr, header) {
+			// the encoder drops the alpha channel of opaque frames, but the
+			// frame data has to match the header of the first frame
+			return errors.New("animation frames differ in color type")
+		}
+		if i == 0 {
+			// the header of the first frame describes the whole animation
+			header = ihdr
+			writeChunk(bw, "IHDR", header)
+			actl := binary.BigEndian.AppendUint32(nil, uint32(len(frames)))
+			writeChunk(bw, "acTL", binary.BigEndian.AppendUint32(actl, 0))
+		}
+		fctl := binary.BigEndian.AppendUint32(nil, seq)
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(size.X))
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(size.Y))
+		fctl = binary.BigEndian.AppendUint64(fctl, 0) // x and y offset
+		fctl = binary.BigEndian.AppendUint16(fctl, uint16(frameDelay(i, len(frames), delay)))
+		fctl = binary.BigEndian.AppendUint16(fctl, 1000)
+		fctl = append(fctl, 0, 0) // no disposal, frames replace the canvas
+		writeChunk(bw, "fcTL", fctl)
+		seq++
+		for _, c := range chunks {
+			if c.kind != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				writeChunk(bw, "IDAT", c.data)
+			} else {
+				writeChunk(bw, "fdAT", append(binary.BigEndian.AppendUint32(nil, seq), c.data...))
+				seq++
+			}
+		}
+	}
+	writeChunk(bw, "IEND", nil)
+	return bw.Flush()
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// pngChunks splits an encoded PNG file into its chunks
+func pngChunks(b []byte) ([]pngChunk, error) {
+	if len(b) < 8 {
+		return nil, errors.New("invalid PNG data")
+	}
+	var chunks []pngChunk
+	for b = b[8:]; len(b) >= 12; {
+		n := int(binary.BigEndian.Uint32(b))
+		if 12+n > len(b) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+		chunks = append(chunks, pngChunk{string(b[4:8]), b[8 : 8+n]})
+		b = b[12+n:]
+	}
+	return chunks, nil
+}
+
+func writeChunk(w *bufio.Writer, kind string, data []byte) {
+	var head [8]byte
+	binary.BigEndian.PutUint32(head[:4], uint32(len(data)))
+	copy(head[4:], kind)
+	w.Write(head[:])
+	w.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
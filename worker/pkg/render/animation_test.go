@@ -0,0 +1,93 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// solidFrame returns a w x h frame filled with c
+func solidFrame(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestWriteAPNG(t *testing.T) {
+	frames := []*image.RGBA{
+		solidFrame(8, 6, color.RGBA{255, 0, 0, 255}),
+		solidFrame(8, 6, color.RGBA{0, 255, 0, 255}),
+		solidFrame(8, 6, color.RGBA{0, 0, 255, 255}),
+	}
+	var buf bytes.Buffer
+	if err := WriteAPNG(&buf, frames, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// decoders without APNG support show the first frame
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 6 {
+		t.Fatalf("decoded a %v image", b)
+	}
+	if r, g, b, _ := img.At(3, 3).RGBA(); r != 0xffff || g != 0 || b != 0 {
+		t.Errorf("first frame pixel is %v, want red", img.At(3, 3))
+	}
+
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	var seqs []uint32
+	for _, c := range chunks {
+		kinds = append(kinds, c.kind)
+		switch c.kind {
+		case "acTL":
+			if n := binary.BigEndian.Uint32(c.data); n != uint32(len(frames)) {
+				t.Errorf("acTL announces %d frames, want %d", n, len(frames))
+			}
+			if plays := binary.BigEndian.Uint32(c.data[4:]); plays != 0 {
+				t.Errorf("acTL plays %d times, want a loop", plays)
+			}
+		case "fcTL", "fdAT":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+		}
+	}
+	if kinds[0] != "IHDR" || kinds[1] != "acTL" || kinds[len(kinds)-1] != "IEND" {
+		t.Errorf("chunks %v", kinds)
+	}
+	for i, seq := range seqs {
+		if seq != uint32(i) {
+			t.Fatalf("sequence numbers %v are not consecutive from 0", seqs)
+		}
+	}
+	// one fcTL per frame and at least one fdAT for each frame after the first
+	if len(seqs) < 2*len(frames)-1 {
+		t.Errorf("%d fcTL and fdAT chunks for %d frames", len(seqs), len(frames))
+	}
+}
+
+func TestWriteAPNGMismatch(t *testing.T) {
+	opaque := solidFrame(4, 4, color.RGBA{255, 0, 0, 255})
+	tests := []struct {
+		name   string
+		frames []*image.RGBA
+	}{
+		{"no frames", nil},
+		{"sizes", []*image.RGBA{opaque, solidFrame(5, 4, color.RGBA{255, 0, 0, 255})}},
+		{"color types", []*image.RGBA{opaque, solidFrame(4, 4, color.RGBA{128, 0, 0, 128})}},
+	}
+	for _, tt := range tests {
+		if err := WriteAPNG(&bytes.Buffer{}, tt.frames, 100); err == nil {
+			t.Errorf("%s: frames were accepted", tt.name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Colormap turns values into colors. A continuous colormap interpolates
+// between its stops; a categorical one gives every distinct value its own
+// palette color.
+type Colormap struct {
+	Name        string
+	Categorical bool
+	stops       []color.RGBA
+}
+
+// Names of the available colormaps
+const (
+	ColormapViridis     = "viridis"
+	ColormapPlasma      = "plasma"
+	ColormapCategorical = "categorical"
+)
+
+// colormaps holds the stops of the continuous colormaps, sampled from matplotlib
+var colormaps = map[string][]color.RGBA{
+	ColormapViridis: {
+		{68, 1, 84, 255}, {71, 44, 122, 255}, {59, 81, 139, 255}, {44, 113, 142, 255}, {33, 144, 141, 255},
+		{39, 173, 129, 255}, {92, 200, 99, 255}, {170, 220, 50, 255}, {253, 231, 37, 255},
+	},
+	ColormapPlasma: {
+		{13, 8, 135, 255}, {76, 2, 161, 255}, {126, 3, 168, 255}, {169, 35, 149, 255}, {204, 71, 120, 255},
+		{229, 107, 93, 255}, {248, 148, 65, 255}, {253, 195, 40, 255}, {240, 249, 33, 255},
+	},
+}
+
+// ParseColormap returns the colormap with the given name
+func ParseColormap(name string) (Colormap, error) {
+	if name == ColormapCategorical {
+		return Colormap{Name: name, Categorical: true, stops: palette}, nil
+	}
+	stops, ok := colormaps[name]
+	if !ok {
+		return Colormap{}, fmt.Errorf("unknown colormap %q, expected viridis, plasma or categorical", name)
+	}
+	return Colormap{Name: name, stops: stops}, nil
+}
+
+// At returns the color at t in [0, 1]; a categorical colormap spreads its
+// palette over the range
+func (c Colormap) At(t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	if c.Categorical {
+		return c.stops[min(int(t*float64(len(c.stops))), len(c.stops)-1)]
+	}
+	pos := t * float64(len(c.stops)-1)
+	i := min(int(pos), len(c.stops)-2)
+	f := pos - float64(i)
+	a, b := c.stops[i], c.stops[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x)*(1-f) + float64(y)*f))
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// Category returns the color of category k out of count: the palette color of
+// a categorical colormap, or evenly spaced colors of a continuous one
+func (c Colormap) Category(k, count int) color.RGBA {
+	if c.Categorical {
+		return c.stops[k%len(c.stops)]
+	}
+	if count < 2 {
+		return c.At(0.5)
+	}
+	return c.At(float64(k) / float64(count-1))
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Scales of a density drawing, mapping accumulated density to colormap position
+const (
+	ScaleLinear = "linear"
+	ScaleLog    = "log"
+	ScaleEqHist = "eq_hist" // histogram equalization: every color covers about as many pixels
+)
+
+// Density holds the options of a density drawing
+type Density struct {
+	Scale    string
+	Colormap Colormap
+	Legend   bool // add a color ramp from the lowest to the highest density
+}
+
+// densityGrid accumulates how much of the drawing falls on each pixel
+type densityGrid struct {
+	w, h   int
+	values []float32
+}
+
+func (d densityGrid) add(x, y int, v float64) {
+	if x >= 0 && y >= 0 && x < d.w && y < d.h {
+		d.values[y*d.w+x] += float32(v)
+	}
+}
+
+// RasterizeDensity draws the graph as a density map instead of lines, for
+// graphs with so many edges that a line drawing saturates. Every edge adds one
+// to the pixels it crosses, weighted by coverage when the style asks for
+// antialiasing, and every vertex with edges adds one to its pixel. Pixels
+// without density keep the background; the others take the colormap color of
+// their density on the given scale. The viewport is the one of Rasterize.
+func RasterizeDensity(g *graph.Graph, e *embedding.Embedding, style Style, d Density) (*image.RGBA, error) {
+	if style.Width < 1 || style.Height < 1 {
+		return nil, fmt.Errorf("invalid image size %dx%d", style.Width, style.Height)
+	}
+	grid := densityGrid{w: style.Width, h: style.Height, values: make([]float32, style.Width*style.Height)}
+	vp := newViewport(e, style.Width, style.Height, style.margin())
+	n := e.Len()
+	connected := make([]bool, n)
+	for _, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			continue
+		}
+		connected[edge.U], connected[edge.V] = true, true
+		x0, y0 := vp.point(e.Coords[edge.U])
+		x1, y1 := vp.point(e.Coords[edge.V])
+		if style.Antialias {
+			walkLineAA(x0, y0, x1, y1, func(x, y int, coverage float64) {
+				// coverage a line drawing would round away adds nothing
+				if alpha8(coverage) > 0 {
+					grid.add(x, y, coverage)
+				}
+			})
+		} else {
+			walkLine(x0, y0, x1, y1, grid.w, grid.h, func(x, y int) { grid.add(x, y, 1) })
+		}
+	}
+	for v, p := range e.Coords {
+		if connected[v] {
+			x, y := vp.point(p)
+			grid.add(clamp(int(x), grid.w), clamp(int(y), grid.h), 1)
+		}
+	}
+
+	scale, err := densityScale(grid.values, d.Scale)
+	if err != nil {
+		return nil, err
+	}
+	if d.Legend {
+		lo, hi := float32(math.Inf(1)), float32(0)
+		for _, v := range grid.values {
+			if v > 0 {
+				lo, hi = min(lo, v), max(hi, v)
+			}
+		}
+		if hi > 0 {
+			style.Legends = append(style.Legends, Legend{Title: "density (" + d.Scale + ")", Ramp: &d.Colormap, Min: float64(lo), Max: float64(hi)})
+		}
+	}
+	r := raster{image.NewRGBA(image.Rect(0, 0, style.Width, style.Height))}
+	bg := style.Background
+	for i, v := range grid.values {
+		c := bg
+		if v > 0 {
+			c = d.Colormap.At(scale(v))
+		}
+		p := r.img.Pix[4*i : 4*i+4 : 4*i+4]
+		p[0], p[1], p[2], p[3] = c.R, c.G, c.B, 255
+	}
+	drawLegends(r, style)
+	return r.img, nil
+}
+
+// WriteDensityPNG draws the graph as RasterizeDensity does and encodes it as PNG
+func WriteDensityPNG(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style, d Density) error {
+	img, err := RasterizeDensity(g, e, style, d)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// densityScale returns the function mapping a positive density to [0, 1]
+func densityScale(values []float32, scale string) (func(float32) float64, error) {
+	maxValue := float32(0)
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+	switch scale {
+	case ScaleLinear:
+		return func(v float32) float64 { return float64(v / maxValue) }, nil
+	case ScaleLog:
+		top := math.Log1p(float64(maxValue))
+		return func(v float32) float64 { return math.Log1p(float64(v)) / top }, nil
+	case ScaleEqHist:
+		// the position of a density is the share of drawn pixels with at most that density
+		var sorted []float32
+		for _, v := range values {
+			if v > 0 {
+				sorted = append(sorted, v)
+			}
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		return func(v float32) float64 {
+			rank := sort.Search(len(sorted), func(i int) bool { return sorted[i] > v })
+			return float64(rank) / float64(len(sorted))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown density scale %q, expected linear, log or eq_hist", scale)
+	}
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Colors of the diff drawing: kept edges use the style's edge color
+var (
+	AddedColor   = CategoryColor(2) // green
+	RemovedColor = CategoryColor(3) // red
+)
+
+// WriteDiffSVG draws how g changed from base on layouts aligned to each other:
+// edges only in g in AddedColor and edges of both in the edge color of the
+// style, both at the coordinates of e, and edges only in base in RemovedColor
+// at the coordinates of baseEmb, where their vertices were drawn before.
+func WriteDiffSVG(w io.Writer, base *graph.Graph, baseEmb *embedding.Embedding, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	baseEdges, edges := base.EdgeSet(), g.EdgeSet()
+	var kept, added, removed [][2]int
+	for key := range edges {
+		if key[1] >= e.Len() {
+			continue
+		}
+		if _, ok := baseEdges[key]; ok {
+			kept = append(kept, key)
+		} else {
+			added = append(added, key)
+		}
+	}
+	for key := range baseEdges {
+		if _, ok := edges[key]; !ok && key[1] < baseEmb.Len() {
+			removed = append(removed, key)
+		}
+	}
+	// map iteration order is random; sorting keeps the output reproducible
+	for _, list := range [][][2]int{kept, added, removed} {
+		graph.SortEdges(list)
+	}
+
+	var bounds embedding.Embedding
+	for _, key := range append(kept, added...) {
+		bounds.Coords = append(bounds.Coords, e.Coords[key[0]], e.Coords[key[1]])
+	}
+	for _, key := range removed {
+		bounds.Coords = append(bounds.Coords, baseEmb.Coords[key[0]], baseEmb.Coords[key[1]])
+	}
+	if len(bounds.Coords) == 0 {
+		return fmt.Errorf("no edges to draw")
+	}
+	bounds.Dims = len(bounds.Coords[0])
+	vp := newViewport(&bounds, style.Width, style.Height, style.margin())
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		style.Width, style.Height, style.Width, style.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", Hex(style.Background))
+	path := func(list [][2]int, coords *embedding.Embedding, stroke, opacity string) {
+		if len(list) == 0 {
+			return
+		}
+		var d strings.Builder
+		for _, key := range list {
+			x0, y0 := vp.point(coords.Coords[key[0]])
+			x1, y1 := vp.point(coords.Coords[key[1]])
+			fmt.Fprintf(&d, "M%.2f %.2fL%.2f %.2f", x0, y0, x1, y1)
+		}
+		fmt.Fprintf(bw, `<path fill="none" stroke="%s" stroke-opacity="%s" stroke-width="1" d="%s"/>`+"\n", stroke, opacity, d.String())
+	}
+	// changes are drawn on top of the kept edges, which are dimmed
+	path(kept, e, Hex(style.EdgeColor), "0.35")
+	path(removed, baseEmb, Hex(RemovedColor), "0.9")
+	path(added, e, Hex(AddedColor), "0.9")
+	legend := []struct {
+		label string
+		color string
+	}{
+		{fmt.Sprintf("kept (%d)", len(kept)), Hex(style.EdgeColor)},
+		{fmt.Sprintf("added (%d)", len(added)), Hex(AddedColor)},
+		{fmt.Sprintf("removed (%d)", len(removed)), Hex(RemovedColor)},
+	}
+	for i, l := range legend {
+		y := 20 + 18*i
+		fmt.Fprintf(bw, `<rect x="10" y="%d" width="12" height="12" fill="%s"/>`+"\n", y-11, l.color)
+		fmt.Fprintf(bw, `<text x="28" y="%d" font-family="sans-serif" font-size="13">%s</text>`+"\n", y, l.label)
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"image/color"
+	"strings"
+	"unicode"
+)
+
+// glyphs is a 5x7 bitmap font for the legends of raster images, rows from
+// top to bottom. Lower case letters use the upper case glyphs and characters
+// without a glyph are drawn as '?'.
+var glyphs = map[rune]string{
+	'0': "01110 10001 10011 10101 11001 10001 01110",
+	'1': "00100 01100 00100 00100 00100 00100 01110",
+	'2': "01110 10001 00001 00010 00100 01000 11111",
+	'3': "11111 00010 00100 00010 00001 10001 01110",
+	'4': "00010 00110 01010 10010 11111 00010 00010",
+	'5': "11111 10000 11110 00001 00001 10001 01110",
+	'6': "00110 01000 10000 11110 10001 10001 01110",
+	'7': "11111 00001 00010 00100 01000 01000 01000",
+	'8': "01110 10001 10001 01110 10001 10001 01110",
+	'9': "01110 10001 10001 01111 00001 00010 01100",
+	'A': "01110 10001 10001 11111 10001 10001 10001",
+	'B': "11110 10001 10001 11110 10001 10001 11110",
+	'C': "01110 10001 10000 10000 10000 10001 01110",
+	'D': "11100 10010 10001 10001 10001 10010 11100",
+	'E': "11111 10000 10000 11110 10000 10000 11111",
+	'F': "11111 10000 10000 11110 10000 10000 10000",
+	'G': "01110 10001 10000 10111 10001 10001 01111",
+	'H': "10001 10001 10001 11111 10001 10001 10001",
+	'I': "01110 00100 00100 00100 00100 00100 01110",
+	'J': "00111 00010 00010 00010 00010 10010 01100",
+	'K': "10001 10010 10100 11000 10100 10010 10001",
+	'L': "10000 10000 10000 10000 10000 10000 11111",
+	'M': "10001 11011 10101 10101 10001 10001 10001",
+	'N': "10001 10001 11001 10101 10011 10001 10001",
+	'O': "01110 10001 10001 10001 10001 10001 01110",
+	'P': "11110 10001 10001 11110 10000 10000 10000",
+	'Q': "01110 10001 10001 10001 10101 10010 01101",
+	'R': "11110 10001 10001 11110 10100 10010 10001",
+	'S': "01111 10000 10000 01110 00001 00001 11110",
+	'T': "11111 00100 00100 00100 00100 00100 00100",
+	'U': "10001 10001 10001 10001 10001 10001 01110",
+	'V': "10001 10001 10001 10001 10001 01010 00100",
+	'W': "10001 10001 10001 10101 10101 10101 01010",
+	'X': "10001 10001 01010 00100 01010 10001 10001",
+	'Y': "10001 10001 01010 00100 00100 00100 00100",
+	'Z': "11111 00001 00010 00100 01000 10000 11111",
+	'.': "00000 00000 00000 00000 00000 01100 01100",
+	',': "00000 00000 00000 00000 01100 00100 01000",
+	':': "00000 01100 01100 00000 01100 01100 00000",
+	'-': "00000 00000 00000 11111 00000 00000 00000",
+	'+': "00000 00100 00100 11111 00100 00100 00000",
+	'_': "00000 00000 00000 00000 00000 00000 11111",
+	'/': "00000 00001 00010 00100 01000 10000 00000",
+	'(': "00010 00100 01000 01000 01000 00100 00010",
+	')': "01000 00100 00010 00010 00010 00100 01000",
+	'?': "01110 10001 00001 00010 00100 00000 00100",
+	' ': "00000 00000 00000 00000 00000 00000 00000",
+}
+
+// glyphAdvance is the width of a character including the gap after it
+const glyphAdvance = 6
+
+// text draws s with the bitmap font, the bottom row of the glyphs just above y
+func (r raster) text(x, y float64, s string, c color.RGBA) {
+	x0, y0 := int(x), int(y)-7
+	for i, ch := range []rune(s) {
+		pattern, ok := glyphs[unicode.ToUpper(ch)]
+		if !ok {
+			pattern = glyphs['?']
+		}
+		for row, bits := range strings.Fields(pattern) {
+			for col, bit := range bits {
+				if bit == '1' {
+					r.blend(x0+i*glyphAdvance+col, y0+row, c, 255)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package render
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image/color"
+	"io"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// glTF constants of the buffers written by WriteGLB
+const (
+	glbMagic       = 0x46546c67 // "glTF"
+	glbChunkJSON   = 0x4e4f534a
+	glbChunkBIN    = 0x004e4942
+	gltfFloat      = 5126
+	gltfUint16     = 5123
+	gltfUint32     = 5125
+	gltfArray      = 34962 // vertex attributes
+	gltfElements   = 34963 // indices
+	gltfModePoints = 0
+	gltfModeLines  = 1
+	gltfUnlit      = "KHR_materials_unlit"
+)
+
+type gltfDoc struct {
+	Asset          map[string]string `json:"asset"`
+	ExtensionsUsed []string          `json:"extensionsUsed"`
+	Scene          int               `json:"scene"`
+	Scenes         []gltfScene       `json:"scenes"`
+	Nodes          []gltfNode        `json:"nodes"`
+	Meshes         []gltfMesh        `json:"meshes"`
+	Materials      []gltfMaterial    `json:"materials"`
+	Accessors      []gltfAccessor    `json:"accessors"`
+	BufferViews    []gltfBufferView  `json:"bufferViews"`
+	Buffers        []gltfBuffer      `json:"buffers"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name string `json:"name"`
+	Mesh int    `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   int            `json:"material"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfMaterial struct {
+	Name       string                    `json:"name"`
+	PBR        gltfPBR                   `json:"pbrMetallicRoughness"`
+	AlphaMode  string                    `json:"alphaMode,omitempty"`
+	Extensions map[string]map[string]any `json:"extensions"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// gltfBuilder lays out the binary chunk of a GLB file, one buffer view per accessor
+type gltfBuilder struct {
+	doc gltfDoc
+	bin []byte
+}
+
+// add appends data as a new buffer view and returns the index of its accessor
+func (b *gltfBuilder) add(data []byte, target int, acc gltfAccessor) int {
+	// every element type written here is a multiple of 4 bytes, keeping views aligned
+	b.doc.BufferViews = append(b.doc.BufferViews, gltfBufferView{ByteOffset: len(b.bin), ByteLength: len(data), Target: target})
+	b.bin = append(b.bin, data...)
+	acc.BufferView = len(b.doc.BufferViews) - 1
+	b.doc.Accessors = append(b.doc.Accessors, acc)
+	return len(b.doc.Accessors) - 1
+}
+
+// positions adds a VEC3 position accessor with the bounds glTF requires
+func (b *gltfBuilder) positions(ps [][3]float32) int {
+	lo := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+	hi := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	data := make([]byte, 0, 12*len(ps))
+	for _, p := range ps {
+		for d, x := range p {
+			lo[d], hi[d] = min(lo[d], x), max(hi[d], x)
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(x))
+		}
+	}
+	return b.add(data, gltfArray, gltfAccessor{ComponentType: gltfFloat, Count: len(ps), Type: "VEC3", Min: lo, Max: hi})
+}
+
+// colors adds a COLOR_0 accessor; glTF vertex colors are linear, so they are
+// stored as 16 bit values to keep the dark shades apart
+func (b *gltfBuilder) colors(cs []color.RGBA) int {
+	data := make([]byte, 0, 8*len(cs))
+	for _, c := range cs {
+		for _, x := range [3]uint8{c.R, c.G, c.B} {
+			data = binary.LittleEndian.AppendUint16(data, uint16(math.Round(linear(x)*65535)))
+		}
+		data = binary.LittleEndian.AppendUint16(data, 65535)
+	}
+	return b.add(data, gltfArray, gltfAccessor{ComponentType: gltfUint16, Normalized: true, Count: len(cs), Type: "VEC4"})
+}
+
+// indices adds an index accessor
+func (b *gltfBuilder) indices(is []uint32) int {
+	data := make([]byte, 0, 4*len(is))
+	for _, i := range is {
+		data = binary.LittleEndian.AppendUint32(data, i)
+	}
+	return b.add(data, gltfElements, gltfAccessor{ComponentType: gltfUint32, Count: len(is), Type: "SCALAR"})
+}
+
+// linear converts an sRGB channel to linear light
+func linear(c uint8) float64 {
+	x := float64(c) / 255
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+func unlitMaterial(name string, c color.RGBA, alpha float64) gltfMaterial {
+	mat := gltfMaterial{
+		Name: name,
+		PBR: gltfPBR{
+			BaseColorFactor: [4]float64{linear(c.R), linear(c.G), linear(c.B), alpha},
+			RoughnessFactor: 1,
+		},
+		Extensions: map[string]map[string]any{gltfUnlit: {}},
+	}
+	if alpha < 1 {
+		mat.AlphaMode = "BLEND"
+	}
+	return mat
+}
+
+// WriteGLB writes the embedded graph as binary glTF 2.0 for web viewers: one
+// mesh with a line primitive for the edges and a point primitive for the
+// vertices with edges, colored per vertex. Edges of one color share the vertex
+// positions and take the color from their material; edges styled one by one
+// get their own copies of the end points to carry the colors. Materials are
+// unlit, as the drawings are.
+func WriteGLB(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	m, err := buildModel(g, e, style)
+	if err != nil {
+		return err
+	}
+	if len(m.edges) == 0 {
+		return errors.New("graph has no edges to export")
+	}
+	b := &gltfBuilder{doc: gltfDoc{
+		Asset:          map[string]string{"version": "2.0", "generator": "spectra"},
+		ExtensionsUsed: []string{gltfUnlit},
+		Scenes:         []gltfScene{{Nodes: []int{0}}},
+		Nodes:          []gltfNode{{Name: "graph", Mesh: 0}},
+	}}
+	positions := b.positions(m.positions)
+	colors := b.colors(m.colors)
+
+	var lines gltfPrimitive
+	if m.edgeColors == nil {
+		pairs := make([]uint32, 0, 2*len(m.edges))
+		for _, edge := range m.edges {
+			pairs = append(pairs, edge[0], edge[1])
+		}
+		idx := b.indices(pairs)
+		lines = gltfPrimitive{Attributes: map[string]int{"POSITION": positions}, Indices: &idx}
+		b.doc.Materials = append(b.doc.Materials, unlitMaterial("edges", m.edgeColor, m.edgeAlpha))
+	} else {
+		ends := make([][3]float32, 0, 2*len(m.edges))
+		endColors := make([]color.RGBA, 0, 2*len(m.edges))
+		for i, edge := range m.edges {
+			ends = append(ends, m.positions[edge[0]], m.positions[edge[1]])
+			endColors = append(endColors, m.edgeColors[i], m.edgeColors[i])
+		}
+		lines = gltfPrimitive{Attributes: map[string]int{"POSITION": b.positions(ends), "COLOR_0": b.colors(endColors)}}
+		b.doc.Materials = append(b.doc.Materials, unlitMaterial("edges", color.RGBA{255, 255, 255, 255}, m.edgeAlpha))
+	}
+	lines.Mode = gltfModeLines
+
+	points := b.indices(m.points)
+	b.doc.Materials = append(b.doc.Materials, unlitMaterial("vertices", color.RGBA{255, 255, 255, 255}, 1))
+	b.doc.Meshes = []gltfMesh{{Name: "graph", Primitives: []gltfPrimitive{
+		lines,
+		{Attributes: map[string]int{"POSITION": positions, "COLOR_0": colors}, Indices: &points, Material: 1, Mode: gltfModePoints},
+	}}}
+	b.doc.Buffers = []gltfBuffer{{ByteLength: len(b.bin)}}
+
+	doc, err := json.Marshal(b.doc)
+	if err != nil {
+		return err
+	}
+	// chunks are padded to 4 bytes, JSON with spaces and binary data with zeros
+	for len(doc)%4 != 0 {
+		doc = append(doc, ' ')
+	}
+	for len(b.bin)%4 != 0 {
+		b.bin = append(b.bin, 0)
+	}
+	header := make([]byte, 0, 20)
+	header = binary.LittleEndian.AppendUint32(header, glbMagic)
+	header = binary.LittleEndian.AppendUint32(header, 2)
+	header = binary.LittleEndian.AppendUint32(header, uint32(12+8+len(doc)+8+len(b.bin)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(doc)))
+	header = binary.LittleEndian.AppendUint32(header, glbChunkJSON)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(doc); err != nil {
+		return err
+	}
+	chunk := binary.LittleEndian.AppendUint32(nil, uint32(len(b.bin)))
+	chunk = binary.LittleEndian.AppendUint32(chunk, glbChunkBIN)
+	if _, err := w.Write(chunk); err != nil {
+		return err
+	}
+	_, err = w.Write(b.bin)
+	return err
+}
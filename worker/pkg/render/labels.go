@@ -0,0 +1,131 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// maxLabels bounds the labels of a drawing, since every label is checked
+// against the ones placed before it
+const maxLabels = 1000
+
+// Label layout in pixels: labels are set in the legend font on a box of the
+// background color, labelGap away from their vertex
+const (
+	labelHeight = legendFont + 2
+	labelGap    = 3
+	labelDot    = 2
+)
+
+// SelectLabels returns the vertices to label for spec, most important first.
+// "top:K" picks the K vertices of highest degree and "all" every vertex by
+// degree, up to maxLabels; otherwise spec is a comma separated list of vertex
+// ids, or of row and column names such as r12 and c7 in bipartite graphs.
+// Vertices without edges are not drawn and cannot be labeled.
+func SelectLabels(g *graph.Graph, e *embedding.Embedding, spec string, sides graph.Sides) ([]int, error) {
+	deg := Degrees(g, e)
+	if spec == "all" || strings.HasPrefix(spec, "top:") {
+		k := maxLabels
+		if spec != "all" {
+			var err error
+			k, err = strconv.Atoi(strings.TrimPrefix(spec, "top:"))
+			if err != nil || k < 1 || k > maxLabels {
+				return nil, fmt.Errorf("invalid label count in %q, expected 1 to %d", spec, maxLabels)
+			}
+		}
+		var vertices []int
+		for v, d := range deg {
+			if !math.IsNaN(d) {
+				vertices = append(vertices, v)
+			}
+		}
+		sort.SliceStable(vertices, func(a, b int) bool { return deg[vertices[a]] > deg[vertices[b]] })
+		return vertices[:min(k, len(vertices))], nil
+	}
+	var vertices []int
+	seen := make(map[int]bool)
+	for _, s := range strings.Split(spec, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		v, err := sides.Vertex(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vertex %q in labels, expected top:K, all or vertex ids", s)
+		}
+		if v >= len(deg) || math.IsNaN(deg[v]) {
+			return nil, fmt.Errorf("vertex %s is not in the drawing", s)
+		}
+		if !seen[v] {
+			seen[v] = true
+			vertices = append(vertices, v)
+		}
+	}
+	if len(vertices) > maxLabels {
+		return nil, fmt.Errorf("at most %d vertices can be labeled, got %d", maxLabels, len(vertices))
+	}
+	return vertices, nil
+}
+
+// labelBox is the area taken by a placed label, in image coordinates
+type labelBox struct {
+	x0, y0, x1, y1 float64
+}
+
+func (b labelBox) overlaps(o labelBox) bool {
+	return b.x0 < o.x1 && o.x0 < b.x1 && b.y0 < o.y1 && o.y0 < b.y1
+}
+
+// drawLabels names the labeled vertices of the style by their id, or by their
+// row or column in a bipartite graph. Each label
+// takes the first corner around its vertex, clockwise from the upper right,
+// where it stays inside the image and clear of the labels placed before it;
+// labels without such a corner are left out. When vertices are not drawn,
+// labeled ones are marked with a dot.
+func drawLabels(c canvas, style Style, e *embedding.Embedding, vp viewport) {
+	ink := color.RGBA{0, 0, 0, 255}
+	var placed []labelBox
+	for _, v := range style.Labels {
+		if v < 0 || v >= e.Len() {
+			continue
+		}
+		x, y := vp.point(e.Coords[v])
+		s := style.Sides.Name(v)
+		w, h := textWidth(s)+4, float64(labelHeight)
+		r := float64(labelGap)
+		if style.drawsVertices() {
+			r += style.vertexRadius(v)
+		} else {
+			r += labelDot
+		}
+		for _, corner := range [][2]float64{{r, -r - h}, {r, r}, {-r - w, r}, {-r - w, -r - h}} {
+			b := labelBox{x + corner[0], y + corner[1], x + corner[0] + w, y + corner[1] + h}
+			if b.x0 < 0 || b.y0 < 0 || b.x1 > float64(style.Width) || b.y1 > float64(style.Height) {
+				continue
+			}
+			free := true
+			for _, p := range placed {
+				if b.overlaps(p) {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+			placed = append(placed, b)
+			if !style.drawsVertices() {
+				c.circle(x, y, labelDot, ink)
+			}
+			c.rect(b.x0, b.y0, w, h, style.Background)
+			c.text(b.x0+2, b.y1-2, s, ink)
+			break
+		}
+	}
+}
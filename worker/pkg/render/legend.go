@@ -0,0 +1,125 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// Legend explains one styling of a drawing, either with an entry per
+// category or size, or with a color ramp from Min to Max
+type Legend struct {
+	Title    string
+	Entries  []LegendEntry
+	Ramp     *Colormap
+	Min, Max float64
+}
+
+// LegendEntry is a labeled swatch, drawn as a circle when it has a radius
+type LegendEntry struct {
+	Label  string
+	Color  color.RGBA
+	Radius float64
+}
+
+// capped shortens long categorical legends, noting the entries left out
+func (l Legend) capped() Legend {
+	if len(l.Entries) > maxLegendEntries {
+		more := len(l.Entries) - maxLegendEntries + 1
+		l.Entries = append(l.Entries[:maxLegendEntries-1:maxLegendEntries-1],
+			LegendEntry{Label: "+" + strconv.Itoa(more) + " more"})
+	}
+	return l
+}
+
+// canvas is the drawing surface the legends and labels are drawn on. Positions are in
+// image coordinates and text is placed by the left end of its baseline.
+type canvas interface {
+	rect(x, y, w, h float64, c color.RGBA)
+	circle(x, y, r float64, c color.RGBA)
+	text(x, y float64, s string, c color.RGBA)
+}
+
+// Legend layout in pixels; text is about legendFont pixels high and
+// legendCharWidth wide per character
+const (
+	legendFont      = 11
+	legendCharWidth = 6.5
+	legendPad       = 8
+	legendRow       = 16
+	legendSwatch    = 10
+	legendRampWidth = 128
+	legendRampSteps = 32
+)
+
+// drawLegends stacks the legends of the style in a box at the top left corner
+func drawLegends(c canvas, style Style) {
+	if len(style.Legends) == 0 {
+		return
+	}
+	// the swatch column fits the largest size entry
+	swatch := float64(legendSwatch)
+	for _, l := range style.Legends {
+		for _, entry := range l.Entries {
+			swatch = max(swatch, 2*entry.Radius)
+		}
+	}
+	rowHeight := func(entry LegendEntry) float64 {
+		return max(legendRow, 2*entry.Radius+4)
+	}
+	width, height := float64(legendRampWidth), 0.0
+	for _, l := range style.Legends {
+		width = max(width, textWidth(l.Title))
+		height += legendRow
+		for _, entry := range l.Entries {
+			width = max(width, swatch+6+textWidth(entry.Label))
+			height += rowHeight(entry)
+		}
+		if l.Ramp != nil {
+			width = max(width, textWidth(rampLabel(l)))
+			height += 2 * legendRow
+		}
+	}
+	x, y := 10.0, 10.0
+	width, height = width+2*legendPad, height+2*legendPad-4
+	c.rect(x, y, width, height, color.RGBA{160, 160, 160, 255})
+	c.rect(x+1, y+1, width-2, height-2, style.Background)
+
+	ink := color.RGBA{0, 0, 0, 255}
+	x, y = x+legendPad, y+legendPad
+	for _, l := range style.Legends {
+		c.text(x, y+legendFont, l.Title, ink)
+		y += legendRow
+		for _, entry := range l.Entries {
+			h := rowHeight(entry)
+			mid := y + h/2 - 2
+			switch {
+			case entry.Radius > 0:
+				c.circle(x+swatch/2, mid, entry.Radius, entry.Color)
+			case entry.Color.A != 0:
+				c.rect(x+(swatch-legendSwatch)/2, mid-legendSwatch/2, legendSwatch, legendSwatch, entry.Color)
+			}
+			c.text(x+swatch+6, mid+4, entry.Label, ink)
+			y += h
+		}
+		if l.Ramp != nil {
+			step := float64(legendRampWidth) / legendRampSteps
+			for i := 0; i < legendRampSteps; i++ {
+				t := (float64(i) + 0.5) / legendRampSteps
+				// steps overlap by a fraction of a pixel so no seams show
+				c.rect(x+float64(i)*step, y+1, step+0.5, legendSwatch, l.Ramp.At(t))
+			}
+			y += legendRow
+			c.text(x, y+legendFont, rampLabel(l), ink)
+			y += legendRow
+		}
+	}
+}
+
+func rampLabel(l Legend) string {
+	return formatValue(l.Min) + " .. " + formatValue(l.Max)
+}
+
+func textWidth(s string) float64 {
+	return math.Ceil(float64(len(s)) * legendCharWidth)
+}
@@ -0,0 +1,180 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// maxLegendEntries limits the entries of a categorical legend
+const maxLegendEntries = 10
+
+// ColorVertices colors every vertex by its value and adds a legend. A
+// continuous colormap spans the range of the values; a categorical one gives
+// each distinct value a color. NaN values keep the default color.
+func ColorVertices(s *Style, values []float64, cmap Colormap, title string) {
+	var l Legend
+	s.VertexColors, l = valueColors(values, cmap, title)
+	s.Legends = append(s.Legends, l)
+}
+
+// ColorEdges colors every edge of the graph by its value, as ColorVertices
+// does for vertices
+func ColorEdges(s *Style, values []float64, cmap Colormap, title string) {
+	var l Legend
+	s.EdgeColors, l = valueColors(values, cmap, title)
+	s.Legends = append(s.Legends, l)
+}
+
+// ColorGroups sets the groups of the style, colored with cmap, and adds a
+// legend; negative groups keep the plain colors
+func ColorGroups(s *Style, groups []int, cmap Colormap, title string) {
+	count := 0
+	for _, k := range groups {
+		count = max(count, k+1)
+	}
+	s.Groups = groups
+	s.GroupColors = make([]color.RGBA, count)
+	l := Legend{Title: title}
+	for k := range s.GroupColors {
+		s.GroupColors[k] = cmap.Category(k, count)
+		l.Entries = append(l.Entries, LegendEntry{Label: strconv.Itoa(k), Color: s.GroupColors[k]})
+	}
+	s.Legends = append(s.Legends, l.capped())
+}
+
+// SizeVertices sets vertex radii from minRadius to maxRadius by value, with
+// the area growing linearly with it, and adds a legend. NaN values get the
+// smallest radius.
+func SizeVertices(s *Style, values []float64, minRadius, maxRadius float64, title string) {
+	lo, hi, ok := valueRange(values)
+	radius := func(v float64) float64 {
+		if math.IsNaN(v) || hi == lo {
+			return minRadius
+		}
+		return minRadius + (maxRadius-minRadius)*math.Sqrt((v-lo)/(hi-lo))
+	}
+	s.VertexSizes = make([]float64, len(values))
+	for i, v := range values {
+		s.VertexSizes[i] = radius(v)
+	}
+	l := Legend{Title: title}
+	if ok {
+		for _, v := range []float64{lo, (lo + hi) / 2, hi} {
+			l.Entries = append(l.Entries, LegendEntry{Label: formatValue(v), Color: s.VertexColor, Radius: radius(v)})
+			if hi == lo {
+				break
+			}
+		}
+	}
+	s.Legends = append(s.Legends, l)
+}
+
+// valueColors maps values to colors with the legend explaining them
+func valueColors(values []float64, cmap Colormap, title string) ([]color.RGBA, Legend) {
+	colors := make([]color.RGBA, len(values))
+	l := Legend{Title: title}
+	if cmap.Categorical {
+		var distinct []float64
+		seen := make(map[float64]bool)
+		for _, v := range values {
+			if !math.IsNaN(v) && !seen[v] {
+				seen[v] = true
+				distinct = append(distinct, v)
+			}
+		}
+		sort.Float64s(distinct)
+		index := make(map[float64]int, len(distinct))
+		for k, v := range distinct {
+			index[v] = k
+			l.Entries = append(l.Entries, LegendEntry{Label: formatValue(v), Color: cmap.Category(k, len(distinct))})
+		}
+		for i, v := range values {
+			if !math.IsNaN(v) {
+				colors[i] = cmap.Category(index[v], len(distinct))
+			}
+		}
+		return colors, l.capped()
+	}
+	lo, hi, ok := valueRange(values)
+	if !ok {
+		return colors, l
+	}
+	for i, v := range values {
+		switch {
+		case math.IsNaN(v):
+		case hi == lo:
+			colors[i] = cmap.At(0.5)
+		default:
+			colors[i] = cmap.At((v - lo) / (hi - lo))
+		}
+	}
+	l.Ramp, l.Min, l.Max = &cmap, lo, hi
+	return colors, l
+}
+
+// valueRange returns the smallest and largest value that is not NaN
+func valueRange(values []float64) (lo, hi float64, ok bool) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+	}
+	return lo, hi, lo <= hi
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// Degrees returns the number of edges at every vertex of e, NaN for vertices
+// without edges
+func Degrees(g *graph.Graph, e *embedding.Embedding) []float64 {
+	deg := make([]float64, e.Len())
+	for _, edge := range g.Edges {
+		if edge.U < len(deg) && edge.V < len(deg) && edge.U != edge.V {
+			deg[edge.U]++
+			deg[edge.V]++
+		}
+	}
+	for v, d := range deg {
+		if d == 0 {
+			deg[v] = math.NaN()
+		}
+	}
+	return deg
+}
+
+// EdgeLengths returns the length of every edge of the graph in the
+// coordinates of e, NaN for edges that are not drawn
+func EdgeLengths(g *graph.Graph, e *embedding.Embedding) []float64 {
+	lengths := make([]float64, len(g.Edges))
+	n := e.Len()
+	for i, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			lengths[i] = math.NaN()
+			continue
+		}
+		sum := 0.0
+		for d, x := range e.Coords[edge.U] {
+			diff := x - e.Coords[edge.V][d]
+			sum += diff * diff
+		}
+		lengths[i] = math.Sqrt(sum)
+	}
+	return lengths
+}
+
+// EdgeWeights returns the weight of every edge of the graph
+func EdgeWeights(g *graph.Graph) []float64 {
+	weights := make([]float64, len(g.Edges))
+	for i, edge := range g.Edges {
+		weights[i] = edge.W
+	}
+	return weights
+}
@@ -0,0 +1,75 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// model is an embedded graph prepared for the 3D model formats. Every vertex id
+// keeps its position, so files can be matched with graph.txt; a 2D embedding
+// lies in the plane z = 0.
+type model struct {
+	positions  [][3]float32
+	colors     []color.RGBA // vertex colors
+	colored    bool         // vertices are styled by a property or partition
+	edges      [][2]uint32
+	edgeColors []color.RGBA // color of each edge, nil when all take edgeColor
+	edgeColor  color.RGBA
+	edgeAlpha  float64
+	points     []uint32 // the vertices with edges, the ones drawings show
+}
+
+func buildModel(g *graph.Graph, e *embedding.Embedding, style Style) (*model, error) {
+	if e.Dims < 2 || e.Dims > 3 {
+		return nil, fmt.Errorf("cannot export a %d-dimensional embedding, expected 2 or 3 dimensions", e.Dims)
+	}
+	if !(style.EdgeAlpha > 0 && style.EdgeAlpha <= 1) {
+		return nil, fmt.Errorf("edge alpha must be in (0, 1], got %g", style.EdgeAlpha)
+	}
+	n := e.Len()
+	m := &model{
+		positions: make([][3]float32, n),
+		colors:    make([]color.RGBA, n),
+		colored:   len(style.VertexColors) > 0 || len(style.Groups) > 0,
+		edgeColor: style.EdgeColor,
+		edgeAlpha: style.EdgeAlpha,
+	}
+	for v, p := range e.Coords {
+		for d := 0; d < e.Dims; d++ {
+			m.positions[v][d] = float32(p[d])
+		}
+		m.colors[v] = style.vertexColor(v)
+	}
+	connected := make([]bool, n)
+	uniform := true
+	for i, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			continue
+		}
+		connected[edge.U], connected[edge.V] = true, true
+		m.edges = append(m.edges, [2]uint32{uint32(edge.U), uint32(edge.V)})
+		c := style.edgeColor(i, edge.U, edge.V)
+		uniform = uniform && c == style.EdgeColor
+		m.edgeColors = append(m.edgeColors, c)
+	}
+	if uniform {
+		m.edgeColors = nil
+	}
+	for v, ok := range connected {
+		if ok {
+			m.points = append(m.points, uint32(v))
+		}
+	}
+	return m, nil
+}
+
+// colorOf returns the color of edge i of the model
+func (m *model) colorOf(i int) color.RGBA {
+	if m.edgeColors != nil {
+		return m.edgeColors[i]
+	}
+	return m.edgeColor
+}
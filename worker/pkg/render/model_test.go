@@ -0,0 +1,181 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// tetrahedron returns a 3D cycle 1-2-3-4 with the phantom vertex 0 left without edges
+func tetrahedron() (*graph.Graph, *embedding.Embedding) {
+	g := &graph.Graph{N: 5, Edges: []graph.Edge{{U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 1}, {U: 3, V: 4, W: 1}, {U: 4, V: 1, W: 1}}}
+	e := &embedding.Embedding{Coords: [][]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1, 1}}, Dims: 3}
+	return g, e
+}
+
+func TestWriteGLB(t *testing.T) {
+	g, e := tetrahedron()
+	colored := DefaultStyle()
+	colored.EdgeColors = []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}}
+	tests := []struct {
+		name  string
+		style Style
+		// accessor counts in the order they are written
+		counts []int
+	}{
+		{"shared positions", DefaultStyle(), []int{5, 5, 8, 4}},
+		{"edge colors", colored, []int{5, 5, 8, 8, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteGLB(&buf, g, e, tt.style); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+			le := binary.LittleEndian
+			if len(data) < 20 || le.Uint32(data) != glbMagic || le.Uint32(data[4:]) != 2 {
+				t.Fatalf("not a glTF 2.0 binary: % x", data[:min(len(data), 12)])
+			}
+			if total := le.Uint32(data[8:]); int(total) != len(data) {
+				t.Fatalf("header gives length %d, file has %d bytes", total, len(data))
+			}
+			jsonLen := int(le.Uint32(data[12:]))
+			if le.Uint32(data[16:]) != glbChunkJSON || jsonLen%4 != 0 {
+				t.Fatalf("JSON chunk of %d bytes with type %#x", jsonLen, le.Uint32(data[16:]))
+			}
+			rest := data[20+jsonLen:]
+			binLen := int(le.Uint32(rest))
+			if le.Uint32(rest[4:]) != glbChunkBIN || binLen%4 != 0 || 8+binLen != len(rest) {
+				t.Fatalf("BIN chunk of %d bytes with type %#x, %d bytes left", binLen, le.Uint32(rest[4:]), len(rest)-8)
+			}
+
+			var doc gltfDoc
+			if err := json.Unmarshal(data[20:20+jsonLen], &doc); err != nil {
+				t.Fatal(err)
+			}
+			if len(doc.Buffers) != 1 || doc.Buffers[0].ByteLength > binLen {
+				t.Fatalf("buffers %+v do not fit the %d byte chunk", doc.Buffers, binLen)
+			}
+			for i, view := range doc.BufferViews {
+				if view.ByteOffset%4 != 0 || view.ByteOffset+view.ByteLength > doc.Buffers[0].ByteLength {
+					t.Errorf("buffer view %d %+v is misaligned or out of the buffer", i, view)
+				}
+			}
+			var counts []int
+			for _, acc := range doc.Accessors {
+				counts = append(counts, acc.Count)
+			}
+			if fmt.Sprint(counts) != fmt.Sprint(tt.counts) {
+				t.Errorf("accessor counts %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestWritePLY(t *testing.T) {
+	g, e := tetrahedron()
+	var buf bytes.Buffer
+	if err := WritePLY(&buf, g, e, DefaultStyle()); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(&buf)
+	var header []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("header ends early: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "end_header" {
+			break
+		}
+		header = append(header, line)
+	}
+	if header[0] != "ply" || header[1] != "format binary_little_endian 1.0" {
+		t.Errorf("header starts with %q", header[:2])
+	}
+	elements := map[string]int{}
+	properties := 0
+	for _, line := range header {
+		var name string
+		var count int
+		if _, err := fmt.Sscanf(line, "element %s %d", &name, &count); err == nil {
+			elements[name] = count
+		}
+		if strings.HasPrefix(line, "property ") {
+			properties++
+		}
+	}
+	if elements["vertex"] != 5 || elements["edge"] != 4 || properties != 11 {
+		t.Errorf("elements %v with %d properties, want 5 vertices, 4 edges, 11 properties", elements, properties)
+	}
+	// a vertex is 3 floats and 3 bytes, an edge 2 ints and 3 bytes
+	body, _ := io.ReadAll(r)
+	if want := 5*15 + 4*11; len(body) != want {
+		t.Errorf("body has %d bytes, want %d", len(body), want)
+	}
+}
+
+func TestWriteVTK(t *testing.T) {
+	g, e := tetrahedron()
+	var buf bytes.Buffer
+	if err := WriteVTK(&buf, g, e, DefaultStyle()); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(&buf)
+	// expect reads the next text line, skipping the blank line after binary data
+	expect := func(want string) {
+		t.Helper()
+		line, err := r.ReadString('\n')
+		if line == "\n" {
+			line, err = r.ReadString('\n')
+		}
+		if err != nil || strings.TrimSuffix(line, "\n") != want {
+			t.Fatalf("read %q (%v), want %q", line, err, want)
+		}
+	}
+	skip := func(n int) {
+		t.Helper()
+		if _, err := io.ReadFull(r, make([]byte, n)); err != nil {
+			t.Fatalf("binary section of %d bytes: %v", n, err)
+		}
+	}
+	expect("# vtk DataFile Version 3.0")
+	expect("spectral graph embedding")
+	expect("BINARY")
+	expect("DATASET POLYDATA")
+	expect("POINTS 5 float")
+	skip(5 * 12)
+	expect("VERTICES 4 8")
+	skip(4 * 8)
+	expect("LINES 4 12")
+	skip(4 * 12)
+	expect("CELL_DATA 8")
+	expect("COLOR_SCALARS colors 3")
+	skip(8 * 3)
+	expect("POINT_DATA 5")
+	expect("SCALARS vertex_id int 1")
+	expect("LOOKUP_TABLE default")
+	ids := make([]byte, 5*4)
+	if _, err := io.ReadFull(r, ids); err != nil {
+		t.Fatal(err)
+	}
+	for v := 0; v < 5; v++ {
+		if id := binary.BigEndian.Uint32(ids[4*v:]); id != uint32(v) {
+			t.Errorf("point %d has vertex id %d", v, id)
+		}
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "\n" {
+		t.Errorf("trailing data %q", rest)
+	}
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// WriteOBJ writes the embedded graph as a Wavefront OBJ file of "v" vertices
+// and "l" line elements. Styled vertices carry their color as the common
+// "v x y z r g b" extension; edge colors cannot be stored.
+func WriteOBJ(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	m, err := buildModel(g, e, style)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %d vertices, %d edges\n", len(m.positions), len(m.edges))
+	for v, p := range m.positions {
+		fmt.Fprintf(bw, "v %s %s %s", objFloat(p[0]), objFloat(p[1]), objFloat(p[2]))
+		if m.colored {
+			c := m.colors[v]
+			fmt.Fprintf(bw, " %.4f %.4f %.4f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+		}
+		bw.WriteByte('\n')
+	}
+	for _, edge := range m.edges {
+		// OBJ indices start at 1
+		fmt.Fprintf(bw, "l %d %d\n", edge[0]+1, edge[1]+1)
+	}
+	return bw.Flush()
+}
+
+func objFloat(x float32) string {
+	return strconv.FormatFloat(float64(x), 'g', -1, 32)
+}
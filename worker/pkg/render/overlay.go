@@ -0,0 +1,66 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Layer is one drawing of an overlay
+type Layer struct {
+	Label     string
+	Graph     *graph.Graph
+	Embedding *embedding.Embedding
+	Color     color.RGBA
+}
+
+// WriteOverlaySVG draws several layouts on top of each other in a common
+// viewport, each in its own color and half transparent, with a legend. It is
+// meant for layouts already aligned to each other.
+func WriteOverlaySVG(w io.Writer, layers []Layer, style Style) error {
+	bw := bufio.NewWriter(w)
+	// The viewport covers the drawn vertices of all layers.
+	var bounds embedding.Embedding
+	for _, l := range layers {
+		n := l.Embedding.Len()
+		for _, edge := range l.Graph.Edges {
+			if edge.U < n && edge.V < n && edge.U != edge.V {
+				bounds.Coords = append(bounds.Coords, l.Embedding.Coords[edge.U], l.Embedding.Coords[edge.V])
+			}
+		}
+	}
+	if len(bounds.Coords) == 0 {
+		return fmt.Errorf("no edges to draw")
+	}
+	bounds.Dims = len(bounds.Coords[0])
+	vp := newViewport(&bounds, style.Width, style.Height, style.margin())
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		style.Width, style.Height, style.Width, style.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", Hex(style.Background))
+	for _, l := range layers {
+		n := l.Embedding.Len()
+		fmt.Fprintf(bw, `<path fill="none" stroke="%s" stroke-opacity="0.6" stroke-width="1" d="`, Hex(l.Color))
+		for _, edge := range l.Graph.Edges {
+			if edge.U >= n || edge.V >= n || edge.U == edge.V {
+				continue
+			}
+			x0, y0 := vp.point(l.Embedding.Coords[edge.U])
+			x1, y1 := vp.point(l.Embedding.Coords[edge.V])
+			fmt.Fprintf(bw, "M%.2f %.2fL%.2f %.2f", x0, y0, x1, y1)
+		}
+		fmt.Fprint(bw, "\"/>\n")
+	}
+	for i, l := range layers {
+		y := 20 + 18*i
+		fmt.Fprintf(bw, `<rect x="10" y="%d" width="12" height="12" fill="%s"/>`+"\n", y-11, Hex(l.Color))
+		fmt.Fprintf(bw, `<text x="28" y="%d" font-family="sans-serif" font-size="13">%s</text>`+"\n", y, html.EscapeString(l.Label))
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
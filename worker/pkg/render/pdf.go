@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// kappa places the control points of the four Bezier curves of a circle
+const kappa = 0.5522847498
+
+// WritePDF draws the graph as WriteSVG does, as a single page PDF document with
+// one point per pixel. The page content is a compressed stream of paths: edges
+// are stroked polylines and vertices filled circles, followed by the labels and legends. The document has no dates or
+// ids, so equal inputs give equal files.
+func WritePDF(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	if style.Width < 1 || style.Height < 1 {
+		return fmt.Errorf("invalid page size %dx%d", style.Width, style.Height)
+	}
+	if !(style.EdgeAlpha > 0 && style.EdgeAlpha <= 1) {
+		return fmt.Errorf("edge alpha must be in (0, 1], got %g", style.EdgeAlpha)
+	}
+	var content bytes.Buffer
+	// flip the y axis so the page uses image coordinates
+	fmt.Fprintf(&content, "1 0 0 -1 0 %d cm\n", style.Height)
+	fmt.Fprintf(&content, "%s rg 0 0 %d %d re f\n", pdfColor(style.Background), style.Width, style.Height)
+	fmt.Fprint(&content, "1 w 1 J 1 j\n")
+	c := pdfCanvas{&content}
+	sc := buildScene(g, e, style)
+	for _, l := range sc.layers {
+		for _, p := range l.paths {
+			fmt.Fprintf(&content, "q /Edges gs %s RG\n", pdfColor(p.stroke))
+			for _, line := range p.lines {
+				for i, pt := range line {
+					op := "l"
+					if i == 0 {
+						op = "m"
+					}
+					fmt.Fprintf(&content, "%.2f %.2f %s\n", pt[0], pt[1], op)
+				}
+				fmt.Fprint(&content, "S\n")
+			}
+			fmt.Fprint(&content, "Q\n")
+		}
+		for _, p := range l.points {
+			c.circle(p.x, p.y, p.r, p.fill)
+		}
+	}
+	drawLabels(c, style, e, sc.vp)
+	drawLegends(c, style)
+	var stream bytes.Buffer
+	zw := zlib.NewWriter(&stream)
+	if _, err := zw.Write(content.Bytes()); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Contents 4 0 R /Resources << /ExtGState << /Edges 5 0 R >> /Font << /F1 6 0 R >> >> >>",
+			style.Width, style.Height),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()),
+		fmt.Sprintf("<< /Type /ExtGState /CA %g >>", style.EdgeAlpha),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+	bw := bufio.NewWriter(w)
+	// the binary comment marks the file as binary for transfer tools
+	offset, _ := fmt.Fprint(bw, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = offset
+		n, _ := fmt.Fprintf(bw, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+		offset += n
+	}
+	fmt.Fprintf(bw, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(bw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(bw, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, offset)
+	return bw.Flush()
+}
+
+// pdfCanvas writes legend and label shapes and vertices to a page content stream
+type pdfCanvas struct {
+	w io.Writer
+}
+
+func (c pdfCanvas) rect(x, y, w, h float64, col color.RGBA) {
+	fmt.Fprintf(c.w, "%s rg %.2f %.2f %.2f %.2f re f\n", pdfColor(col), x, y, w, h)
+}
+
+// circle fills a circle drawn as four Bezier curves
+func (c pdfCanvas) circle(x, y, r float64, col color.RGBA) {
+	k := r * kappa
+	fmt.Fprintf(c.w, "%s rg %.2f %.2f m\n", pdfColor(col), x+r, y)
+	fmt.Fprintf(c.w, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", x+r, y+k, x+k, y+r, x, y+r)
+	fmt.Fprintf(c.w, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", x-k, y+r, x-r, y+k, x-r, y)
+	fmt.Fprintf(c.w, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", x-r, y-k, x-k, y-r, x, y-r)
+	fmt.Fprintf(c.w, "%.2f %.2f %.2f %.2f %.2f %.2f c f\n", x+k, y-r, x+r, y-k, x+r, y)
+}
+
+// text sets s in Helvetica; the text matrix flips the glyphs back upright
+func (c pdfCanvas) text(x, y float64, s string, col color.RGBA) {
+	escaped := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+	fmt.Fprintf(c.w, "%s rg BT /F1 %d Tf 1 0 0 -1 %.2f %.2f Tm (%s) Tj ET\n", pdfColor(col), legendFont, x, y, escaped)
+}
+
+// pdfColor formats c as the three components of a PDF RGB color
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
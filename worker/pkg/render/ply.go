@@ -0,0 +1,50 @@
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// WritePLY writes the embedded graph as a binary little endian PLY file with a
+// vertex element of positions and colors and an edge element of vertex pairs
+// and colors
+func WritePLY(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	m, err := buildModel(g, e, style)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ply\nformat binary_little_endian 1.0\ncomment spectral graph embedding\n")
+	fmt.Fprintf(bw, "element vertex %d\n", len(m.positions))
+	fmt.Fprint(bw, "property float x\nproperty float y\nproperty float z\n")
+	fmt.Fprint(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	fmt.Fprintf(bw, "element edge %d\n", len(m.edges))
+	fmt.Fprint(bw, "property int vertex1\nproperty int vertex2\n")
+	fmt.Fprint(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	fmt.Fprint(bw, "end_header\n")
+
+	var rec []byte
+	for v, p := range m.positions {
+		rec = rec[:0]
+		for _, x := range p {
+			rec = binary.LittleEndian.AppendUint32(rec, math.Float32bits(x))
+		}
+		c := m.colors[v]
+		rec = append(rec, c.R, c.G, c.B)
+		bw.Write(rec)
+	}
+	for i, edge := range m.edges {
+		rec = binary.LittleEndian.AppendUint32(rec[:0], edge[0])
+		rec = binary.LittleEndian.AppendUint32(rec, edge[1])
+		c := m.colorOf(i)
+		rec = append(rec, c.R, c.G, c.B)
+		bw.Write(rec)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,248 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Rasterize draws the graph with the first two coordinates of e into an image,
+// using the same viewport as WriteSVG. Edges are drawn in file order with
+// Bresenham lines, or with Xiaolin Wu lines when the style asks for
+// antialiasing, and blended over the background with the edge alpha; vertices
+// with at least one edge are drawn on top as filled discs. The result only
+// depends on the input, so equal inputs give equal images.
+func Rasterize(g *graph.Graph, e *embedding.Embedding, style Style) (*image.RGBA, error) {
+	if style.Width < 1 || style.Height < 1 {
+		return nil, fmt.Errorf("invalid image size %dx%d", style.Width, style.Height)
+	}
+	if !(style.EdgeAlpha > 0 && style.EdgeAlpha <= 1) {
+		return nil, fmt.Errorf("edge alpha must be in (0, 1], got %g", style.EdgeAlpha)
+	}
+	return rasterize(g, e, style, newViewport(e, style.Width, style.Height, style.margin())), nil
+}
+
+// rasterize draws the graph as Rasterize does, through the given viewport
+func rasterize(g *graph.Graph, e *embedding.Embedding, style Style, vp viewport) *image.RGBA {
+	r := raster{image.NewRGBA(image.Rect(0, 0, style.Width, style.Height))}
+	bg := style.Background
+	for i := 0; i < len(r.img.Pix); i += 4 {
+		r.img.Pix[i], r.img.Pix[i+1], r.img.Pix[i+2], r.img.Pix[i+3] = bg.R, bg.G, bg.B, 255
+	}
+	n := e.Len()
+	alpha := alpha8(style.EdgeAlpha)
+	connected := make([]bool, n)
+	for i, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			continue
+		}
+		connected[edge.U], connected[edge.V] = true, true
+		c := style.edgeColor(i, edge.U, edge.V)
+		x0, y0 := vp.point(e.Coords[edge.U])
+		x1, y1 := vp.point(e.Coords[edge.V])
+		if style.Antialias {
+			r.lineAA(x0, y0, x1, y1, c, alpha)
+		} else {
+			r.line(x0, y0, x1, y1, c, alpha)
+		}
+	}
+	if style.drawsVertices() {
+		for v, p := range e.Coords {
+			if !connected[v] {
+				continue
+			}
+			x, y := vp.point(p)
+			r.disc(x, y, style.vertexRadius(v), style.vertexColor(v), style.Antialias)
+		}
+	}
+	drawLabels(r, style, e, vp)
+	drawLegends(r, style)
+	return r.img
+}
+
+// WritePNG draws the graph as Rasterize does and encodes it as PNG
+func WritePNG(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	img, err := Rasterize(g, e, style)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// raster blends colors into an opaque image. Coverage and opacity are rounded
+// to 8-bit alpha and blended with integer arithmetic.
+type raster struct {
+	img *image.RGBA
+}
+
+func alpha8(a float64) uint32 {
+	return uint32(math.Floor(a*255 + 0.5))
+}
+
+// blend paints c with alpha a (0..255) over pixel (x, y), ignoring pixels outside the image
+func (r raster) blend(x, y int, c color.RGBA, a uint32) {
+	if a == 0 || x < 0 || y < 0 || x >= r.img.Rect.Dx() || y >= r.img.Rect.Dy() {
+		return
+	}
+	i := r.img.PixOffset(x, y)
+	p := r.img.Pix[i : i+3 : i+3]
+	if a >= 255 {
+		p[0], p[1], p[2] = c.R, c.G, c.B
+		return
+	}
+	p[0] = uint8((uint32(p[0])*(255-a) + uint32(c.R)*a + 127) / 255)
+	p[1] = uint8((uint32(p[1])*(255-a) + uint32(c.G)*a + 127) / 255)
+	p[2] = uint8((uint32(p[2])*(255-a) + uint32(c.B)*a + 127) / 255)
+}
+
+// line draws a one pixel wide Bresenham line between the pixels nearest to
+// the end points, clamped to the image
+func (r raster) line(fx0, fy0, fx1, fy1 float64, c color.RGBA, a uint32) {
+	walkLine(fx0, fy0, fx1, fy1, r.img.Rect.Dx(), r.img.Rect.Dy(), func(x, y int) {
+		r.blend(x, y, c, a)
+	})
+}
+
+// walkLine visits the pixels of a Bresenham line in a w x h image, as line draws them
+func walkLine(fx0, fy0, fx1, fy1 float64, w, h int, plot func(x, y int)) {
+	x0, y0 := clamp(int(math.Round(fx0)), w), clamp(int(math.Round(fy0)), h)
+	x1, y1 := clamp(int(math.Round(fx1)), w), clamp(int(math.Round(fy1)), h)
+	dx, sx := x1-x0, 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	dy, sy := y0-y1, 1
+	if dy > 0 {
+		dy = -dy
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		plot(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func clamp(v, size int) int {
+	return max(0, min(v, size-1))
+}
+
+// lineAA draws an antialiased line with Xiaolin Wu's algorithm; pixel (x, y)
+// covers [x, x+1) x [y, y+1)
+func (r raster) lineAA(x0, y0, x1, y1 float64, c color.RGBA, a uint32) {
+	walkLineAA(x0, y0, x1, y1, func(x, y int, coverage float64) {
+		r.blend(x, y, c, (a*alpha8(coverage)+127)/255)
+	})
+}
+
+// walkLineAA visits the pixels of a Xiaolin Wu line with their coverage;
+// pixels outside the image are visited too
+func walkLineAA(x0, y0, x1, y1 float64, visit func(x, y int, coverage float64)) {
+	// move pixel centers to integer positions
+	x0, y0, x1, y1 = x0-0.5, y0-0.5, x1-0.5, y1-0.5
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0, x1, y1 = y0, x0, y1, x1
+	}
+	if x0 > x1 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	plot := func(x, y int, coverage float64) {
+		if steep {
+			visit(y, x, coverage)
+		} else {
+			visit(x, y, coverage)
+		}
+	}
+	gradient := 1.0
+	if dx := x1 - x0; dx > 0 {
+		gradient = (y1 - y0) / dx
+	}
+
+	xEnd := math.Round(x0)
+	yEnd := y0 + gradient*(xEnd-x0)
+	gap := 1 - frac(x0+0.5)
+	xStart := int(xEnd)
+	plot(xStart, int(math.Floor(yEnd)), (1-frac(yEnd))*gap)
+	plot(xStart, int(math.Floor(yEnd))+1, frac(yEnd)*gap)
+	y := yEnd + gradient
+
+	xEnd = math.Round(x1)
+	yEnd = y1 + gradient*(xEnd-x1)
+	gap = frac(x1 + 0.5)
+	xStop := int(xEnd)
+	if xStop == xStart {
+		return
+	}
+	plot(xStop, int(math.Floor(yEnd)), (1-frac(yEnd))*gap)
+	plot(xStop, int(math.Floor(yEnd))+1, frac(yEnd)*gap)
+
+	for x := xStart + 1; x < xStop; x++ {
+		plot(x, int(math.Floor(y)), 1-frac(y))
+		plot(x, int(math.Floor(y))+1, frac(y))
+		y += gradient
+	}
+}
+
+// rect fills the pixels whose centers lie inside the rectangle
+func (r raster) rect(x, y, w, h float64, c color.RGBA) {
+	for py := int(math.Round(y)); py < int(math.Round(y+h)); py++ {
+		for px := int(math.Round(x)); px < int(math.Round(x+w)); px++ {
+			r.blend(px, py, c, 255)
+		}
+	}
+}
+
+// circle fills a crisp disc, for the legend swatches
+func (r raster) circle(x, y, radius float64, c color.RGBA) {
+	r.disc(x, y, radius, c, false)
+}
+
+func frac(x float64) float64 {
+	return x - math.Floor(x)
+}
+
+// disc fills a circle of the given radius around (cx, cy). Without
+// antialiasing it covers the pixels within the rounded radius of the pixel
+// nearest to the center; with it, border pixels are blended by the part of the
+// pixel inside the circle, estimated from the distance of the pixel center.
+func (r raster) disc(cx, cy, radius float64, c color.RGBA, antialias bool) {
+	if !antialias {
+		x0, y0 := int(math.Round(cx)), int(math.Round(cy))
+		rad := int(math.Round(radius))
+		for dy := -rad; dy <= rad; dy++ {
+			for dx := -rad; dx <= rad; dx++ {
+				if dx*dx+dy*dy <= rad*rad {
+					r.blend(x0+dx, y0+dy, c, 255)
+				}
+			}
+		}
+		return
+	}
+	for y := int(math.Floor(cy - radius - 1)); y <= int(math.Ceil(cy+radius+1)); y++ {
+		for x := int(math.Floor(cx - radius - 1)); x <= int(math.Ceil(cx+radius+1)); x++ {
+			dist := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			coverage := math.Max(0, math.Min(1, radius+0.5-dist))
+			r.blend(x, y, c, alpha8(coverage))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// square returns the cycle 0-1-2-3 on the corners of the unit square
+func square() (*graph.Graph, *embedding.Embedding) {
+	g := &graph.Graph{N: 4, Edges: []graph.Edge{{U: 0, V: 1, W: 1}, {U: 1, V: 2, W: 1}, {U: 2, V: 3, W: 1}, {U: 3, V: 0, W: 1}}}
+	e := &embedding.Embedding{Coords: [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, Dims: 2}
+	return g, e
+}
+
+// squareStyle draws red edges on white in a 21x21 image
+func squareStyle() Style {
+	st := DefaultStyle()
+	st.Width, st.Height = 21, 21
+	st.EdgeColor = color.RGBA{255, 0, 0, 255}
+	return st
+}
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	red   = color.RGBA{255, 0, 0, 255}
+)
+
+func TestRasterizePixels(t *testing.T) {
+	g, e := square()
+	half := squareStyle()
+	half.EdgeAlpha = 0.5
+	margin := squareStyle()
+	margin.Margin = 5
+	tests := []struct {
+		name  string
+		style Style
+		want  map[image.Point]color.RGBA
+	}{
+		{"edges on the border", squareStyle(), map[image.Point]color.RGBA{
+			{0, 0}: red, {10, 0}: red, {20, 10}: red, {0, 20}: red, {10, 10}: white,
+		}},
+		{"alpha blends over the background", half, map[image.Point]color.RGBA{
+			{10, 0}: {255, 127, 127, 255}, {10, 10}: white,
+		}},
+		{"margin keeps the border free", margin, map[image.Point]color.RGBA{
+			{0, 0}: white, {4, 10}: white, {5, 10}: red, {15, 10}: white, {16, 10}: red, {17, 10}: white, {10, 5}: red,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Rasterize(g, e, tt.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for p, want := range tt.want {
+				if got := img.RGBAAt(p.X, p.Y); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRasterizeAntialias(t *testing.T) {
+	g, e := square()
+	st := squareStyle()
+	st.Margin = 5
+	st.Antialias = true
+	img, err := Rasterize(g, e, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the left edge runs along x = 5, between the centers of columns 4 and 5
+	left, right := img.RGBAAt(4, 10), img.RGBAAt(5, 10)
+	if left != right {
+		t.Errorf("pixels on both sides of the edge differ: %v and %v", left, right)
+	}
+	if left == white || left == red || left.R != 255 {
+		t.Errorf("edge pixel %v is not a blend of red and white", left)
+	}
+	if got := img.RGBAAt(10, 10); got != white {
+		t.Errorf("center pixel = %v, want background", got)
+	}
+}
+
+func TestWritePNGDeterministic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 60
+	g := &graph.Graph{N: n}
+	e := embedding.New(n, 2)
+	for v := 0; v < n; v++ {
+		e.Coords[v][0], e.Coords[v][1] = rng.Float64(), rng.NormFloat64()
+		for k := 0; k < 3; k++ {
+			g.Edges = append(g.Edges, graph.Edge{U: v, V: rng.Intn(n), W: 1})
+		}
+	}
+	styles := map[string]Style{"plain": DefaultStyle()}
+	st := DefaultStyle()
+	st.Width, st.Height, st.Margin = 300, 200, 12
+	st.EdgeAlpha, st.Antialias, st.VertexSize = 0.3, true, 2.5
+	st.Labels = []int{1, 2, 3}
+	styles["styled"] = st
+	for name, st := range styles {
+		var a, b bytes.Buffer
+		if err := WritePNG(&a, g, e, st); err != nil {
+			t.Fatal(err)
+		}
+		if err := WritePNG(&b, g, e, st); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(a.Bytes(), b.Bytes()) {
+			t.Errorf("%s: two renders of the same input differ", name)
+		}
+	}
+}
+
+func TestRasterizeInvalidStyle(t *testing.T) {
+	g, e := square()
+	for name, st := range map[string]Style{
+		"zero size":  {Width: 0, Height: 10, EdgeAlpha: 1},
+		"zero alpha": {Width: 10, Height: 10},
+		"alpha > 1":  {Width: 10, Height: 10, EdgeAlpha: 1.5},
+	} {
+		if _, err := Rasterize(g, e, st); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
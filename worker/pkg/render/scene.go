@@ -0,0 +1,199 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// layer is one group of a vector drawing: the edges inside a group with the
+// group's vertices, or the plain edges and vertices outside any group
+type layer struct {
+	id     string
+	fill   color.RGBA // fill of the vertices without a color of their own
+	paths  []path
+	points []point
+}
+
+// path holds the polylines of one stroke color, in image coordinates
+type path struct {
+	stroke color.RGBA
+	lines  [][][2]float64
+}
+
+// point is a vertex as a circle in image coordinates
+type point struct {
+	x, y, r float64
+	fill    color.RGBA
+}
+
+// scene holds a drawing prepared for the vector writers
+type scene struct {
+	layers []layer
+	vp     viewport // for the labels drawn over the layers
+}
+
+// buildScene maps the graph to image coordinates. Edges go to the layer of
+// their group when both ends share it, to the plain layer otherwise, and are
+// chained into polylines through shared vertices of the same color. Edges
+// shorter than MinEdgeLength pixels are merged into their neighbors in the
+// polyline, or left out when the whole polyline is that short.
+func buildScene(g *graph.Graph, e *embedding.Embedding, style Style) scene {
+	vp := newViewport(e, style.Width, style.Height, style.margin())
+	n := e.Len()
+	groups := 0
+	for v := 0; v < n; v++ {
+		groups = max(groups, style.group(v)+1)
+	}
+	// layer 0 holds the plain edges, layer k+1 those of group k; within a
+	// layer the edges are split by color in order of first use
+	type colored struct {
+		stroke color.RGBA
+		edges  [][2]int
+	}
+	edges := make([][]colored, groups+1)
+	index := make([]map[color.RGBA]int, groups+1)
+	connected := make([]bool, n)
+	for i, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			continue
+		}
+		connected[edge.U], connected[edge.V] = true, true
+		l := 0
+		if gu := style.group(edge.U); gu >= 0 && gu == style.group(edge.V) {
+			l = gu + 1
+		}
+		stroke := style.edgeColor(i, edge.U, edge.V)
+		if index[l] == nil {
+			index[l] = make(map[color.RGBA]int)
+		}
+		k, ok := index[l][stroke]
+		if !ok {
+			k = len(edges[l])
+			index[l][stroke] = k
+			edges[l] = append(edges[l], colored{stroke: stroke})
+		}
+		edges[l][k].edges = append(edges[l][k].edges, [2]int{edge.U, edge.V})
+	}
+
+	s := scene{vp: vp}
+	c := newChainer(n)
+	for l := range edges {
+		ly := layer{id: "edges", fill: style.VertexColor}
+		if l > 0 {
+			ly = layer{id: fmt.Sprintf("group-%d", l-1), fill: style.groupColor(l - 1)}
+		}
+		for _, list := range edges[l] {
+			p := path{stroke: list.stroke}
+			for _, chain := range c.chains(list.edges) {
+				line := make([][2]float64, 0, len(chain))
+				for i, v := range chain {
+					x, y := vp.point(e.Coords[v])
+					// the last point is kept so the polyline still ends at its vertex
+					if i > 0 && math.Hypot(x-line[len(line)-1][0], y-line[len(line)-1][1]) < style.MinEdgeLength {
+						if i < len(chain)-1 || len(line) == 1 {
+							continue
+						}
+						line = line[:len(line)-1]
+					}
+					line = append(line, [2]float64{x, y})
+				}
+				if len(line) > 1 {
+					p.lines = append(p.lines, line)
+				}
+			}
+			if len(p.lines) > 0 {
+				ly.paths = append(ly.paths, p)
+			}
+		}
+		if style.drawsVertices() {
+			for v := 0; v < n; v++ {
+				if connected[v] && style.group(v)+1 == l {
+					x, y := vp.point(e.Coords[v])
+					ly.points = append(ly.points, point{x: x, y: y, r: style.vertexRadius(v), fill: style.vertexColor(v)})
+				}
+			}
+		}
+		if len(ly.paths) > 0 || len(ly.points) > 0 {
+			s.layers = append(s.layers, ly)
+		}
+	}
+	return s
+}
+
+// chainer splits edge lists into polylines. Its per-vertex arrays are shared
+// by all lists and reset only where a list touched them.
+type chainer struct {
+	degree []int // incident edges of the vertex in the current list
+	first  []int // offset of the incident edges of the vertex
+	next   []int // first incident edge not checked yet
+}
+
+func newChainer(n int) *chainer {
+	return &chainer{degree: make([]int, n), first: make([]int, n), next: make([]int, n)}
+}
+
+// chains walks the edges into vertex sequences: starting at the first unused
+// edge in list order, each walk follows unused edges until it gets stuck
+func (c *chainer) chains(edges [][2]int) [][]int {
+	var touched []int
+	for _, e := range edges {
+		for _, v := range e {
+			if c.degree[v] == 0 {
+				touched = append(touched, v)
+			}
+			c.degree[v]++
+		}
+	}
+	offset := 0
+	for _, v := range touched {
+		c.first[v], c.next[v] = offset, offset
+		offset += c.degree[v]
+	}
+	incident := make([]int, offset)
+	for i, e := range edges {
+		for _, v := range e {
+			incident[c.next[v]] = i
+			c.next[v]++
+		}
+	}
+	for _, v := range touched {
+		c.next[v] = c.first[v]
+	}
+
+	used := make([]bool, len(edges))
+	var chains [][]int
+	for i, e := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		chain := []int{e[0], e[1]}
+		v := e[1]
+		for {
+			end := c.first[v] + c.degree[v]
+			for c.next[v] < end && used[incident[c.next[v]]] {
+				c.next[v]++
+			}
+			if c.next[v] == end {
+				break
+			}
+			j := incident[c.next[v]]
+			used[j] = true
+			if edges[j][0] == v {
+				v = edges[j][1]
+			} else {
+				v = edges[j][0]
+			}
+			chain = append(chain, v)
+		}
+		chains = append(chains, chain)
+	}
+	for _, v := range touched {
+		c.degree[v] = 0
+	}
+	return chains
+}
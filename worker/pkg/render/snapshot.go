@@ -0,0 +1,147 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// Camera looks at a 3D embedding from the direction given by its azimuth
+// around the z axis and its elevation above the xy plane, in degrees. Views
+// are orthographic: azimuth 0 and elevation 0 show x to the right and z up.
+type Camera struct {
+	Azimuth   float64
+	Elevation float64
+}
+
+// StandardViews are the cameras of the named snapshot views; the isometric
+// view sees the three axes at equal angles
+var StandardViews = map[string]Camera{
+	"front": {0, 0},
+	"side":  {90, 0},
+	"top":   {0, 90},
+	"iso":   {45, math.Atan(1/math.Sqrt2) * 180 / math.Pi},
+}
+
+// snapshotFog is how far the farthest parts of a snapshot fade into the background
+const snapshotFog = 0.65
+
+// ParseView reads a view as the name of a standard view or as
+// "azimuth:elevation" and returns the name its snapshot is saved under
+func ParseView(s string) (string, Camera, error) {
+	if cam, ok := StandardViews[s]; ok {
+		return s, cam, nil
+	}
+	az, el, ok := strings.Cut(s, ":")
+	if ok {
+		a, errA := strconv.ParseFloat(az, 64)
+		e, errE := strconv.ParseFloat(el, 64)
+		if errA == nil && errE == nil && math.Abs(a) <= 360 && math.Abs(e) <= 90 {
+			return "az" + az + "-el" + el, Camera{a, e}, nil
+		}
+	}
+	return "", Camera{}, fmt.Errorf("invalid view %q, expected front, side, top, iso or azimuth:elevation", s)
+}
+
+// axes returns the screen right and up directions of the camera and the
+// direction towards it
+func (c Camera) axes() (right, up, toward [3]float64) {
+	az, el := c.Azimuth*math.Pi/180, c.Elevation*math.Pi/180
+	right = [3]float64{math.Cos(az), math.Sin(az), 0}
+	up = [3]float64{-math.Sin(el) * math.Sin(az), math.Sin(el) * math.Cos(az), math.Cos(el)}
+	toward = [3]float64{math.Cos(el) * math.Sin(az), -math.Cos(el) * math.Cos(az), math.Sin(el)}
+	return right, up, toward
+}
+
+// RasterizeSnapshot draws a 3D embedding as seen by the camera, in software.
+// The projected drawing keeps its aspect ratio and is centered in the image.
+// Edges and then vertices are drawn from back to front, and parts of the
+// graph fade into the background with their distance from the camera, so the
+// depth stays readable without lighting. A 2D embedding lies in the plane z = 0.
+func RasterizeSnapshot(g *graph.Graph, e *embedding.Embedding, style Style, cam Camera) (*image.RGBA, error) {
+	if style.Width < 1 || style.Height < 1 {
+		return nil, fmt.Errorf("invalid image size %dx%d", style.Width, style.Height)
+	}
+	m, err := buildModel(g, e, style)
+	if err != nil {
+		return nil, err
+	}
+	right, up, toward := cam.axes()
+	flat := embedding.New(len(m.positions), 2)
+	depth := make([]float64, len(m.positions))
+	for v, p := range m.positions {
+		for d, x := range p {
+			flat.Coords[v][0] += float64(x) * right[d]
+			flat.Coords[v][1] += float64(x) * up[d]
+			depth[v] += float64(x) * toward[d]
+		}
+	}
+	near, far := math.Inf(-1), math.Inf(1)
+	for _, v := range m.points {
+		near, far = max(near, depth[v]), min(far, depth[v])
+	}
+	shade := func(c color.RGBA, z float64) color.RGBA {
+		if near <= far {
+			return c
+		}
+		t := snapshotFog * (near - z) / (near - far)
+		mix := func(a, b uint8) uint8 {
+			return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+		}
+		bg := style.Background
+		return color.RGBA{mix(c.R, bg.R), mix(c.G, bg.G), mix(c.B, bg.B), 255}
+	}
+
+	r := raster{image.NewRGBA(image.Rect(0, 0, style.Width, style.Height))}
+	bg := style.Background
+	for i := 0; i < len(r.img.Pix); i += 4 {
+		r.img.Pix[i], r.img.Pix[i+1], r.img.Pix[i+2], r.img.Pix[i+3] = bg.R, bg.G, bg.B, 255
+	}
+	vp := newUniformViewport(flat, style.Width, style.Height, style.margin())
+	order := make([]int, len(m.edges))
+	edgeDepth := make([]float64, len(m.edges))
+	for i, edge := range m.edges {
+		order[i] = i
+		edgeDepth[i] = (depth[edge[0]] + depth[edge[1]]) / 2
+	}
+	sort.SliceStable(order, func(a, b int) bool { return edgeDepth[order[a]] < edgeDepth[order[b]] })
+	alpha := alpha8(style.EdgeAlpha)
+	for _, i := range order {
+		x0, y0 := vp.point(flat.Coords[m.edges[i][0]])
+		x1, y1 := vp.point(flat.Coords[m.edges[i][1]])
+		c := shade(m.colorOf(i), edgeDepth[i])
+		if style.Antialias {
+			r.lineAA(x0, y0, x1, y1, c, alpha)
+		} else {
+			r.line(x0, y0, x1, y1, c, alpha)
+		}
+	}
+	if style.drawsVertices() {
+		points := append([]uint32(nil), m.points...)
+		sort.SliceStable(points, func(a, b int) bool { return depth[points[a]] < depth[points[b]] })
+		for _, v := range points {
+			x, y := vp.point(flat.Coords[v])
+			r.disc(x, y, style.vertexRadius(int(v)), shade(m.colors[v], depth[v]), style.Antialias)
+		}
+	}
+	drawLegends(r, style)
+	return r.img, nil
+}
+
+// WriteSnapshotPNG draws the graph as RasterizeSnapshot does and encodes it as PNG
+func WriteSnapshotPNG(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style, cam Camera) error {
+	img, err := RasterizeSnapshot(g, e, style, cam)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
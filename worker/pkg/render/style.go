@@ -0,0 +1,154 @@
+// Package render draws an embedded graph to image formats and exports it as 3D models.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"worker/pkg/graph"
+)
+
+// Style controls the size and colors of a drawing. A zero VertexSize draws edges only.
+// When Groups is set, vertices are colored by their group (such as a cluster) and
+// edges inside a group take the group color; negative groups keep the plain colors.
+// Margin is kept free around the drawing, in addition to the vertex radius.
+// MinEdgeLength simplifies vector output of large graphs: edges shorter than
+// this many pixels are merged into their neighbors or left out.
+// The per-element fields, set by the Color* and Size* functions, override the
+// plain and group colors: VertexColors and VertexSizes are indexed by vertex,
+// EdgeColors by the position of the edge in the graph, and colors with zero
+// alpha keep the default. Legends explain them in a corner of the drawing.
+// Labels lists the vertices named by their id in 2D drawings, most important
+// first; a label that would overlap an earlier one is left out. Sides names
+// them by row and column instead when the graph is bipartite.
+type Style struct {
+	Width         int
+	Height        int
+	Margin        int
+	Background    color.RGBA
+	EdgeColor     color.RGBA
+	EdgeAlpha     float64 // opacity of the edges, 1 draws them opaque
+	VertexColor   color.RGBA
+	VertexSize    float64
+	Antialias     bool // smooth edges and vertices in raster images
+	MinEdgeLength float64
+	Groups        []int
+	GroupColors   []color.RGBA // colors of the groups, the palette when unset
+
+	VertexColors []color.RGBA
+	VertexSizes  []float64
+	EdgeColors   []color.RGBA
+	Legends      []Legend
+	Labels       []int
+	Sides        graph.Sides
+}
+
+// DefaultStyle matches the drawings produced by the job pipeline
+func DefaultStyle() Style {
+	return Style{
+		Width:       1200,
+		Height:      800,
+		Background:  color.RGBA{255, 255, 255, 255},
+		EdgeColor:   color.RGBA{65, 105, 225, 255},
+		EdgeAlpha:   1,
+		VertexColor: color.RGBA{25, 25, 112, 255},
+	}
+}
+
+// ParseColor reads a color in "#rrggbb" or "rrggbb" form
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	var r, g, b uint8
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
+
+// Hex formats c as "#rrggbb"
+func Hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// palette holds the categorical colors used for clusters (Tableau 10)
+var palette = []color.RGBA{
+	{31, 119, 180, 255},
+	{255, 127, 14, 255},
+	{44, 160, 44, 255},
+	{214, 39, 40, 255},
+	{148, 103, 189, 255},
+	{140, 86, 75, 255},
+	{227, 119, 194, 255},
+	{127, 127, 127, 255},
+	{188, 189, 34, 255},
+	{23, 190, 207, 255},
+}
+
+// CategoryColor returns the palette color of group c, cycling through the palette
+func CategoryColor(c int) color.RGBA {
+	return palette[c%len(palette)]
+}
+
+// margin is the space between the drawing and the image border, in pixels
+func (s Style) margin() float64 {
+	radius := s.VertexSize
+	for _, r := range s.VertexSizes {
+		radius = max(radius, r)
+	}
+	return float64(s.Margin) + radius
+}
+
+// group returns the group of vertex v, or -1 when the style has none
+func (s Style) group(v int) int {
+	if v < len(s.Groups) {
+		return s.Groups[v]
+	}
+	return -1
+}
+
+// groupColor returns the color of group k
+func (s Style) groupColor(k int) color.RGBA {
+	if k < len(s.GroupColors) {
+		return s.GroupColors[k]
+	}
+	return CategoryColor(k)
+}
+
+// drawsVertices reports whether vertices are drawn at all
+func (s Style) drawsVertices() bool {
+	return s.VertexSize > 0 || len(s.VertexSizes) > 0
+}
+
+// vertexColor returns the fill of vertex v
+func (s Style) vertexColor(v int) color.RGBA {
+	if v < len(s.VertexColors) && s.VertexColors[v].A != 0 {
+		return s.VertexColors[v]
+	}
+	if gv := s.group(v); gv >= 0 {
+		return s.groupColor(gv)
+	}
+	return s.VertexColor
+}
+
+// vertexRadius returns the radius of vertex v in pixels
+func (s Style) vertexRadius(v int) float64 {
+	if v < len(s.VertexSizes) {
+		return s.VertexSizes[v]
+	}
+	return s.VertexSize
+}
+
+// edgeColor returns the stroke of edge i of the graph, from u to v
+func (s Style) edgeColor(i, u, v int) color.RGBA {
+	if i < len(s.EdgeColors) && s.EdgeColors[i].A != 0 {
+		return s.EdgeColors[i]
+	}
+	if gu := s.group(u); gu >= 0 && gu == s.group(v) {
+		return s.groupColor(gu)
+	}
+	return s.EdgeColor
+}
@@ -0,0 +1,87 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// WriteSVG draws the graph with the first two coordinates of e as an SVG document.
+// Every group of the style becomes a <g> element with its edges and vertices, after
+// one for the plain edges and vertices. Edges are chained into polylines of a
+// single path per group and color so large graphs stay compact. Only vertices with
+// at least one edge are drawn: ids without edges (such as vertex 0 of a 1-based
+// Matrix Market file) are not part of the graph.
+func WriteSVG(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		style.Width, style.Height, style.Width, style.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", Hex(style.Background))
+	opacity := ""
+	if style.EdgeAlpha < 1 {
+		opacity = fmt.Sprintf(` stroke-opacity="%g"`, style.EdgeAlpha)
+	}
+	sc := buildScene(g, e, style)
+	for _, l := range sc.layers {
+		fmt.Fprintf(bw, `<g id="%s">`+"\n", l.id)
+		for _, p := range l.paths {
+			fmt.Fprintf(bw, `<path fill="none" stroke="%s"%s stroke-width="1" stroke-linejoin="round" d="`, Hex(p.stroke), opacity)
+			for _, line := range p.lines {
+				for i, pt := range line {
+					op := 'L'
+					if i == 0 {
+						op = 'M'
+					}
+					fmt.Fprintf(bw, "%c%.2f %.2f", op, pt[0], pt[1])
+				}
+			}
+			fmt.Fprint(bw, "\"/>\n")
+		}
+		if len(l.points) > 0 {
+			fmt.Fprintf(bw, `<g fill="%s">`+"\n", Hex(l.fill))
+			for _, p := range l.points {
+				fill := ""
+				if p.fill != l.fill {
+					fill = fmt.Sprintf(` fill="%s"`, Hex(p.fill))
+				}
+				fmt.Fprintf(bw, `<circle cx="%.2f" cy="%.2f" r="%g"%s/>`+"\n", p.x, p.y, p.r, fill)
+			}
+			fmt.Fprint(bw, "</g>\n")
+		}
+		fmt.Fprint(bw, "</g>\n")
+	}
+	if len(style.Labels) > 0 {
+		fmt.Fprintf(bw, `<g id="labels" font-family="sans-serif" font-size="%d">`+"\n", legendFont)
+		drawLabels(svgCanvas{bw}, style, e, sc.vp)
+		fmt.Fprint(bw, "</g>\n")
+	}
+	if len(style.Legends) > 0 {
+		fmt.Fprintf(bw, `<g id="legend" font-family="sans-serif" font-size="%d">`+"\n", legendFont)
+		drawLegends(svgCanvas{bw}, style)
+		fmt.Fprint(bw, "</g>\n")
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// svgCanvas writes legend and label shapes as SVG elements
+type svgCanvas struct {
+	w io.Writer
+}
+
+func (c svgCanvas) rect(x, y, w, h float64, col color.RGBA) {
+	fmt.Fprintf(c.w, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`+"\n", x, y, w, h, Hex(col))
+}
+
+func (c svgCanvas) circle(x, y, r float64, col color.RGBA) {
+	fmt.Fprintf(c.w, `<circle cx="%.2f" cy="%.2f" r="%g" fill="%s"/>`+"\n", x, y, r, Hex(col))
+}
+
+func (c svgCanvas) text(x, y float64, s string, col color.RGBA) {
+	fmt.Fprintf(c.w, `<text x="%.2f" y="%.2f" fill="%s">%s</text>`+"\n", x, y, Hex(col), html.EscapeString(s))
+}
@@ -0,0 +1,34 @@
+package render
+
+import (
+	"errors"
+	"image"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// ThumbnailSize is the side of the square job thumbnails, in pixels
+const ThumbnailSize = 256
+
+// thumbnailEdges is how many edges a thumbnail draws opaque; the edges of
+// larger graphs are faded so that the shape of the drawing stays visible
+// instead of filling the thumbnail
+const thumbnailEdges = 2000
+
+// Thumbnail draws a small square preview of a 2D embedding in the default
+// colors. Unlike Rasterize it keeps the aspect ratio of the drawing, and its
+// edges are antialiased and fade with the size of the graph.
+func Thumbnail(g *graph.Graph, e *embedding.Embedding, size int) (*image.RGBA, error) {
+	if size < 1 {
+		return nil, errors.New("invalid thumbnail size")
+	}
+	style := DefaultStyle()
+	style.Width, style.Height = size, size
+	style.Margin = max(1, size/32)
+	style.Antialias = true
+	if len(g.Edges) > thumbnailEdges {
+		style.EdgeAlpha = max(0.05, float64(thumbnailEdges)/float64(len(g.Edges)))
+	}
+	return rasterize(g, e, style, newUniformViewport(e, size, size, style.margin())), nil
+}
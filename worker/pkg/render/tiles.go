@@ -0,0 +1,237 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// TileSize is the width and height of a map tile in pixels
+const TileSize = 256
+
+// MaxTileZoom is the deepest zoom level tiles can be drawn at
+const MaxTileZoom = 16
+
+// tileMap is a drawing prepared for one zoom level of a slippy map: at zoom z
+// the drawing fills a square of TileSize·2^z pixels, keeping its aspect
+// ratio, cut into 2^z x 2^z tiles with x growing east and y south
+type tileMap struct {
+	style     Style
+	tiles     int          // tiles per side
+	points    [][2]float64 // vertex positions on the whole map
+	edges     [][3]int     // drawn edges as end points and position in the graph
+	connected []bool
+}
+
+func newTileMap(g *graph.Graph, e *embedding.Embedding, style Style, z int) (*tileMap, error) {
+	if z < 0 || z > MaxTileZoom {
+		return nil, fmt.Errorf("zoom %d is not in 0..%d", z, MaxTileZoom)
+	}
+	if !(style.EdgeAlpha > 0 && style.EdgeAlpha <= 1) {
+		return nil, fmt.Errorf("edge alpha must be in (0, 1], got %g", style.EdgeAlpha)
+	}
+	m := &tileMap{style: style, tiles: 1 << z, connected: make([]bool, e.Len())}
+	vp := newSquareViewport(e, TileSize*m.tiles, style.margin())
+	m.points = make([][2]float64, e.Len())
+	for v, p := range e.Coords {
+		m.points[v][0], m.points[v][1] = vp.point(p)
+	}
+	n := e.Len()
+	for i, edge := range g.Edges {
+		if edge.U >= n || edge.V >= n || edge.U == edge.V {
+			continue
+		}
+		m.connected[edge.U], m.connected[edge.V] = true, true
+		m.edges = append(m.edges, [3]int{edge.U, edge.V, i})
+	}
+	return m, nil
+}
+
+// tileRange returns the tiles covered by the box [x0, x1] x [y0, y1] on the map
+func (m *tileMap) tileRange(x0, y0, x1, y1 float64) (tx0, ty0, tx1, ty1 int) {
+	cell := func(v float64) int {
+		return max(0, min(m.tiles-1, int(math.Floor(v/TileSize))))
+	}
+	return cell(x0), cell(y0), cell(x1), cell(y1)
+}
+
+// draw renders tile (tx, ty) from the given edges and vertices of the map
+func (m *tileMap) draw(tx, ty int, edges []int, vertices []int) *image.RGBA {
+	r := raster{image.NewRGBA(image.Rect(0, 0, TileSize, TileSize))}
+	bg := m.style.Background
+	for i := 0; i < len(r.img.Pix); i += 4 {
+		r.img.Pix[i], r.img.Pix[i+1], r.img.Pix[i+2], r.img.Pix[i+3] = bg.R, bg.G, bg.B, 255
+	}
+	ox, oy := float64(tx*TileSize), float64(ty*TileSize)
+	alpha := alpha8(m.style.EdgeAlpha)
+	for _, i := range edges {
+		u, v := m.edges[i][0], m.edges[i][1]
+		x0, y0 := m.points[u][0]-ox, m.points[u][1]-oy
+		x1, y1 := m.points[v][0]-ox, m.points[v][1]-oy
+		c := m.style.edgeColor(m.edges[i][2], u, v)
+		if m.style.Antialias {
+			// a wider box keeps the coverage of pixels at the tile border
+			if x0, y0, x1, y1, ok := clipSegment(x0, y0, x1, y1, -2, TileSize+2); ok {
+				r.lineAA(x0, y0, x1, y1, c, alpha)
+			}
+		} else if x0, y0, x1, y1, ok := clipSegment(x0, y0, x1, y1, -0.5, TileSize-0.5); ok {
+			r.line(x0, y0, x1, y1, c, alpha)
+		}
+	}
+	for _, v := range vertices {
+		r.disc(m.points[v][0]-ox, m.points[v][1]-oy, m.style.vertexRadius(v), m.style.vertexColor(v), m.style.Antialias)
+	}
+	return r.img
+}
+
+// RasterizeTile draws tile (x, y) of zoom level z of a slippy map of the
+// drawing, with edges and vertices drawn as Rasterize draws them
+func RasterizeTile(g *graph.Graph, e *embedding.Embedding, style Style, z, x, y int) (*image.RGBA, error) {
+	m, err := newTileMap(g, e, style, z)
+	if err != nil {
+		return nil, err
+	}
+	if x < 0 || y < 0 || x >= m.tiles || y >= m.tiles {
+		return nil, fmt.Errorf("tile %d/%d/%d is outside the map", z, x, y)
+	}
+	var edges, vertices []int
+	for i, edge := range m.edges {
+		tx0, ty0, tx1, ty1 := m.edgeTiles(edge)
+		if tx0 <= x && x <= tx1 && ty0 <= y && y <= ty1 {
+			edges = append(edges, i)
+		}
+	}
+	if style.drawsVertices() {
+		for v, ok := range m.connected {
+			tx0, ty0, tx1, ty1 := m.vertexTiles(v)
+			if ok && tx0 <= x && x <= tx1 && ty0 <= y && y <= ty1 {
+				vertices = append(vertices, v)
+			}
+		}
+	}
+	return m.draw(x, y, edges, vertices), nil
+}
+
+// WriteTiles draws zoom levels 0 to maxZoom of a slippy map of the drawing
+// into dir as z/x/y.png and returns the number of tiles written. Every edge
+// and vertex is only drawn into the tiles its bounding box touches.
+func WriteTiles(dir string, g *graph.Graph, e *embedding.Embedding, style Style, maxZoom int) (int, error) {
+	count := 0
+	for z := 0; z <= maxZoom; z++ {
+		m, err := newTileMap(g, e, style, z)
+		if err != nil {
+			return count, err
+		}
+		edges := make([][]int, m.tiles*m.tiles)
+		vertices := make([][]int, m.tiles*m.tiles)
+		for i, edge := range m.edges {
+			tx0, ty0, tx1, ty1 := m.edgeTiles(edge)
+			for ty := ty0; ty <= ty1; ty++ {
+				for tx := tx0; tx <= tx1; tx++ {
+					edges[ty*m.tiles+tx] = append(edges[ty*m.tiles+tx], i)
+				}
+			}
+		}
+		if style.drawsVertices() {
+			for v, ok := range m.connected {
+				if !ok {
+					continue
+				}
+				tx0, ty0, tx1, ty1 := m.vertexTiles(v)
+				for ty := ty0; ty <= ty1; ty++ {
+					for tx := tx0; tx <= tx1; tx++ {
+						vertices[ty*m.tiles+tx] = append(vertices[ty*m.tiles+tx], v)
+					}
+				}
+			}
+		}
+		for tx := 0; tx < m.tiles; tx++ {
+			for ty := 0; ty < m.tiles; ty++ {
+				img := m.draw(tx, ty, edges[ty*m.tiles+tx], vertices[ty*m.tiles+tx])
+				path := filepath.Join(dir, strconv.Itoa(z), strconv.Itoa(tx), strconv.Itoa(ty)+".png")
+				if err := WriteTileFile(path, img); err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// WriteTileFile encodes a tile as PNG at path, creating its directory. The
+// file is written under a temporary name and renamed, so concurrent readers
+// never see a partial tile.
+func WriteTileFile(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tile-*")
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// edgeTiles returns the tiles touched by the bounding box of an edge, widened
+// by the antialiasing border
+func (m *tileMap) edgeTiles(edge [3]int) (int, int, int, int) {
+	p, q := m.points[edge[0]], m.points[edge[1]]
+	return m.tileRange(min(p[0], q[0])-2, min(p[1], q[1])-2, max(p[0], q[0])+2, max(p[1], q[1])+2)
+}
+
+// vertexTiles returns the tiles touched by the disc of vertex v
+func (m *tileMap) vertexTiles(v int) (int, int, int, int) {
+	p, r := m.points[v], m.style.vertexRadius(v)+1
+	return m.tileRange(p[0]-r, p[1]-r, p[0]+r, p[1]+r)
+}
+
+// clipSegment cuts the segment to the square [lo, hi] x [lo, hi] with the
+// Liang-Barsky algorithm; ok is false when no part of it lies inside. End
+// points inside the square are returned unchanged.
+func clipSegment(x0, y0, x1, y1, lo, hi float64) (float64, float64, float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := x1-x0, y1-y0
+	for _, side := range [4][2]float64{{-dx, x0 - lo}, {dx, hi - x0}, {-dy, y0 - lo}, {dy, hi - y0}} {
+		p, q := side[0], side[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = max(t0, t)
+		} else {
+			t1 = min(t1, t)
+		}
+		if t0 > t1 {
+			return 0, 0, 0, 0, false
+		}
+	}
+	cx0, cy0, cx1, cy1 := x0, y0, x1, y1
+	if t0 > 0 {
+		cx0, cy0 = x0+t0*dx, y0+t0*dy
+	}
+	if t1 < 1 {
+		cx1, cy1 = x0+t1*dx, y0+t1*dy
+	}
+	return cx0, cy0, cx1, cy1, true
+}
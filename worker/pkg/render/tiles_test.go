@@ -0,0 +1,142 @@
+package render
+
+import (
+	"image"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+func TestClipSegment(t *testing.T) {
+	tests := []struct {
+		name string
+		in   [4]float64
+		want [4]float64
+		ok   bool
+	}{
+		{"inside", [4]float64{1, 2, 15, 18}, [4]float64{1, 2, 15, 18}, true},
+		{"crossing the left side", [4]float64{-10, 5, 10, 5}, [4]float64{0, 5, 10, 5}, true},
+		{"reversed", [4]float64{30, 5, -10, 5}, [4]float64{20, 5, 0, 5}, true},
+		{"through two corners", [4]float64{-10, -10, 30, 30}, [4]float64{0, 0, 20, 20}, true},
+		{"on the border", [4]float64{0, -5, 0, 25}, [4]float64{0, 0, 0, 20}, true},
+		{"outside", [4]float64{-10, -5, -1, 30}, [4]float64{}, false},
+		{"parallel outside", [4]float64{25, 0, 25, 10}, [4]float64{}, false},
+		{"missing a corner", [4]float64{15, -10, 30, 5}, [4]float64{}, false},
+	}
+	for _, tt := range tests {
+		x0, y0, x1, y1, ok := clipSegment(tt.in[0], tt.in[1], tt.in[2], tt.in[3], 0, 20)
+		got := [4]float64{x0, y0, x1, y1}
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-12 {
+				t.Errorf("%s: clipped to %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTileRange(t *testing.T) {
+	m := &tileMap{tiles: 4}
+	tests := []struct {
+		box  [4]float64
+		want [4]int
+	}{
+		{[4]float64{10, 10, 20, 20}, [4]int{0, 0, 0, 0}},
+		// boxes reaching past the map are clamped to its border tiles
+		{[4]float64{-10, -10, 1100, 1100}, [4]int{0, 0, 3, 3}},
+		{[4]float64{-300, 1000, -1, 2000}, [4]int{0, 3, 0, 3}},
+		// a tile starts at its first pixel
+		{[4]float64{255.9, 256, 511.9, 512}, [4]int{0, 1, 1, 2}},
+	}
+	for _, tt := range tests {
+		tx0, ty0, tx1, ty1 := m.tileRange(tt.box[0], tt.box[1], tt.box[2], tt.box[3])
+		if got := [4]int{tx0, ty0, tx1, ty1}; got != tt.want {
+			t.Errorf("tileRange(%v) = %v, want %v", tt.box, got, tt.want)
+		}
+	}
+}
+
+func TestRasterizeTileMatchesPyramid(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	const n = 80
+	g := &graph.Graph{N: n}
+	e := embedding.New(n, 2)
+	for v := 1; v < n; v++ {
+		e.Coords[v][0], e.Coords[v][1] = rng.NormFloat64(), rng.NormFloat64()
+		for k := 0; k < 2; k++ {
+			g.Edges = append(g.Edges, graph.Edge{U: v, V: 1 + rng.Intn(n-1), W: 1})
+		}
+	}
+	plain := DefaultStyle()
+	styled := DefaultStyle()
+	styled.Antialias, styled.VertexSize, styled.EdgeAlpha, styled.Margin = true, 3, 0.5, 10
+	for name, st := range map[string]Style{"plain": plain, "styled": styled} {
+		dir := t.TempDir()
+		const maxZoom = 2
+		count, err := WriteTiles(dir, g, e, st, maxZoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1+4+16 {
+			t.Fatalf("%s: wrote %d tiles, want 21", name, count)
+		}
+		for z := 0; z <= maxZoom; z++ {
+			for x := 0; x < 1<<z; x++ {
+				for y := 0; y < 1<<z; y++ {
+					want, err := RasterizeTile(g, e, st, z, x, y)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got := readTile(t, filepath.Join(dir, strconv.Itoa(z), strconv.Itoa(x), strconv.Itoa(y)+".png"))
+					if !sameImage(got, want) {
+						t.Errorf("%s: tile %d/%d/%d differs from the pyramid", name, z, x, y)
+					}
+				}
+			}
+		}
+	}
+	if _, err := RasterizeTile(g, e, plain, 1, 2, 0); err == nil {
+		t.Error("a tile outside the map was drawn")
+	}
+}
+
+func readTile(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func sameImage(a image.Image, b *image.RGBA) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		for x := b.Rect.Min.X; x < b.Rect.Max.X; x++ {
+			r, g, bl, al := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r != r2 || g != g2 || bl != b2 || al != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
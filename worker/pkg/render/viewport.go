@@ -0,0 +1,68 @@
+package render
+
+import "worker/pkg/embedding"
+
+// viewport maps embedding coordinates to image coordinates, scaling x and y
+// independently to fill the image inside the margin; y grows upwards.
+type viewport struct {
+	minX, minY     float64
+	scaleX, scaleY float64
+	height         float64
+	margin         float64
+}
+
+func newViewport(e *embedding.Embedding, width, height int, margin float64) viewport {
+	minX, maxX := 1e300, -1e300
+	minY, maxY := 1e300, -1e300
+	for _, p := range e.Coords {
+		x, y := p[0], 0.0
+		if e.Dims > 1 {
+			y = p[1]
+		}
+		minX, maxX = min(minX, x), max(maxX, x)
+		minY, maxY = min(minY, y), max(maxY, y)
+	}
+	rangeX, rangeY := maxX-minX, maxY-minY
+	if rangeX <= 0 {
+		rangeX = 1e-9
+	}
+	if rangeY <= 0 {
+		rangeY = 1e-9
+	}
+	return viewport{
+		minX:   minX,
+		minY:   minY,
+		scaleX: (float64(width) - 2*margin) / rangeX,
+		scaleY: (float64(height) - 2*margin) / rangeY,
+		height: float64(height),
+		margin: margin,
+	}
+}
+
+// newUniformViewport maps the embedding into the image with one scale for both
+// axes, so the drawing keeps its aspect ratio, centered in the longer direction
+func newUniformViewport(e *embedding.Embedding, width, height int, margin float64) viewport {
+	vp := newViewport(e, width, height, margin)
+	w, h := float64(width)-2*margin, float64(height)-2*margin
+	rangeX, rangeY := w/vp.scaleX, h/vp.scaleY
+	scale := min(vp.scaleX, vp.scaleY)
+	vp.minX -= (w/scale - rangeX) / 2
+	vp.minY -= (h/scale - rangeY) / 2
+	vp.scaleX, vp.scaleY = scale, scale
+	return vp
+}
+
+// newSquareViewport maps the embedding into a square image of the given size,
+// keeping its aspect ratio
+func newSquareViewport(e *embedding.Embedding, size int, margin float64) viewport {
+	return newUniformViewport(e, size, size, margin)
+}
+
+// point returns the image position of vertex coordinates p
+func (v viewport) point(p []float64) (float64, float64) {
+	y := 0.0
+	if len(p) > 1 {
+		y = p[1]
+	}
+	return v.margin + (p[0]-v.minX)*v.scaleX, v.height - v.margin - (y-v.minY)*v.scaleY
+}
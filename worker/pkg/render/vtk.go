@@ -0,0 +1,70 @@
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"worker/pkg/embedding"
+	"worker/pkg/graph"
+)
+
+// WriteVTK writes the embedded graph as legacy VTK polydata in binary form, as
+// read by ParaView: all vertices as points, a vertex cell for every vertex with
+// edges and a line cell for every edge. The cells carry their colors and the
+// points their vertex id, so selections can be traced back to the graph.
+func WriteVTK(w io.Writer, g *graph.Graph, e *embedding.Embedding, style Style) error {
+	m, err := buildModel(g, e, style)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	// binary legacy files are big endian
+	be := binary.BigEndian
+	var buf []byte
+	flush := func() {
+		bw.Write(buf)
+		buf = buf[:0]
+	}
+	fmt.Fprint(bw, "# vtk DataFile Version 3.0\nspectral graph embedding\nBINARY\nDATASET POLYDATA\n")
+
+	fmt.Fprintf(bw, "POINTS %d float\n", len(m.positions))
+	for _, p := range m.positions {
+		for _, x := range p {
+			buf = be.AppendUint32(buf, math.Float32bits(x))
+		}
+		flush()
+	}
+	fmt.Fprintf(bw, "\nVERTICES %d %d\n", len(m.points), 2*len(m.points))
+	for _, v := range m.points {
+		buf = be.AppendUint32(be.AppendUint32(buf, 1), v)
+		flush()
+	}
+	fmt.Fprintf(bw, "\nLINES %d %d\n", len(m.edges), 3*len(m.edges))
+	for _, edge := range m.edges {
+		buf = be.AppendUint32(be.AppendUint32(be.AppendUint32(buf, 2), edge[0]), edge[1])
+		flush()
+	}
+
+	// cell data follows the cell order: vertex cells first, then lines
+	fmt.Fprintf(bw, "\nCELL_DATA %d\nCOLOR_SCALARS colors 3\n", len(m.points)+len(m.edges))
+	for _, v := range m.points {
+		c := m.colors[v]
+		buf = append(buf, c.R, c.G, c.B)
+		flush()
+	}
+	for i := range m.edges {
+		c := m.colorOf(i)
+		buf = append(buf, c.R, c.G, c.B)
+		flush()
+	}
+	fmt.Fprintf(bw, "\nPOINT_DATA %d\nSCALARS vertex_id int 1\nLOOKUP_TABLE default\n", len(m.positions))
+	for v := range m.positions {
+		buf = be.AppendUint32(buf, uint32(v))
+		flush()
+	}
+	fmt.Fprint(bw, "\n")
+	return bw.Flush()
+}
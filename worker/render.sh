@@ -0,0 +1,151 @@
+#!/bin/bash
+
+# Render a finished job again from its stored eigenvectors, without recomputing them.
+# Usage: render.sh <job dir> <output dir> <s3 directory>
+# The projection is read from PROJECTION and PROJECTION_AXES, the layout refinement from
+# REFINEMENT, REFINE_ITERATIONS, STRESS_PIVOTS, FORCE_GRAVITY, FORCE_REPULSION and
+# REFINE_FRAMES, the drawing options from
+# RENDER_FORMATS (comma separated png, svg, pdf, obj, ply, glb, vtk), RENDER_WIDTH, RENDER_HEIGHT,
+# RENDER_MARGIN, EDGE_COLOR, EDGE_ALPHA, BACKGROUND, VERTEX_COLOR, VERTEX_SIZE,
+# ANTIALIAS and MIN_EDGE_LENGTH. COLOR_BY colors vertices by a partition computed
+# with the job (cluster or bisection), by component, degree, eigenvector (number
+# EIGENVECTOR) or attribute (values in ATTRIBUTES_FILE); SIZE_BY=degree sizes them,
+# EDGE_COLOR_BY (length or weight) colors edges and COLORMAP picks the colormap.
+# LABELS names vertices in the 2D drawings: top:K, all or comma separated vertex ids,
+# which may be row and column names such as r12 and c7 for bipartite graphs.
+# RENDER_MODE=density draws the PNG as a density map on the scale DENSITY_SCALE
+# (linear, log or eq_hist) instead of lines.
+
+log_error() {
+    echo "[ERROR] $(date +'%Y-%m-%d %H:%M:%S') - $1" >> "$2/error.txt"
+    echo "[ERROR] $1" >&2
+}
+
+set -e
+
+mkdir -p "$2"
+
+if [ ! -f "$1/eigenvectors.txt" ]; then
+    log_error "Job has no stored eigenvectors" "$2"
+    exit 1
+fi
+
+FORMATS=",${RENDER_FORMATS:-png,obj},"
+WIDTH="${RENDER_WIDTH:-1200}"
+HEIGHT="${RENDER_HEIGHT:-800}"
+EDGE_COLOR="${EDGE_COLOR:-#4169e1}"
+BACKGROUND="${BACKGROUND:-#ffffff}"
+VERTEX_COLOR="${VERTEX_COLOR:-#191970}"
+VERTEX_SIZE="${VERTEX_SIZE:-0}"
+STYLE_FLAGS="-width $WIDTH -height $HEIGHT -margin ${RENDER_MARGIN:-0} -edge-color $EDGE_COLOR -edge-alpha ${EDGE_ALPHA:-1}
+    -background $BACKGROUND -vertex-color $VERTEX_COLOR -vertex-size $VERTEX_SIZE -antialias=${ANTIALIAS:-false}
+    -min-edge-length ${MIN_EDGE_LENGTH:-0}"
+STYLING_FLAGS="-color-by=${COLOR_BY:-} -size-by=${SIZE_BY:-} -edge-color-by=${EDGE_COLOR_BY:-} -colormap=${COLORMAP:-}
+    -labels=${LABELS:-} -sides $1/sides.txt"
+GROUPS_FILE=""
+case "${COLOR_BY:-}" in
+    ""|component|degree) ;;
+    cluster) GROUPS_FILE="$1/out.clusters.txt" ;;
+    bisection) GROUPS_FILE="$1/out.bisection.txt" ;;
+    eigenvector) STYLING_FLAGS="$STYLING_FLAGS -eigenvectors $1/eigenvectors.txt -eigenvector ${EIGENVECTOR:-2}" ;;
+    attribute)
+        if [ ! -f "${ATTRIBUTES_FILE:-}" ]; then
+            log_error "Render has no attribute file" "$2"
+            exit 1
+        fi
+        STYLING_FLAGS="$STYLING_FLAGS -attributes $ATTRIBUTES_FILE"
+        ;;
+    *)
+        log_error "Unknown color_by $COLOR_BY" "$2"
+        exit 1
+        ;;
+esac
+if [ -n "$GROUPS_FILE" ] && [ ! -f "$GROUPS_FILE" ]; then
+    log_error "Job has no $COLOR_BY partition" "$2"
+    exit 1
+fi
+
+echo "Projecting embedding..."
+if ! ./spectra project -in "$1/eigenvectors.txt" -out "$2/embedding.txt" -dims 2 -mode "${PROJECTION:-first}" -axes "${PROJECTION_AXES:-}" \
+    || ! ./spectra project -in "$1/eigenvectors.txt" -out "$2/embedding_3d.txt" -dims 3 -mode "${PROJECTION:-first}" -axes "${PROJECTION_AXES:-}"; then
+    log_error "Failed to project embedding" "$2"
+    exit 1
+fi
+
+if [ "${REFINEMENT:-none}" != "none" ]; then
+    echo "Refining layout..."
+    for emb in embedding.txt embedding_3d.txt; do
+        # intermediate frames are kept for the 2D drawing only
+        FRAMES_DIR=""
+        if [ "$emb" = "embedding.txt" ] && [ "${REFINE_FRAMES:-0}" -gt 0 ]; then
+            FRAMES_DIR="$2/frames"
+        fi
+        if ! ./spectra refine -graph "$1/graph.txt" -in "$2/$emb" -out "$2/$emb" -method "$REFINEMENT" \
+            -iterations "${REFINE_ITERATIONS:-0}" -pivots "${STRESS_PIVOTS:-50}" \
+            -gravity "${FORCE_GRAVITY:-0.05}" -repulsion "${FORCE_REPULSION:-1}" \
+            -frames-dir "$FRAMES_DIR" -frame-every "${REFINE_FRAMES:-10}"; then
+            log_error "Failed to refine layout" "$2"
+            exit 1
+        fi
+    done
+fi
+
+echo "Computing layout metrics..."
+if ! ./spectra metrics -graph "$1/graph.txt" -in "$2/embedding.txt" -out "$2/metrics.json" -seed "${SEED:-1}"; then
+    log_error "Failed to compute layout metrics" "$2"
+    exit 1
+fi
+
+case "$FORMATS" in *,png,*)
+    echo "Generating visualization..."
+    if [ "${RENDER_MODE:-lines}" = "density" ]; then
+        if ! ./spectra density -graph "$1/graph.txt" -in "$2/embedding.txt" -out "$2/out.png" $STYLE_FLAGS \
+            -scale "${DENSITY_SCALE:-eq_hist}" -colormap "${COLORMAP:-viridis}"; then
+            log_error "Failed to generate density map" "$2"
+            exit 1
+        fi
+    elif ! ./spectra png -graph "$1/graph.txt" -in "$2/embedding.txt" -out "$2/out.png" $STYLE_FLAGS $STYLING_FLAGS -groups "$GROUPS_FILE"; then
+        log_error "Failed to generate visualization" "$2"
+        exit 1
+    fi
+    ;;
+esac
+
+case "$FORMATS" in *,svg,*)
+    echo "Generating .svg file..."
+    if ! ./spectra svg -graph "$1/graph.txt" -in "$2/embedding.txt" -out "$2/out.svg" $STYLE_FLAGS $STYLING_FLAGS -groups "$GROUPS_FILE"; then
+        log_error "Failed to generate .svg file" "$2"
+        exit 1
+    fi
+    ;;
+esac
+
+case "$FORMATS" in *,pdf,*)
+    echo "Generating .pdf file..."
+    if ! ./spectra pdf -graph "$1/graph.txt" -in "$2/embedding.txt" -out "$2/out.pdf" $STYLE_FLAGS $STYLING_FLAGS -groups "$GROUPS_FILE"; then
+        log_error "Failed to generate .pdf file" "$2"
+        exit 1
+    fi
+    ;;
+esac
+
+# 3D models take the vertex and edge colors of the drawings
+for format in obj ply glb vtk; do
+    case "$FORMATS" in *,$format,*)
+        echo "Generating .$format file..."
+        if ! ./spectra "$format" -graph "$1/graph.txt" -in "$2/embedding_3d.txt" -out "$2/out.$format" $STYLE_FLAGS $STYLING_FLAGS -groups "$GROUPS_FILE"; then
+            log_error "Failed to generate .$format file" "$2"
+            exit 1
+        fi
+        ;;
+    esac
+done
+
+echo "Uploading results to storage..."
+if ! /app/venv/bin/python ./upload_to_s3.py --local-path "$2" --s3-directory "$3"; then
+    log_error "Failed to upload files to storage" "$2"
+    exit 1
+fi
+
+echo "Render completed successfully!"
+exit 0
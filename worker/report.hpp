@@ -0,0 +1,120 @@
+// Spectral diagnostics collected while solving and written as JSON next to
+// the embedding, so the backend can show whether a drawing comes from a
+// converged solve.
+#ifndef SPECTRA_REPORT_HPP
+#define SPECTRA_REPORT_HPP
+
+#include <Eigen/Sparse>
+#include <Eigen/Dense>
+#include <vector>
+#include <string>
+#include <fstream>
+#include <iostream>
+#include <iomanip>
+#include <sstream>
+#include <cmath>
+
+#include "laplacian.hpp"
+
+namespace report {
+
+struct Stage {
+  std::string name;
+  double seconds;
+  long iterations;
+  bool converged;
+  double residual;            // last change of the iterate, 0 for non-iterative stages
+};
+
+class Report {
+public:
+  void addStage(const std::string& name, double seconds, long iterations = 0,
+                bool converged = true, double residual = 0) {
+    Stage s = {name, seconds, iterations, converged, residual};
+    stages_.push_back(s);
+  }
+
+  // Write the report. vecs holds the trivial vector followed by the layout
+  // vectors; eigenvalues are Rayleigh quotients mapped back to the Laplacian.
+  bool write(const std::string& path, const laplacian::Operator& op,
+             const std::vector<const Eigen::VectorXd*>& vecs, double totalSeconds) const {
+    std::ofstream fout(path);
+    if (!fout.is_open()) {
+      std::cerr << "Error: Cannot open report file " << path << std::endl;
+      return false;
+    }
+    std::vector<double> eigenvalues, residuals;
+    for (size_t k = 0; k < vecs.size(); k++) {
+      double mu, residual;
+      rayleigh(op, *vecs[k], mu, residual);
+      eigenvalues.push_back(op.eigenvalue(mu));
+      residuals.push_back(residual);
+    }
+    bool converged = true;
+    for (size_t k = 0; k < stages_.size(); k++)
+      converged = converged && stages_[k].converged;
+
+    fout << std::setprecision(12);
+    fout << "{\n";
+    fout << "  \"laplacian\": \"" << op.name() << "\",\n";
+    fout << "  \"vertices\": " << op.matrix().rows() << ",\n";
+    fout << "  \"eigenvalues\": " << list(eigenvalues) << ",\n";
+    fout << "  \"residuals\": " << list(residuals) << ",\n";
+    if (eigenvalues.size() > 1)
+      fout << "  \"algebraic_connectivity\": " << number(eigenvalues[1]) << ",\n";
+    if (eigenvalues.size() > 2)
+      fout << "  \"spectral_gap\": " << number(eigenvalues[2] - eigenvalues[1]) << ",\n";
+    fout << "  \"converged\": " << (converged ? "true" : "false") << ",\n";
+    fout << "  \"total_seconds\": " << number(totalSeconds) << ",\n";
+    fout << "  \"stages\": [";
+    for (size_t k = 0; k < stages_.size(); k++) {
+      const Stage& s = stages_[k];
+      fout << (k == 0 ? "\n" : ",\n");
+      fout << "    {\"name\": \"" << s.name << "\", \"seconds\": " << number(s.seconds)
+           << ", \"iterations\": " << s.iterations
+           << ", \"converged\": " << (s.converged ? "true" : "false")
+           << ", \"residual\": " << number(s.residual) << "}";
+    }
+    fout << "\n  ]\n";
+    fout << "}\n";
+    fout.close();
+    std::cout << "Spectral report written to " << path << std::endl;
+    return true;
+  }
+
+private:
+  std::vector<Stage> stages_;
+
+  // mu = <v, Mv>_W / <v, v>_W and the relative residual |Mv - mu v| / |v|.
+  static void rayleigh(const laplacian::Operator& op, const Eigen::VectorXd& v, double& mu, double& residual) {
+    Eigen::VectorXd Mv = op.matrix() * v;
+    Eigen::VectorXd Wv = v.cwiseProduct(op.weights());
+    double denom = v.dot(Wv);
+    mu = denom != 0 ? Mv.dot(Wv) / denom : 0;
+    double norm = v.norm();
+    residual = norm > 0 ? (Mv - mu * v).norm() / norm : 0;
+  }
+
+  // JSON has no NaN or infinity.
+  static std::string number(double x) {
+    if (!std::isfinite(x))
+      return "null";
+    std::ostringstream out;
+    out << std::setprecision(12) << x;
+    return out.str();
+  }
+
+  static std::string list(const std::vector<double>& xs) {
+    std::string s = "[";
+    for (size_t k = 0; k < xs.size(); k++) {
+      if (k > 0)
+        s += ", ";
+      s += number(xs[k]);
+    }
+    return s + "]";
+  }
+};
+
+} // namespace report
+
+#endif
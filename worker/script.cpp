@@ -10,13 +10,20 @@
 #include <cassert>
 #include <cstdlib>
 #include <cstring>
+#include <cstdio>
 #include <algorithm>
 #include <iomanip>
+#include <random>
 
 #ifdef _OPENMP
 #include <omp.h>
 #endif
 
+#include "coarsening.hpp"
+#include "deterministic.hpp"
+#include "laplacian.hpp"
+#include "report.hpp"
+
 using namespace std;
 using namespace Eigen;
 
@@ -26,6 +33,7 @@ typedef struct {
   long m;                     // number of nonzeros (for undirected graph, m = 2 * #edges)
   unsigned int *rowOffsets;
   unsigned int *adj;
+  double *weights;            // edge weights of the fine graph, parallel to adj
   long n_coarse;              // number of vertices in coarse graph
   long m_coarse;              // number of edges in coarse graph
   unsigned int *rowOffsetsCoarse;
@@ -42,7 +50,7 @@ std::string to_string_with_precision(const T a_value, const int n = 8) {
   return out.str();
 }
 
-// Comparison function used in sorting coarse edges.
+// Comparison function used in sorting coarse edges (u, v, fine edge index) by (u, v).
 static int vu_cmpfn_inc(const void *a, const void *b) {
   int *av = (int *) a;
   int *bv = (int *) b;
@@ -118,7 +126,7 @@ static int simpleCoarsening(graph_t *g, int coarseningType) {
       toMatch[i] = 1;
   }
 
-  int *coarse_edges = (int *) malloc(2 * g->m * sizeof(int));
+  int *coarse_edges = (int *) malloc(3 * g->m * sizeof(int));
   assert(coarse_edges != NULL);
 
   // Update coarse IDs.
@@ -151,17 +159,18 @@ static int simpleCoarsening(graph_t *g, int coarseningType) {
       int v = vertIDs[g->adj[j]];
       coarse_edges[ecount++] = u;
       coarse_edges[ecount++] = v;
+      coarse_edges[ecount++] = j;
     }
   }
-  ecount /= 2;
-  qsort(coarse_edges, g->m, 2 * sizeof(int), vu_cmpfn_inc);
+  ecount /= 3;
+  qsort(coarse_edges, g->m, 3 * sizeof(int), vu_cmpfn_inc);
 
   int m_coarse = 1;
   int prev_u = coarse_edges[0];
   int prev_v = coarse_edges[1];
   for (int i = 1; i < ecount; i++) {
-    int curr_u = coarse_edges[2*i];
-    int curr_v = coarse_edges[2*i+1];
+    int curr_u = coarse_edges[3*i];
+    int curr_v = coarse_edges[3*i+1];
     if ((curr_u != prev_u) || (curr_v != prev_v)) {
       m_coarse++;
       prev_u = curr_u;
@@ -172,7 +181,7 @@ static int simpleCoarsening(graph_t *g, int coarseningType) {
   double *eweights = (double *) malloc(m_coarse * sizeof(double));
   assert(eweights != NULL);
   for (int i = 0; i < m_coarse; i++)
-    eweights[i] = 1.0;
+    eweights[i] = 0.0;
 
   unsigned int *rowOffsetsCoarse = (unsigned int *) malloc((coarse_vert_count+1) * sizeof(unsigned int));
   assert(rowOffsetsCoarse != NULL);
@@ -186,19 +195,19 @@ static int simpleCoarsening(graph_t *g, int coarseningType) {
   prev_u = coarse_edges[0];
   prev_v = coarse_edges[1];
   adjCoarse[0] = prev_v;
+  eweights[0] = g->weights[coarse_edges[2]];
   rowOffsetsCoarse[prev_u+1]++;
   for (int i = 1; i < ecount; i++) {
-    int curr_u = coarse_edges[2*i];
-    int curr_v = coarse_edges[2*i+1];
+    int curr_u = coarse_edges[3*i];
+    int curr_v = coarse_edges[3*i+1];
     if ((curr_u != prev_u) || (curr_v != prev_v)) {
       m_coarse++;
       adjCoarse[m_coarse-1] = curr_v;
       rowOffsetsCoarse[curr_u+1]++;
       prev_u = curr_u;
       prev_v = curr_v;
-    } else {
-      eweights[m_coarse-1] += 1.0;
     }
+    eweights[m_coarse-1] += g->weights[coarse_edges[3*i+2]];
   }
   for (int i = 1; i <= coarse_vert_count; i++)
     rowOffsetsCoarse[i] += rowOffsetsCoarse[i-1];
@@ -219,46 +228,29 @@ static int simpleCoarsening(graph_t *g, int coarseningType) {
 
 // ---------------------------------------------------------------------
 // LOAD THE GRAPH INTO AN EIGEN SPARSE MATRIX.
+// Builds the weighted adjacency matrix A and the weighted degrees; the
+// Laplacian operator used by the solver is derived from them.
 // When coarsening is off (type 0) we build the matrix from the fine graph.
 // Otherwise we use the coarse graph arrays.
-static int loadToMatrix(SparseMatrix<double,RowMajor>& M, VectorXd& degrees, graph_t *g, int coarseningType) {
+static int loadToMatrix(SparseMatrix<double,RowMajor>& A, VectorXd& degrees, graph_t *g, int coarseningType) {
   typedef Triplet<double> T;
   vector<T> tripletList;
 
-  if (coarseningType == 0) {
-    tripletList.reserve(g->m);
-    for (int i = 0; i < g->n; i++) {
-      tripletList.push_back(T(i, i, 0.5));
-      degrees(i) = g->rowOffsets[i+1] - g->rowOffsets[i];
-      double nzv = 1.0 / (2.0 * (g->rowOffsets[i+1] - g->rowOffsets[i]));
-      for (unsigned int j = g->rowOffsets[i]; j < g->rowOffsets[i+1]; j++) {
-        unsigned int v = g->adj[j];
-        tripletList.push_back(T(i, v, nzv));
-      }
-    }
-    M.setFromTriplets(tripletList.begin(), tripletList.end());
-  } else {
-    for (int i = 0; i < g->n_coarse; i++) {
-      double degree_i = 0;
-      for (unsigned int j = g->rowOffsetsCoarse[i]; j < g->rowOffsetsCoarse[i+1]; j++)
-        degree_i += g->eweights[j];
-      degrees(i) = degree_i;
-    }
-    tripletList.reserve(g->m_coarse);
-    for (long i = 0; i < g->n_coarse; i++) {
-      double diag_val = 0;
-      double inv_2deg = 1.0/(2.0 * degrees(i));
-      for (unsigned int j = g->rowOffsetsCoarse[i]; j < g->rowOffsetsCoarse[i+1]; j++) {
-        unsigned int v = g->adjCoarse[j];
-        if (v == (unsigned int) i)
-          diag_val = g->eweights[j] * inv_2deg;
-        else
-          tripletList.push_back(T(i, v, g->eweights[j] * inv_2deg));
-      }
-      tripletList.push_back(T(i, i, diag_val + 0.5));
+  long n = coarseningType == 0 ? g->n : g->n_coarse;
+  unsigned int *rowOffsets = coarseningType == 0 ? g->rowOffsets : g->rowOffsetsCoarse;
+  unsigned int *adj = coarseningType == 0 ? g->adj : g->adjCoarse;
+  double *weights = coarseningType == 0 ? g->weights : g->eweights;
+
+  tripletList.reserve(rowOffsets[n]);
+  for (long i = 0; i < n; i++) {
+    double degree_i = 0;
+    for (unsigned int j = rowOffsets[i]; j < rowOffsets[i+1]; j++) {
+      tripletList.push_back(T(i, adj[j], weights[j]));
+      degree_i += weights[j];
     }
-    M.setFromTriplets(tripletList.begin(), tripletList.end());
+    degrees(i) = degree_i;
   }
+  A.setFromTriplets(tripletList.begin(), tripletList.end());
   return 0;
 }
 
@@ -289,8 +281,8 @@ static VectorXd bfs(unsigned int *row, unsigned int *col, long N, long M, unsign
 // ---------------------------------------------------------------------
 // HIGH-DIMENSIONAL EMBEDDING (HDE) Initialization.
 // It repeatedly computes distance vectors (via BFS) and then performs D-orthogonalization.
-// The final two vectors (after an eigen–decomposition) are used as initial second and third eigenvectors.
-static int HDE(SparseMatrix<double,RowMajor>& M, graph_t *g, VectorXd& degrees, VectorXd& secondVec, VectorXd& thirdVec) {
+// The first vecs.size() vectors (after an eigen–decomposition) are used as initial layout eigenvectors.
+static int HDE(graph_t *g, VectorXd& degrees, vector<VectorXd>& vecs, report::Report& rep) {
   auto startTimer = chrono::high_resolution_clock::now();
   long n = g->n;
   typedef Triplet<double> T;
@@ -300,7 +292,7 @@ static int HDE(SparseMatrix<double,RowMajor>& M, graph_t *g, VectorXd& degrees,
     LTripletList.push_back(T(i, i, degrees(i)));
     for (unsigned int j = g->rowOffsets[i]; j < g->rowOffsets[i+1]; j++) {
       unsigned int v = g->adj[j];
-      LTripletList.push_back(T(i, v, -1.0));
+      LTripletList.push_back(T(i, v, -g->weights[j]));
     }
   }
   SparseMatrix<double,RowMajor> L(n, n);
@@ -352,155 +344,264 @@ static int HDE(SparseMatrix<double,RowMajor>& M, graph_t *g, VectorXd& degrees,
   MatrixXd LX = L * dist_bak;
   MatrixXd XtLX = dist_bak.transpose() * LX;
   SelfAdjointEigenSolver<MatrixXd> es(XtLX);
-  MatrixXd init_vecs = dist_bak * es.eigenvectors().leftCols(2).real();
+  int k = min((int) vecs.size(), maxM);
+  MatrixXd init_vecs = dist_bak * es.eigenvectors().leftCols(k).real();
   auto endTimer2 = chrono::high_resolution_clock::now();
   cout << "HDE Initialization time: " << chrono::duration<double>(endTimer2 - startTimer).count() << " s." << endl;
-  secondVec = init_vecs.col(0);
-  thirdVec  = init_vecs.col(1);
+  rep.addStage("hde", chrono::duration<double>(endTimer2 - startTimer).count(), maxM);
+  for (int c = 0; c < k; c++)
+    vecs[c] = init_vecs.col(c);
   return 0;
 }
 
 // ---------------------------------------------------------------------
-// Koren's Power–Iteration Algorithm for computing the second and third eigenvectors.
-// It performs D–orthonormalization against previously computed eigenvectors.
-static int powerIterationKoren(SparseMatrix<double,RowMajor>& M, VectorXd& degrees, double eps,
-                                VectorXd& firstVec, VectorXd& secondVec, VectorXd& thirdVec, int coarseningType) {
-  cout << "Using eps " << eps << " for second eigenvector" << endl;
+// Frames of the layout while the finest level is solved, for animations: every
+// `every` solver steps the current vectors are written to <dir>/frame-NNNNN.txt
+// in the form of eigenvectors.txt, with their signs fixed as in the result so
+// the drawing does not flip between frames. At most maxFrames frames are kept:
+// when they are reached, every second frame is dropped and the spacing doubles,
+// so the frames stay evenly spread over the whole solve.
+struct FrameWriter {
+  // frame numbers have five digits
+  static const int frameLimit = 99999;
+
+  string dir;
+  int every = 0;
+  int maxFrames = 200;
+  int step = 0;
+  int written = 0;
+
+  // Count a solver step; true when a frame is due after it.
+  bool due() {
+    if (dir.empty() || every <= 0 || ++step % every != 0)
+      return false;
+    if (written >= maxFrames)
+      thin();
+    return step % every == 0;
+  }
+
+  string name(int i) const {
+    ostringstream name;
+    name << dir << "/frame-" << setw(5) << setfill('0') << i << ".txt";
+    return name.str();
+  }
+
+  // Keep the odd frames, those at multiples of twice the spacing, as 0, 1, ...
+  void thin() {
+    for (int i = 0; i < written; i++) {
+      if (i % 2 == 0)
+        remove(name(i).c_str());
+      else
+        rename(name(i).c_str(), name(i / 2).c_str());
+    }
+    written /= 2;
+    every *= 2;
+  }
+
+  void write(const vector<VectorXd>& vecs) {
+    vector<VectorXd> frame(vecs);
+    for (size_t c = 0; c < frame.size(); c++)
+      deterministic::canonicalSign(frame[c]);
+    string path = name(written++);
+    ofstream fout(path);
+    if (!fout.is_open()) {
+      cerr << "Error: Cannot write frame " << path << endl;
+      return;
+    }
+    fout << setprecision(8);
+    long n = frame.empty() ? 0 : frame[0].size();
+    for (long i = 0; i < n; i++) {
+      for (size_t c = 0; c < frame.size(); c++)
+        fout << (c > 0 ? " " : "") << frame[c](i);
+      fout << "\n";
+    }
+  }
+};
+
+// ---------------------------------------------------------------------
+// Koren's Power–Iteration Algorithm for computing the layout eigenvectors.
+// Eigenvector c (numbered from 2, the trivial one being 1) is D–orthonormalized against
+// the first vector and all previously computed ones; with a weighted graph D holds the
+// weighted degrees, which gives the weighted variant. The tolerance doubles with every
+// further vector, as the later eigenvectors converge more slowly.
+static int powerIterationKoren(const laplacian::Operator& op, double eps,
+                                VectorXd& firstVec, vector<VectorXd>& vecs, int coarseningType,
+                                report::Report& rep, const string& stage, FrameWriter* frames = nullptr) {
+  const SparseMatrix<double,RowMajor>& M = op.matrix();
+  const VectorXd& degrees = op.weights();
   int n = M.rows();
-  VectorXd uk_hat = secondVec;
-  VectorXd uk(n);
-  VectorXd firstVecD = firstVec.cwiseProduct(degrees);
-  double mult1_denom = firstVec.dot(firstVecD);
-  VectorXd residual(n);
-  int num_iterations1 = 0;
   const int max_iter = 10000; // maximum iterations as safeguard
-  double prev_diff = std::numeric_limits<double>::infinity();
 
-  auto startTimer = chrono::high_resolution_clock::now();
-  while (num_iterations1 < max_iter) {
-    uk = uk_hat;
-    double mult1_num = uk.dot(firstVecD);
-    uk = uk - (mult1_num / mult1_denom) * firstVec;
-    uk_hat = M * uk;
-    uk_hat.normalize();
-    num_iterations1++;
-    residual = uk - uk_hat;
-    double diff = residual.norm();
-
-    // Align sign to avoid oscillations:
-    if (uk_hat.dot(uk) < 0)
-      uk_hat = -uk_hat;
-
-    if (diff < eps)
-      break;
-
-    // Check for stagnation every 100 iterations.
-    if (num_iterations1 % 100 == 0) {
-      if (fabs(diff - prev_diff) < 1e-12 && diff > eps * 10) {
-        cout << "Stagnation detected in second eigenvector iteration at iteration "
-             << num_iterations1 << ", diff = " << diff << endl;
-        break;
+  // Previously computed vectors and their D-norms used for orthogonalization.
+  vector<VectorXd> basis(1, firstVec);
+  vector<VectorXd> basisD(1, firstVec.cwiseProduct(degrees));
+  vector<double> basisDenom(1, firstVec.dot(basisD[0]));
+
+  for (size_t c = 0; c < vecs.size(); c++) {
+    int number = (int) c + 2;
+    cout << "Using eps " << eps << " for eigenvector " << number << endl;
+    VectorXd uk_hat = vecs[c];
+    VectorXd uk(n);
+    VectorXd residual = VectorXd::Zero(n);
+    int num_iterations = 0;
+    double prev_diff = std::numeric_limits<double>::infinity();
+
+    auto startTimer = chrono::high_resolution_clock::now();
+    while (num_iterations < max_iter) {
+      uk = uk_hat;
+      for (size_t b = 0; b < basis.size(); b++) {
+        double mult_num = uk.dot(basisD[b]);
+        uk = uk - (mult_num / basisDenom[b]) * basis[b];
+      }
+      uk_hat = M * uk;
+      uk_hat.normalize();
+      num_iterations++;
+      residual = uk - uk_hat;
+      double diff = residual.norm();
+
+      // Align sign to avoid oscillations:
+      if (uk_hat.dot(uk) < 0)
+        uk_hat = -uk_hat;
+      if (frames && frames->due()) {
+        vecs[c] = uk_hat;
+        frames->write(vecs);
       }
-      prev_diff = diff;
-    }
-  }
-  if(num_iterations1 >= max_iter)
-    cout << "Warning: second eigenvector power iteration did not converge within "
-         << max_iter << " iterations." << endl;
 
-  cout << "Num iterations for second eigenvector: " << num_iterations1 << endl;
-  secondVec = uk_hat;
-  auto endTimer = chrono::high_resolution_clock::now();
-  cout << "Second eigenvector computation time: "
-       << chrono::duration<double>(endTimer - startTimer).count() << " s." << endl;
-
-  eps = 2.0 * eps;
-  cout << "Using eps " << eps << " for third eigenvector" << endl;
-  VectorXd secondVecD = secondVec.cwiseProduct(degrees);
-  double mult2_denom = secondVec.dot(secondVecD);
-  uk_hat = thirdVec;
-  int num_iterations2 = 0;
-  prev_diff = std::numeric_limits<double>::infinity();
-  startTimer = chrono::high_resolution_clock::now();
-  while (num_iterations2 < max_iter) {
-    uk = uk_hat;
-    double mult1_num = uk.dot(firstVecD);
-    uk = uk - (mult1_num / mult1_denom) * firstVec;
-    double mult2_num = uk.dot(secondVecD);
-    uk = uk - (mult2_num / mult2_denom) * secondVec;
-    uk_hat = M * uk;
-    uk_hat.normalize();
-    num_iterations2++;
-    residual = uk - uk_hat;
-    double diff = residual.norm();
-
-    // Align sign to avoid oscillation.
-    if (uk_hat.dot(uk) < 0)
-      uk_hat = -uk_hat;
-
-    if (diff < eps)
-      break;
-    if (num_iterations2 % 100 == 0) {
-      if (fabs(diff - prev_diff) < 1e-12 && diff > eps * 10) {
-        cout << "Stagnation detected in third eigenvector iteration at iteration "
-             << num_iterations2 << ", diff = " << diff << endl;
+      if (diff < eps)
         break;
+
+      // Check for stagnation every 100 iterations.
+      if (num_iterations % 100 == 0) {
+        if (fabs(diff - prev_diff) < 1e-12 && diff > eps * 10) {
+          cout << "Stagnation detected in eigenvector " << number << " iteration at iteration "
+               << num_iterations << ", diff = " << diff << endl;
+          break;
+        }
+        prev_diff = diff;
       }
-      prev_diff = diff;
     }
+    if (num_iterations >= max_iter)
+      cout << "Warning: eigenvector " << number << " power iteration did not converge within "
+           << max_iter << " iterations." << endl;
+
+    cout << "Num iterations for eigenvector " << number << ": " << num_iterations << endl;
+    vecs[c] = uk_hat;
+    auto endTimer = chrono::high_resolution_clock::now();
+    cout << "Eigenvector " << number << " computation time: "
+         << chrono::duration<double>(endTimer - startTimer).count() << " s." << endl;
+    rep.addStage(stage + " vector " + to_string(number), chrono::duration<double>(endTimer - startTimer).count(),
+                 num_iterations, residual.norm() < eps, residual.norm());
+
+    basis.push_back(vecs[c]);
+    basisD.push_back(vecs[c].cwiseProduct(degrees));
+    basisDenom.push_back(vecs[c].dot(basisD.back()));
+    eps = 2.0 * eps;
   }
-  if(num_iterations2 >= max_iter)
-    cout << "Warning: third eigenvector power iteration did not converge within "
-         << max_iter << " iterations." << endl;
-
-  cout << "Num iterations for third eigenvector: " << num_iterations2 << endl;
-  thirdVec = uk_hat;
-  auto endTimer2 = chrono::high_resolution_clock::now();
-  cout << "Third eigenvector computation time: "
-       << chrono::duration<double>(endTimer2 - startTimer).count() << " s." << endl;
-  cout << "Dot products of eigenvectors: " << firstVec.dot(secondVec) << " "
-       << firstVec.dot(thirdVec) << " " << secondVec.dot(thirdVec) << endl;
+  cout << "Dot products of eigenvectors:";
+  for (size_t a = 0; a < basis.size(); a++)
+    for (size_t b = a + 1; b < basis.size(); b++)
+      cout << " " << basis[a].dot(basis[b]);
+  cout << endl;
   return 0;
 }
 
 
 // ---------------------------------------------------------------------
-// Tutte Refinement: Multiply the coordinate vectors repeatedly with a modified matrix.
-static int RefineTutte(SparseMatrix<double,RowMajor>& M, VectorXd& secondVec, VectorXd& thirdVec, int numSmoothing) {
+// Tutte Refinement: Multiply the coordinate vectors repeatedly with D^-1 A (self loops
+// removed), so each step moves a vertex to the weighted barycenter of its neighbors.
+// The smoothing matrix does not depend on the selected Laplacian.
+static int RefineTutte(const laplacian::Operator& op, vector<VectorXd>& vecs, int numSmoothing,
+                       report::Report& rep, FrameWriter* frames = nullptr) {
   cout << "Number of smoothing rounds: " << numSmoothing << endl;
   auto startTimer = chrono::high_resolution_clock::now();
-  SparseMatrix<double,RowMajor> M2 = 2 * M;
+  SparseMatrix<double,RowMajor> M2 = op.degrees().cwiseInverse().asDiagonal() * op.adjacency();
   M2.diagonal().setZero();
   for (int i = 0; i < numSmoothing; i++) {
-    secondVec = M2 * secondVec;
-    thirdVec = M2 * thirdVec;
+    for (size_t c = 0; c < vecs.size(); c++)
+      vecs[c] = M2 * vecs[c];
+    if (frames && frames->due())
+      frames->write(vecs);
   }
   auto endTimer = chrono::high_resolution_clock::now();
   cout << "RefineTutte Time: " << chrono::duration<double>(endTimer - startTimer).count() << " s." << endl;
+  rep.addStage("tutte", chrono::duration<double>(endTimer - startTimer).count(), numSmoothing);
   return 0;
 }
 
 // ---------------------------------------------------------------------
-// Write the computed 2D coordinates (using second and third eigenvectors) to an output file.
-static int writeCoords(SparseMatrix<double,RowMajor>& M, VectorXd& firstVec, VectorXd& secondVec, VectorXd& thirdVec,
+// Write the computed layout eigenvectors to an output file, one vertex per line
+// and one column per eigenvector (column c holds eigenvector c + 2).
+static int writeCoords(const SparseMatrix<double,RowMajor>& M, vector<VectorXd>& vecs,
                        int coarseningType, int doHDE, int refineType, double eps, const char *inputFilename, std::string output_path) {
-  string outFilename = output_path + "/embedding.txt";
+  string outFilename = output_path + "/eigenvectors.txt";
   ofstream fout(outFilename);
   if (!fout.is_open()) {
     cerr << "Error: Cannot open output file " << outFilename << endl;
     return 1;
   }
+  fout << setprecision(12);
   int n = M.cols();
   for (int i = 0; i < n; i++) {
-    fout << secondVec(i) << " " << thirdVec(i) << "\n";
+    for (size_t c = 0; c < vecs.size(); c++)
+      fout << (c > 0 ? " " : "") << vecs[c](i);
+    fout << "\n";
   }
   fout.close();
   cout << "Embedding written to " << outFilename << endl;
   return 0;
 }
 
+// Read start vectors written as eigenvectors.txt: one vertex per line and one
+// column per vector. Vertices and vectors missing from the file start random.
+static int readStartVectors(const string& filename, vector<VectorXd>& vecs, std::mt19937_64& rng) {
+  ifstream fin(filename);
+  if (!fin.is_open()) {
+    cerr << "Error: cannot open start vectors " << filename << endl;
+    return -1;
+  }
+  long n = vecs.empty() ? 0 : vecs[0].size();
+  for (size_t c = 0; c < vecs.size(); c++)
+    vecs[c] = deterministic::randomStart(n, rng);
+  vector<bool> given(vecs.size(), false);
+  string line;
+  long row = 0;
+  int columns = 0;
+  while (row < n && getline(fin, line)) {
+    istringstream ls(line);
+    double x;
+    for (size_t c = 0; c < vecs.size() && ls >> x; c++) {
+      vecs[c](row) = x;
+      given[c] = true;
+      columns = max(columns, (int) c + 1);
+    }
+    row++;
+  }
+  fin.close();
+  for (size_t c = 0; c < vecs.size(); c++) {
+    if (given[c] && vecs[c].norm() > 0)
+      vecs[c].normalize();
+  }
+  return columns;
+}
+
+// Pointers to the vectors, as taken by canonicalize and the report.
+static vector<VectorXd*> pointers(vector<VectorXd>& vecs) {
+  vector<VectorXd*> ptrs;
+  for (size_t c = 0; c < vecs.size(); c++)
+    ptrs.push_back(&vecs[c]);
+  return ptrs;
+}
+
+static vector<const VectorXd*> reportVectors(const VectorXd& firstVec, const vector<VectorXd>& vecs) {
+  vector<const VectorXd*> ptrs(1, &firstVec);
+  for (size_t c = 0; c < vecs.size(); c++)
+    ptrs.push_back(&vecs[c]);
+  return ptrs;
+}
+
 // ---------------------------------------------------------------------
-// Read graph from a text file (each line: "u v") and build a CSR structure.
+// Read graph from a text file (each line: "u v" or "u v w") and build a CSR structure.
+// A missing weight column means unit weight.
 graph_t* readGraphFromTxt(const char *filename) {
   ifstream fin(filename);
   if (!fin.is_open()) {
@@ -508,28 +609,43 @@ graph_t* readGraphFromTxt(const char *filename) {
     exit(1);
   }
   vector< pair<unsigned int, unsigned int> > edges;
+  vector<double> edgeWeights;
   unsigned int u, v;
   unsigned int maxVertex = 0;
-  while (fin >> u >> v) {
+  string line;
+  while (getline(fin, line)) {
+    istringstream ls(line);
+    if (!(ls >> u >> v))
+      continue;
+    double w;
+    if (!(ls >> w))
+      w = 1.0;
+    if (w < 0) {
+      cerr << "Error: negative edge weight " << w << " for edge " << u << " " << v << endl;
+      exit(1);
+    }
     edges.push_back({u, v});
+    edgeWeights.push_back(w);
     maxVertex = max(maxVertex, max(u, v));
   }
   fin.close();
   long n = maxVertex + 1;       // assuming vertices are zero-indexed
   long m = edges.size() * 2;    // undirected graph: add both (u,v) and (v,u)
 
-  vector< vector<unsigned int> > adjList(n);
-  for (auto &e : edges) {
-    u = e.first;
-    v = e.second;
-    adjList[u].push_back(v);
-    adjList[v].push_back(u);
+  vector< vector< pair<unsigned int, double> > > adjList(n);
+  for (size_t e = 0; e < edges.size(); e++) {
+    u = edges[e].first;
+    v = edges[e].second;
+    adjList[u].push_back({v, edgeWeights[e]});
+    adjList[v].push_back({u, edgeWeights[e]});
   }
 
   unsigned int *rowOffsets = (unsigned int *) malloc((n+1) * sizeof(unsigned int));
   assert(rowOffsets != NULL);
   unsigned int *adj = (unsigned int *) malloc(m * sizeof(unsigned int));
   assert(adj != NULL);
+  double *weights = (double *) malloc(m * sizeof(double));
+  assert(weights != NULL);
 
   rowOffsets[0] = 0;
   for (long i = 0; i < n; i++) {
@@ -537,7 +653,8 @@ graph_t* readGraphFromTxt(const char *filename) {
   }
   for (long i = 0; i < n; i++) {
     for (size_t j = 0; j < adjList[i].size(); j++) {
-      adj[rowOffsets[i] + j] = adjList[i][j];
+      adj[rowOffsets[i] + j] = adjList[i][j].first;
+      weights[rowOffsets[i] + j] = adjList[i][j].second;
     }
   }
 
@@ -547,6 +664,7 @@ graph_t* readGraphFromTxt(const char *filename) {
   g->m = m;
   g->rowOffsets = rowOffsets;
   g->adj = adj;
+  g->weights = weights;
   g->n_coarse = 0;
   g->m_coarse = 0;
   g->rowOffsetsCoarse = NULL;
@@ -562,12 +680,22 @@ graph_t* readGraphFromTxt(const char *filename) {
 // then compute the spectral embedding using HDE, Koren's algorithm and/or Tutte refinement.
 // Finally, write the output embedding.
 int main(int argc, char **argv) {
-  if (argc != 6) {
-    cout << "Usage: " << argv[0] << " <graph.txt> <0/1/2> <0/1> <0/1/2/3>" << endl;
+  if (argc < 6 || (argc - 6) % 2 != 0) {
+    cout << "Usage: " << argv[0] << " <graph.txt> <0/1/2/3> <0/1> <0/1/2/3> <output dir> [options]" << endl;
     cout << "    where <graph.txt> is a text file with lines: \"u v\"" << endl;
-    cout << "    <0/1/2>: coarsening type (0: none, 1: coarsen and continue, 2: coarsen and stop)" << endl;
+    cout << "    <0/1/2/3>: coarsening type (0: none, 1: coarsen and continue, 2: coarsen and stop, 3: multilevel)" << endl;
     cout << "    <0/1>: HDE flag (0: off, 1: on)" << endl;
     cout << "    <0/1/2/3>: refinement (0: none, 1: Koren, 2: Tutte, 3: Koren+Tutte)" << endl;
+    cout << "    options:" << endl;
+    cout << "      --strategy <hem/mis>: multilevel coarsening strategy (default: hem)" << endl;
+    cout << "      --levels <n>: maximum number of multilevel coarsening steps (default: 0, until the graph is small)" << endl;
+    cout << "      --seed <n>: seed for the random start vectors (default: 1)" << endl;
+    cout << "      --laplacian <generalized/combinatorial/symmetric/random-walk/signless>: operator (default: generalized)" << endl;
+    cout << "      --report <file>: where to write the spectral report (default: <output dir>/report.json)" << endl;
+    cout << "      --vectors <k>: number of layout eigenvectors written to eigenvectors.txt (default: 2)" << endl;
+    cout << "      --frames <dir>: write the layout every --frame-every solver steps to <dir>/frame-NNNNN.txt" << endl;
+    cout << "      --frame-every <n>: solver steps between frames (default: 10)" << endl;
+    cout << "      --max-frames <n>: frames kept at most, thinned out evenly (default: 200, at most 99999)" << endl;
     return 1;
   }
   const char *inputFilename = argv[1];
@@ -575,11 +703,55 @@ int main(int argc, char **argv) {
   int coarseningType = atoi(argv[2]);
   int doHDE = atoi(argv[3]);
   int refineType = atoi(argv[4]);
+  coarsening::Strategy strategy = coarsening::HEAVY_EDGE_MATCHING;
+  int maxLevels = 0;
+  unsigned long long seed = 1;
+  string laplacianName = "generalized";
+  string reportPath = output_path + "/report.json";
+  int numVectors = 2;
+  string initPath;
+  FrameWriter frames;
+  frames.every = 10;
+  for (int i = 6; i < argc; i += 2) {
+    string opt(argv[i]);
+    if (opt == "--strategy")
+      strategy = coarsening::parseStrategy(argv[i+1]);
+    else if (opt == "--levels")
+      maxLevels = atoi(argv[i+1]);
+    else if (opt == "--seed")
+      seed = strtoull(argv[i+1], NULL, 10);
+    else if (opt == "--laplacian")
+      laplacianName = argv[i+1];
+    else if (opt == "--report")
+      reportPath = argv[i+1];
+    else if (opt == "--vectors")
+      numVectors = atoi(argv[i+1]);
+    else if (opt == "--init")
+      initPath = argv[i+1];
+    else if (opt == "--frames")
+      frames.dir = argv[i+1];
+    else if (opt == "--frame-every")
+      frames.every = atoi(argv[i+1]);
+    else if (opt == "--max-frames")
+      frames.maxFrames = atoi(argv[i+1]);
+    else
+      cout << "Ignoring unknown option " << opt << endl;
+  }
+
+  if (!initPath.empty() && (coarseningType != 0 || doHDE)) {
+    // The start vectors already are close to the result; solving a coarse
+    // graph or running HDE would replace them.
+    cout << "Warm start given, skipping coarsening and HDE" << endl;
+    coarseningType = 0;
+    doHDE = 0;
+  }
 
   if (coarseningType == 1)
     cout << "Coarsening graph and continuing" << endl;
   else if (coarseningType == 2)
     cout << "Coarsening graph and stopping" << endl;
+  else if (coarseningType == 3)
+    cout << "Multilevel coarsening (" << coarsening::strategyName(strategy) << ")" << endl;
   else
     coarseningType = 0;
 
@@ -597,40 +769,79 @@ int main(int argc, char **argv) {
   else if (refineType == 3)
     cout << "Using Koren's algorithm followed by Tutte refinement" << endl;
 
+  if (numVectors < 1 || numVectors > 50) {
+    cout << "Number of eigenvectors must be between 1 and 50, using 2" << endl;
+    numVectors = 2;
+  }
+  if (frames.maxFrames < 2 || frames.maxFrames > FrameWriter::frameLimit) {
+    cout << "Number of frames must be between 2 and " << FrameWriter::frameLimit << ", using 200" << endl;
+    frames.maxFrames = 200;
+  }
+  cout << "Computing " << numVectors << " layout eigenvectors" << endl;
+  cout << "Using seed " << seed << endl;
+  std::mt19937_64 rng(seed);
+
   auto startTimer = chrono::high_resolution_clock::now();
 
   cout << "Reading graph from file: " << inputFilename << endl;
+  report::Report rep;
   graph_t *g = readGraphFromTxt(inputFilename);
+  rep.addStage("read graph", chrono::duration<double>(chrono::high_resolution_clock::now() - startTimer).count());
   cout << "Graph: vertices = " << g->n << ", edges = " << g->m/2 << endl;
 
   // Perform coarsening if selected.
-  simpleCoarsening(g, coarseningType);
-
-  VectorXd secondCoarse, thirdCoarse;
-  if (coarseningType > 0) {
+  auto coarseningTimer = chrono::high_resolution_clock::now();
+  if (coarseningType != 3)
+    simpleCoarsening(g, coarseningType);
+
+  vector<VectorXd> coarseVecs(numVectors);
+  coarsening::Hierarchy hierarchy;
+  if (coarseningType == 3) {
+    hierarchy = coarsening::buildHierarchy(g->n, g->rowOffsets, g->adj, g->weights, strategy, maxLevels, 1000);
+    cout << "Hierarchy levels: " << hierarchy.numLevels() << ", coarsest graph: "
+         << hierarchy.coarsest().n << " vertices" << endl;
+    rep.addStage("coarsening", chrono::duration<double>(chrono::high_resolution_clock::now() - coarseningTimer).count());
+    for (int l = hierarchy.numLevels() - 1; l > 0; l--) {
+      const coarsening::Level& level = hierarchy.levels[l];
+      SparseMatrix<double,RowMajor> Al(level.n, level.n);
+      VectorXd degreesl(level.n);
+      coarsening::loadLevelAdjacency(level, Al, degreesl);
+      std::unique_ptr<laplacian::Operator> opl = laplacian::make(laplacianName, Al, degreesl);
+      VectorXd firstLevel = opl->firstVector();
+      double epsl = 1e-5;
+      if (l == hierarchy.numLevels() - 1) {
+        // Solve on the coarsest graph from a random start.
+        for (int c = 0; c < numVectors; c++)
+          coarseVecs[c] = deterministic::randomStart(level.n, rng);
+        epsl = 1e-9;
+      } else {
+        // Prolongate from the coarser level and refine.
+        for (int c = 0; c < numVectors; c++) {
+          coarseVecs[c] = coarsening::prolongate(level, coarseVecs[c]);
+          coarseVecs[c].normalize();
+        }
+      }
+      cout << "Level " << l << ": " << level.n << " vertices" << endl;
+      powerIterationKoren(*opl, epsl, firstLevel, coarseVecs, coarseningType, rep, "level " + to_string(l) + " koren");
+    }
+  } else if (coarseningType > 0) {
     int n_coarse = g->n_coarse;
-    SparseMatrix<double,RowMajor> Mc(n_coarse, n_coarse);
+    SparseMatrix<double,RowMajor> Ac(n_coarse, n_coarse);
     VectorXd degreesc(n_coarse);
     degreesc.setZero();
-    loadToMatrix(Mc, degreesc, g, coarseningType);
-    VectorXd firstCoarse = VectorXd::Ones(n_coarse);
-    firstCoarse.normalize();
-    secondCoarse = VectorXd::Random(n_coarse);
-    if (secondCoarse(0) < 0)
-      secondCoarse = -secondCoarse;
-    secondCoarse.normalize();
-    thirdCoarse = VectorXd::Random(n_coarse);
-    if (thirdCoarse(0) < 0)
-      thirdCoarse = -thirdCoarse;
-    thirdCoarse.normalize();
+    rep.addStage("coarsening", chrono::duration<double>(chrono::high_resolution_clock::now() - coarseningTimer).count());
+    loadToMatrix(Ac, degreesc, g, coarseningType);
+    std::unique_ptr<laplacian::Operator> opc = laplacian::make(laplacianName, Ac, degreesc);
+    VectorXd firstCoarse = opc->firstVector();
+    for (int c = 0; c < numVectors; c++)
+      coarseVecs[c] = deterministic::randomStart(n_coarse, rng);
     double epsc = 1e-9;
-    powerIterationKoren(Mc, degreesc, epsc, firstCoarse, secondCoarse, thirdCoarse, coarseningType);
+    powerIterationKoren(*opc, epsc, firstCoarse, coarseVecs, coarseningType, rep, "coarse koren");
     if (coarseningType == 2) {
+      deterministic::canonicalize(opc->matrix(), opc->weights(), pointers(coarseVecs));
       writeCoords(
-        Mc,
-        firstCoarse,
-        secondCoarse,
-        thirdCoarse,
+        opc->matrix(),
+        coarseVecs,
         coarseningType,
         doHDE,
         refineType,
@@ -638,60 +849,73 @@ int main(int argc, char **argv) {
         inputFilename,
         output_path
         );
+      rep.write(reportPath, *opc, reportVectors(firstCoarse, coarseVecs),
+                chrono::duration<double>(chrono::high_resolution_clock::now() - startTimer).count());
       return 0;
     }
   }
 
   // Load the full (fine) graph.
-  SparseMatrix<double,RowMajor> M(g->n, g->n);
+  SparseMatrix<double,RowMajor> A(g->n, g->n);
   VectorXd degrees(g->n);
   degrees.setZero();
-  loadToMatrix(M, degrees, g, 0);
+  loadToMatrix(A, degrees, g, 0);
+  std::unique_ptr<laplacian::Operator> op = laplacian::make(laplacianName, A, degrees);
+  cout << "Laplacian: " << op->name() << endl;
 
-  VectorXd firstVec = VectorXd::Ones(g->n);
-  firstVec.normalize();
-  VectorXd secondVec(g->n);
-  VectorXd thirdVec(g->n);
+  VectorXd firstVec = op->firstVector();
+  vector<VectorXd> vecs(numVectors, VectorXd(g->n));
 
   if (coarseningType == 1) {
-    for (long i = 0; i < g->n; i++) {
-      secondVec(i) = secondCoarse[g->coarseID[i]];
-      thirdVec(i)  = thirdCoarse[g->coarseID[i]];
+    for (int c = 0; c < numVectors; c++) {
+      for (long i = 0; i < g->n; i++)
+        vecs[c](i) = coarseVecs[c][g->coarseID[i]];
+      vecs[c].normalize();
+    }
+  } else if (coarseningType == 3 && hierarchy.numLevels() > 1) {
+    for (int c = 0; c < numVectors; c++) {
+      vecs[c] = coarsening::prolongate(hierarchy.levels[0], coarseVecs[c]);
+      vecs[c].normalize();
     }
-    secondVec.normalize();
-    thirdVec.normalize();
+  } else if (!initPath.empty()) {
+    auto warmTimer = chrono::high_resolution_clock::now();
+    int columns = readStartVectors(initPath, vecs, rng);
+    if (columns < 0)
+      return 1;
+    cout << "Warm start from " << initPath << ": " << columns << " of " << numVectors << " vectors given" << endl;
+    rep.addStage("warm start", chrono::duration<double>(chrono::high_resolution_clock::now() - warmTimer).count());
   } else if (doHDE == 1) {
-    HDE(M, g, degrees, secondVec, thirdVec);
+    HDE(g, degrees, vecs, rep);
   } else {
-    secondVec = VectorXd::Random(g->n);
-    if (secondVec(0) < 0)
-      secondVec = -secondVec;
-    secondVec.normalize();
-    thirdVec = VectorXd::Random(g->n);
-    if (thirdVec(0) < 0)
-      thirdVec = -thirdVec;
-    thirdVec.normalize();
+    for (int c = 0; c < numVectors; c++)
+      vecs[c] = deterministic::randomStart(g->n, rng);
   }
 
   if (coarseningType != 2) {
     int numTutteSmoothing = 500;
     double eps = 1e-5;
     if (refineType == 0) {
-      secondVec.normalize();
-      thirdVec.normalize();
+      for (int c = 0; c < numVectors; c++)
+        vecs[c].normalize();
     } else if (refineType == 1) {
-      powerIterationKoren(M, degrees, eps, firstVec, secondVec, thirdVec, 0);
+      powerIterationKoren(*op, eps, firstVec, vecs, 0, rep, "koren", &frames);
     } else if (refineType == 2) {
-      RefineTutte(M, secondVec, thirdVec, numTutteSmoothing);
+      RefineTutte(*op, vecs, numTutteSmoothing, rep, &frames);
     } else if (refineType == 3) {
-      RefineTutte(M, secondVec, thirdVec, numTutteSmoothing);
-      powerIterationKoren(M, degrees, eps, firstVec, secondVec, thirdVec, 0);
+      RefineTutte(*op, vecs, numTutteSmoothing, rep, &frames);
+      powerIterationKoren(*op, eps, firstVec, vecs, 0, rep, "koren", &frames);
     }
-    writeCoords(M, firstVec, secondVec, thirdVec, coarseningType, doHDE, refineType, 0, inputFilename, output_path);
+    if (frames.written > 0)
+      cout << "Wrote " << frames.written << " frames to " << frames.dir << endl;
+    deterministic::canonicalize(op->matrix(), op->weights(), pointers(vecs));
+    writeCoords(op->matrix(), vecs, coarseningType, doHDE, refineType, 0, inputFilename, output_path);
+    rep.write(reportPath, *op, reportVectors(firstVec, vecs),
+              chrono::duration<double>(chrono::high_resolution_clock::now() - startTimer).count());
   }
 
   free(g->rowOffsets);
   free(g->adj);
+  free(g->weights);
   if (coarseningType > 0) {
     free(g->rowOffsetsCoarse);
     free(g->adjCoarse);
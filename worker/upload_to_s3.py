@@ -6,20 +6,14 @@ import argparse
 import boto3
 from botocore.exceptions import ClientError
 
-def upload_to_s3(local_path, s3_directory):
-    """
-    Upload a file to the MinIO 'artifacts' bucket in the specified directory
-    and save the S3 paths to result.txt
+# Every artifact goes to the 'artifacts' bucket
+BUCKET_NAME = 'artifacts'
 
-    :param local_path: Directory containing out.* files
-    :param s3_directory: Directory in the S3 bucket
-    :return: True if file was uploaded, else False
+def make_client():
     """
-    # Define the bucket name - this is now hardcoded as 'artifacts'
-    bucket_name = 'artifacts'
-
-    # Initialize the S3 client with MinIO endpoint
-    s3_client = boto3.client(
+    Create the S3 client of the MinIO server from docker-compose
+    """
+    return boto3.client(
         's3',
         endpoint_url='http://minio:9000',  # MinIO endpoint from docker-compose
         aws_access_key_id='minioadmin',    # Default MinIO access key
@@ -28,6 +22,18 @@ def upload_to_s3(local_path, s3_directory):
         verify=False  # Disable SSL verification for local development
     )
 
+def upload_to_s3(local_path, s3_directory):
+    """
+    Upload a file to the MinIO 'artifacts' bucket in the specified directory
+    and save the S3 paths to result.txt
+
+    :param local_path: Directory containing out.* files
+    :param s3_directory: Directory in the S3 bucket
+    :return: True if file was uploaded, else False
+    """
+    bucket_name = BUCKET_NAME
+    s3_client = make_client()
+
     # Find files matching pattern 'out.*'
     out_files = glob.glob(os.path.join(local_path, 'out.*'))
 
@@ -67,10 +73,64 @@ def upload_to_s3(local_path, s3_directory):
 
     return success
 
+def upload_tree(local_path, s3_directory, tree):
+    """
+    Upload every file below local_path/tree to s3_directory/tree, keeping the
+    relative paths. The files are not listed in result.txt: they are found by
+    their path, like the z/x/y.png tiles of a map.
+
+    :return: True if all files were uploaded, else False
+    """
+    bucket_name = BUCKET_NAME
+    s3_client = make_client()
+
+    root = os.path.join(local_path, tree)
+    success = True
+    count = 0
+    for dir_path, _, file_names in os.walk(root):
+        for file_name in sorted(file_names):
+            if file_name.startswith('.'):
+                continue
+            file_path = os.path.join(dir_path, file_name)
+            relative = os.path.relpath(file_path, local_path).replace(os.sep, '/')
+            s3_path = f"{s3_directory.rstrip('/')}/{relative}"
+            try:
+                s3_client.upload_file(file_path, bucket_name, s3_path)
+                count += 1
+            except ClientError as e:
+                print(f"Error uploading {relative} to MinIO: {e}")
+                success = False
+    print(f"Uploaded {count} files of {tree} to MinIO bucket '{bucket_name}'")
+    return success
+
+def upload_files(local_path, s3_directory, files):
+    """
+    Upload single files below local_path to s3_directory, keeping their paths
+    relative to local_path. Like trees they are not listed in result.txt.
+
+    :return: True if all files were uploaded, else False
+    """
+    s3_client = make_client()
+    success = True
+    for relative in files:
+        file_path = os.path.join(local_path, relative)
+        s3_path = f"{s3_directory.rstrip('/')}/{relative.replace(os.sep, '/')}"
+        try:
+            s3_client.upload_file(file_path, BUCKET_NAME, s3_path)
+            print(f"Uploaded {relative} to MinIO bucket '{BUCKET_NAME}'")
+        except (ClientError, OSError) as e:
+            print(f"Error uploading {relative} to MinIO: {e}")
+            success = False
+    return success
+
 def main():
     parser = argparse.ArgumentParser(description='Upload out.* files to MinIO artifacts bucket')
     parser.add_argument('--local-path', required=True, help='Local directory containing out.* files')
     parser.add_argument('--s3-directory', required=True, help='Directory in the S3 bucket')
+    parser.add_argument('--tree', action='append', default=[],
+                        help='Subdirectory uploaded with all its files, such as tiles (repeatable)')
+    parser.add_argument('--file', action='append', default=[],
+                        help='Single file below the local path uploaded alone, without the out.* files (repeatable)')
 
     args = parser.parse_args()
 
@@ -78,7 +138,15 @@ def main():
         print(f"Error: Local path {args.local_path} is not a directory or does not exist")
         sys.exit(1)
 
+    if args.file:
+        if not upload_files(args.local_path, args.s3_directory, args.file):
+            sys.exit(1)
+        return
+
     success = upload_to_s3(args.local_path, args.s3_directory)
+    for tree in args.tree:
+        if os.path.isdir(os.path.join(args.local_path, tree)):
+            success = upload_tree(args.local_path, args.s3_directory, tree) and success
 
     if not success:
         sys.exit(1)